@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "abtest",
+		Summary: "solve the same scrambles with two solvers and compare their solutions statistically",
+		Run:     runABTest,
+	})
+}
+
+// ABTest holds how two solvers, A and B, did on the same random scrambles.
+type ABTest struct {
+	Width, Height int
+	Games         int
+	// MovesA and MovesB are the lengths of the solutions of the scrambles both solvers solved, in single shifts, paired by scramble, and TimesA and TimesB their solving times.
+	MovesA, MovesB []int
+	TimesA, TimesB []time.Duration
+	// FailuresA and FailuresB hold the state code of every scramble each solver failed to solve, along with why.
+	FailuresA, FailuresB []string
+}
+
+// RunABTest arranges `games` boards with the given dimensions uniformly at random and solves each with both solvers, checking their solutions.
+func RunABTest(width, height, games int, a, b Solver) (*ABTest, error) {
+	board, err := NewBoard(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &ABTest{Width: width, Height: height, Games: games}
+	for i := 0; i < games; i++ {
+		board.uniformShuffle()
+
+		movesA, tookA, errA := runSolver(a, &board)
+		if errA != nil {
+			t.FailuresA = append(t.FailuresA, fmt.Sprintf("%s: %s", EncodeState(&board), errA))
+		}
+		movesB, tookB, errB := runSolver(b, &board)
+		if errB != nil {
+			t.FailuresB = append(t.FailuresB, fmt.Sprintf("%s: %s", EncodeState(&board), errB))
+		}
+		if errA != nil || errB != nil {
+			continue
+		}
+
+		t.MovesA, t.MovesB = append(t.MovesA, movesA), append(t.MovesB, movesB)
+		t.TimesA, t.TimesB = append(t.TimesA, tookA), append(t.TimesB, tookB)
+	}
+
+	return t, nil
+}
+
+// Wins returns on how many scrambles B's solution was shorter than, as long as, and longer than A's.
+func (t *ABTest) Wins() (wins, ties, losses int) {
+	for i := range t.MovesA {
+		switch {
+		case t.MovesB[i] < t.MovesA[i]:
+			wins++
+		case t.MovesB[i] == t.MovesA[i]:
+			ties++
+		default:
+			losses++
+		}
+	}
+
+	return
+}
+
+// Difference returns the mean of how many more single shifts B's solutions take than A's, the half width of its 95% confidence interval,
+// and the p-value of a paired t-test of the difference being 0, approximating the t distribution by the normal one, which is close enough past 30 scrambles.
+func (t *ABTest) Difference() (mean, interval, p float64) {
+	n := float64(len(t.MovesA))
+	if n < 2 {
+		return 0, math.Inf(1), 1
+	}
+
+	for i := range t.MovesA {
+		mean += float64(t.MovesB[i] - t.MovesA[i])
+	}
+	mean /= n
+
+	var variance float64
+	for i := range t.MovesA {
+		d := float64(t.MovesB[i]-t.MovesA[i]) - mean
+		variance += d * d
+	}
+	se := math.Sqrt(variance / (n - 1) / n)
+
+	if se == 0 {
+		if mean == 0 {
+			return 0, 0, 1
+		}
+		return mean, 0, 0
+	}
+	return mean, 1.96 * se, math.Erfc(math.Abs(mean/se) / math.Sqrt2)
+}
+
+// meanMillis returns the mean of the durations, in milliseconds.
+func meanMillis(times []time.Duration) float64 {
+	if len(times) == 0 {
+		return 0
+	}
+
+	var sum time.Duration
+	for _, t := range times {
+		sum += t
+	}
+	return float64(sum) / float64(len(times)) / float64(time.Millisecond)
+}
+
+// runABTest runs the abtest command.
+func runABTest(args []string) error {
+	fs := flag.NewFlagSet("abtest", flag.ContinueOnError)
+	nameA := fs.String("a", "human", "solver A, the baseline: human, short or short:N, like for simulate")
+	nameB := fs.String("b", "short", "solver B, the one compared with the baseline")
+	games := fs.Int("games", 500, "number of scrambles to solve with both solvers")
+	size := fs.String("size", "4x4", "board size")
+	seed := fs.Int64("seed", 0, "seed of the scrambles, to repeat a comparison, or 0 to pick one")
+	alpha := fs.Float64("alpha", 0.05, "significance level the difference is tested at")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: abtest [-a human] [-b short] [-games 500] [-size 4x4] [-seed N] [-alpha 0.05]")
+		fmt.Fprintln(fs.Output(), "Solves the same scrambles, picked uniformly at random, with two solvers, and compares the lengths of their solutions:")
+		fmt.Fprintln(fs.Output(), "the mean difference with its confidence interval, how often B wins, and whether the difference is significant, to accept or reject a change to a solver.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *games <= 0 {
+		return fmt.Errorf("the number of games must be at least 1")
+	}
+	if *alpha <= 0 || *alpha >= 1 {
+		return fmt.Errorf("the significance level must be between 0 and 1")
+	}
+
+	w, h, err := ParseTwoDimensions(*size)
+	if err != nil {
+		return err
+	}
+	a, err := ParseSolver(*nameA)
+	if err != nil {
+		return err
+	}
+	b, err := ParseSolver(*nameB)
+	if err != nil {
+		return err
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rng = rand.New(rand.NewSource(*seed))
+	fmt.Printf("Seed %d\n", *seed)
+
+	t, err := RunABTest(w, h, *games, a, b)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d %s scrambles, A is %s and B is %s\n", t.Games, *size, *nameA, *nameB)
+	for _, s := range []struct {
+		name     string
+		moves    []int
+		times    []time.Duration
+		failures []string
+	}{{"A", t.MovesA, t.TimesA, t.FailuresA}, {"B", t.MovesB, t.TimesB, t.FailuresB}} {
+		values := make([]float64, len(s.moves))
+		for i, m := range s.moves {
+			values[i] = float64(m)
+		}
+		d := Summarize(values)
+
+		fmt.Printf("%s: %.2f moves on average (std %.1f), median %.0f, %.3fms on average, %d failed\n", s.name, d.Mean, d.Std, d.Median, meanMillis(s.times), len(s.failures))
+		for _, f := range s.failures {
+			fmt.Printf("  %s\n", f)
+		}
+	}
+	if len(t.MovesA) == 0 {
+		return fmt.Errorf("no scramble was solved by both solvers")
+	}
+
+	wins, ties, losses := t.Wins()
+	n := float64(len(t.MovesA))
+	fmt.Printf("B is shorter on %d scrambles (%.1f%%), as long on %d (%.1f%%) and longer on %d (%.1f%%)\n",
+		wins, 100*float64(wins)/n, ties, 100*float64(ties)/n, losses, 100*float64(losses)/n)
+
+	mean, interval, p := t.Difference()
+	fmt.Printf("B - A: %+.2f moves on average, 95%% confidence interval %+.2f to %+.2f, p = %.4g\n", mean, mean-interval, mean+interval, p)
+	switch {
+	case p >= *alpha:
+		fmt.Printf("No significant difference at %g\n", *alpha)
+	case mean < 0:
+		fmt.Printf("B is significantly shorter at %g\n", *alpha)
+	default:
+		fmt.Printf("B is significantly longer at %g\n", *alpha)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "mixing",
+		Summary: "run random walks to measure how well shuffling mixes the board",
+		Run:     runMixing,
+	})
+}
+
+// WalkStats holds the statistics of many random walks from the solved state, where every step is one of Shuffle's moves.
+type WalkStats struct {
+	Walks int
+	Steps int
+	// Every is how many steps there are between samples.
+	Every int
+
+	// MeanDistance and StdDistance hold the mean and standard deviation of the board's Distance at every sample.
+	MeanDistance []float64
+	StdDistance  []float64
+	// Returns is how many steps, over all walks, left the board solved.
+	Returns int
+}
+
+// RandomWalks runs `walks` random walks of `steps` steps on a solved board with the given dimensions, sampling its Distance every `every` steps.
+func RandomWalks(width, height, walks, steps, every int) (*WalkStats, error) {
+	b, err := NewBoard(width, height)
+	if err != nil {
+		return nil, err
+	}
+	if every <= 0 {
+		every = 1
+	}
+
+	samples := steps / every
+	sum := make([]float64, samples)
+	sqSum := make([]float64, samples)
+
+	s := &WalkStats{Walks: walks, Steps: steps, Every: every}
+	for i := 0; i < walks; i++ {
+		b.Reset()
+
+		for step := 1; step <= steps; step++ {
+			b.Shuffle(1)
+
+			if b.IsSolved() {
+				s.Returns++
+			}
+
+			if step%every == 0 {
+				d := float64(b.Distance())
+				sum[step/every-1] += d
+				sqSum[step/every-1] += d * d
+			}
+		}
+	}
+
+	s.MeanDistance, s.StdDistance = meanStd(sum, sqSum, walks)
+	return s, nil
+}
+
+// UniformDistance samples the Distance of `samples` solvable boards picked uniformly at random, returning its mean and standard deviation.
+// This is what a perfectly mixed shuffle looks like.
+func UniformDistance(width, height, samples int) (mean, std float64, err error) {
+	b, err := NewBoard(width, height)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var sum, sqSum float64
+	for i := 0; i < samples; i++ {
+		b.uniformShuffle()
+
+		d := float64(b.Distance())
+		sum += d
+		sqSum += d * d
+	}
+
+	means, stds := meanStd([]float64{sum}, []float64{sqSum}, samples)
+	return means[0], stds[0], nil
+}
+
+// uniformShuffle rearranges the board into a solvable arrangement picked uniformly at random.
+func (b *Board) uniformShuffle() {
+	w := b.Width()
+
+	for i, t := range rng.Perm(w * b.Height()) {
+		(*b)[i%w][i/w] = t + 1
+	}
+
+	// swapping two tiles flips the permutation parity, making it solvable.
+	if !b.IsSolvable() {
+		(*b)[0][0], (*b)[1][0] = (*b)[1][0], (*b)[0][0]
+	}
+}
+
+// SolvableStates returns how many solvable arrangements a board with the given dimensions has.
+func SolvableStates(width, height int) float64 {
+	states := 1.0
+	for i := 2; i <= width*height; i++ {
+		states *= float64(i)
+	}
+
+	if width%2 == 1 && height%2 == 1 {
+		states /= 2
+	}
+
+	return states
+}
+
+// meanStd computes the mean and standard deviation of every sample from the sum of the values and the sum of their squares over `n` values.
+func meanStd(sum, sqSum []float64, n int) (mean, std []float64) {
+	mean = make([]float64, len(sum))
+	std = make([]float64, len(sum))
+	if n == 0 {
+		return
+	}
+
+	for i := range sum {
+		mean[i] = sum[i] / float64(n)
+		std[i] = math.Sqrt(math.Max(sqSum[i]/float64(n)-mean[i]*mean[i], 0))
+	}
+
+	return
+}
+
+// runMixing runs the mixing command.
+func runMixing(args []string) error {
+	fs := flag.NewFlagSet("mixing", flag.ContinueOnError)
+	size := fs.String("size", "3x3", "board size")
+	walks := fs.Int("walks", 1000, "number of random walks")
+	steps := fs.Int("steps", 0, "number of Shuffle moves per walk, 0 uses twice the default shuffle iterations")
+	every := fs.Int("every", 0, "number of moves between samples, 0 takes 10 samples per walk")
+	prof := AddProfilingFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	defer prof.Stop()
+	if err := prof.Start(); err != nil {
+		return err
+	}
+
+	w, h, err := ParseTwoDimensions(*size)
+	if err != nil {
+		return err
+	}
+
+	defaultIters := DefaultShuffleIterations(w, h)
+	if *steps <= 0 {
+		*steps = 2 * defaultIters
+	}
+	if *every <= 0 {
+		*every = *steps / 10
+	}
+
+	s, err := RandomWalks(w, h, *walks, *steps, *every)
+	if err != nil {
+		return err
+	}
+
+	uMean, uStd, err := UniformDistance(w, h, *walks)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d random walks of %d moves on a %dx%d board\n", s.Walks, s.Steps, w, h)
+	fmt.Printf("%8s %14s %8s\n", "moves", "mean distance", "std")
+
+	mixedAt := -1
+	for i := range s.MeanDistance {
+		step := (i + 1) * s.Every
+		fmt.Printf("%8d %14.2f %8.2f\n", step, s.MeanDistance[i], s.StdDistance[i])
+
+		if mixedAt == -1 && s.MeanDistance[i] >= 0.95*uMean {
+			mixedAt = step
+		}
+	}
+	fmt.Printf("%8s %14.2f %8.2f\n", "uniform", uMean, uStd)
+
+	fmt.Printf("Returned to solved after %d of %d moves (%.3g), a uniformly random board is solved with a probability of %.3g\n",
+		s.Returns, s.Walks*s.Steps, float64(s.Returns)/float64(s.Walks*s.Steps), 1/SolvableStates(w, h))
+
+	if mixedAt == -1 {
+		fmt.Printf("The mean distance never got within 5%% of a uniformly random board's in %d moves, the default is %d shuffle iterations\n", s.Steps, defaultIters)
+	} else {
+		fmt.Printf("The mean distance gets within 5%% of a uniformly random board's after %d moves, the default is %d shuffle iterations\n", mixedAt, defaultIters)
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "automate",
+		Summary: "write a solution as mouse drags for web Loopover, as an xdotool or AutoHotkey script",
+		Run:     runAutomate,
+	})
+}
+
+// DragGeometry is where the grid of a board is on the screen, in pixels.
+type DragGeometry struct {
+	// X and Y are the top left corner of the grid.
+	X, Y int
+	// Tile is the width and height of a tile.
+	Tile int
+}
+
+// center returns the center of the tile at (x, y) of the board.
+func (g DragGeometry) center(x, y int) (int, int) {
+	return g.X + x*g.Tile + g.Tile/2, g.Y + y*g.Tile + g.Tile/2
+}
+
+// Drag is a drag of the mouse from a tile to another in the same slice, which shifts the slice on web Loopover, in screen pixels.
+// Path holds the center of every tile the drag goes through, from the first to the last, as some implementations follow the mouse tile by tile.
+type Drag struct {
+	Path [][2]int
+}
+
+// Drags returns the drags that make the moves on the board. Moves are normalized, so that no drag goes past the edge of the grid.
+func Drags(seq []Move, b *Board, g DragGeometry) []Drag {
+	drags := make([]Drag, 0, len(seq))
+	for _, m := range seq {
+		m = b.NormalizeMove(m)
+		if m.Amount == 0 {
+			continue
+		}
+
+		// start from the edge the slice moves away from, so the drag stays on the grid.
+		l := b.SliceLength(m.Axis)
+		from, step := 0, 1
+		if m.Amount < 0 {
+			from, step = l-1, -1
+		}
+
+		var d Drag
+		for i := 0; i <= Abs(m.Amount); i++ {
+			x, y := from+i*step, m.Index
+			if m.Axis == VerticalAxis {
+				x, y = m.Index, from+i*step
+			}
+
+			px, py := g.center(x, y)
+			d.Path = append(d.Path, [2]int{px, py})
+		}
+		drags = append(drags, d)
+	}
+
+	return drags
+}
+
+// DragFormat is what drags are written as.
+type DragFormat int
+
+const (
+	// PlainDrags writes every drag as a line of the points it goes through, like "30,30 90,30".
+	PlainDrags DragFormat = iota
+	// XdotoolDrags writes a shell script that makes the drags with xdotool, on X11.
+	XdotoolDrags
+	// AutoHotkeyDrags writes an AutoHotkey script that makes the drags, on Windows.
+	AutoHotkeyDrags
+)
+
+func (f DragFormat) String() string {
+	switch f {
+	case PlainDrags:
+		return "plain"
+	case XdotoolDrags:
+		return "xdotool"
+	case AutoHotkeyDrags:
+		return "ahk"
+	default:
+		return fmt.Sprintf("DragFormat(%d)", int(f))
+	}
+}
+
+// ParseDragFormat parses the name of a drag format, as returned by DragFormat.String.
+func ParseDragFormat(s string) (DragFormat, error) {
+	for f := PlainDrags; f <= AutoHotkeyDrags; f++ {
+		if strings.EqualFold(s, f.String()) {
+			return f, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown format %q, expected plain, xdotool or ahk", s)
+}
+
+// WriteDrags writes the drags in the format, waiting `delay` between two drags and `countdown` before the first one, to switch to the browser.
+// `comment` is written at the top of scripts, line by line.
+func WriteDrags(w io.Writer, drags []Drag, f DragFormat, delay, countdown time.Duration, comment string) error {
+	var sb strings.Builder
+	switch f {
+	case PlainDrags:
+		for _, d := range drags {
+			points := make([]string, 0, len(d.Path))
+			for _, p := range d.Path {
+				points = append(points, fmt.Sprintf("%d,%d", p[0], p[1]))
+			}
+			sb.WriteString(strings.Join(points, " ") + "\n")
+		}
+	case XdotoolDrags:
+		sb.WriteString("#!/bin/sh\n")
+		for _, line := range strings.Split(comment, "\n") {
+			fmt.Fprintf(&sb, "# %s\n", line)
+		}
+		fmt.Fprintf(&sb, "sleep %g\n", countdown.Seconds())
+		for _, d := range drags {
+			fmt.Fprintf(&sb, "xdotool mousemove %d %d mousedown 1", d.Path[0][0], d.Path[0][1])
+			for _, p := range d.Path[1:] {
+				fmt.Fprintf(&sb, " mousemove %d %d", p[0], p[1])
+			}
+			fmt.Fprintf(&sb, " mouseup 1\nsleep %g\n", delay.Seconds())
+		}
+	case AutoHotkeyDrags:
+		for _, line := range strings.Split(comment, "\n") {
+			fmt.Fprintf(&sb, "; %s\n", line)
+		}
+		sb.WriteString("CoordMode, Mouse, Screen\n")
+		fmt.Fprintf(&sb, "Sleep, %d\n", countdown.Milliseconds())
+		for _, d := range drags {
+			last := d.Path[len(d.Path)-1]
+			fmt.Fprintf(&sb, "MouseClickDrag, Left, %d, %d, %d, %d, 5\nSleep, %d\n", d.Path[0][0], d.Path[0][1], last[0], last[1], delay.Milliseconds())
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// runAutomate runs the automate command.
+func runAutomate(args []string) error {
+	fs := flag.NewFlagSet("automate", flag.ContinueOnError)
+	scramblePath := fs.String("scramble", "", "file with the state code of the scramble, or the moves that make it from a solved board, to automate a solution file instead of a replay")
+	size := fs.String("size", "5x5", "board size, when the scramble is given as moves")
+	out := fs.String("o", "", "file to write to instead of the standard output")
+	var g DragGeometry
+	fs.IntVar(&g.X, "x", 0, "x of the top left corner of the grid on the screen, in pixels")
+	fs.IntVar(&g.Y, "y", 0, "y of the top left corner of the grid on the screen, in pixels")
+	fs.IntVar(&g.Tile, "tile", 60, "width of a tile on the screen, in pixels")
+	delay := fs.Duration("delay", 100*time.Millisecond, "time to wait between two drags, for the website to keep up")
+	countdown := fs.Duration("countdown", 3*time.Second, "time to wait before the first drag, to switch to the browser")
+	var format DragFormat
+	fs.Func("format", "what to write: plain for the points of every drag, xdotool for a shell script using xdotool, or ahk for an AutoHotkey script", func(s string) (err error) {
+		format, err = ParseDragFormat(s)
+		return err
+	})
+	var from Notation
+	fs.Func("from", "notation of the moves in the files: programmer, standard or english", func(s string) (err error) {
+		from, err = ParseNotation(s)
+		return err
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: automate [-format plain|xdotool|ahk] [-x 0] [-y 0] [-tile 60] [-delay 100ms] [-countdown 3s] [-o FILE] REPLAY | automate -scramble FILE [-size 5x5] [-from NOTATION] [flags] SOLUTION")
+		fmt.Fprintln(fs.Output(), "Writes the moves of a solution as the mouse drags that make them on web Loopover, to run engine solutions on the website.")
+		fmt.Fprintln(fs.Output(), "Scramble the website to the same board first, and give where its grid is on the screen with -x, -y and -tile.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a replay or solution file")
+	}
+	if g.Tile <= 0 {
+		return fmt.Errorf("the tile width must be at least 1 pixel")
+	}
+
+	var scramble Board
+	var solution []Move
+	if *scramblePath == "" {
+		r, err := LoadReplayFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		scramble, solution = r.Scramble, r.Moves
+	} else {
+		var err error
+		if scramble, err = readScrambleFile(*scramblePath, *size, from); err != nil {
+			return err
+		}
+		if solution, err = readMovesFile(fs.Arg(0), from, &scramble); err != nil {
+			return err
+		}
+	}
+
+	comment := fmt.Sprintf("Scramble: %s\nSolution: %s", EncodeState(&scramble), MoveFormat{}.FormatMoves(solution, &scramble))
+	drags := Drags(solution, &scramble, g)
+	if *out == "" {
+		return WriteDrags(os.Stdout, drags, format, *delay, *countdown, comment)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := WriteDrags(f, drags, format, *delay, *countdown, comment); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if format == XdotoolDrags {
+		return os.Chmod(*out, 0o755)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultAutosaveEvery is how many moves are made between autosaves by default.
+const DefaultAutosaveEvery = 10
+
+// Autosave keeps the position of a game saved as a replay file while solving, so that a solve interrupted by a crash or a closed terminal can be recovered.
+type Autosave struct {
+	Path string
+	// Every is how many moves, undos and redos are made between saves.
+	Every int
+
+	pending int
+}
+
+// AutosavePath returns the path of the autosave file in the data directory.
+func AutosavePath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "autosave.replay"), nil
+}
+
+// Save writes the game's scramble and the moves leading to its current position to the autosave file.
+// The file is written next to it first and then renamed, so a crash while saving doesn't lose the last save.
+func (a *Autosave) Save(g *Game) error {
+	a.pending = 0
+
+	r := g.Replay()
+	n := len(g.History.Path())
+	r.Moves, r.Comments = r.Moves[:n], r.Comments[:n+1]
+
+	if err := os.MkdirAll(filepath.Dir(a.Path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := a.Path + ".tmp"
+	if err := SaveReplay(tmp, r); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, a.Path)
+}
+
+// Remove deletes the autosave file, once there's no solve left to recover.
+func (a *Autosave) Remove() error {
+	a.pending = 0
+
+	if err := os.Remove(a.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// autosave counts a move towards the next autosave, saving the game once enough were made, or removes the autosave if the board is solved.
+func (g *Game) autosave() {
+	a := g.Autosave
+	if a == nil {
+		return
+	}
+
+	var err error
+	if a.pending++; g.Board.IsSolved() {
+		err = a.Remove()
+	} else if a.pending >= a.Every {
+		err = a.Save(g)
+	}
+
+	if err != nil {
+		// a broken autosave shouldn't get in the way of playing, so stop autosaving instead.
+		logger.Warn("autosave failed, no longer autosaving", "path", a.Path, "err", err)
+		g.Autosave = nil
+	}
+}
+
+// RecoverAutosave loads the solve saved in the autosave file at `path` into a new game, with its moves made again.
+// Returns nil and no error if there is nothing to recover.
+func RecoverAutosave(path string) (*Game, error) {
+	r, err := LoadReplayFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := NewGame(r.Scramble.Width(), r.Scramble.Height())
+	if err != nil {
+		return nil, err
+	}
+
+	g.LoadReplay(r)
+	for range r.Moves {
+		g.Redo(-1)
+	}
+
+	return g, nil
+}
+
+// scanRecoverAutosave offers to recover the solve saved in the autosave file at `path`, discarding it if declined.
+// Returns nil if there was nothing to recover or it wasn't recovered.
+func scanRecoverAutosave(con *Console, path string) *Game {
+	g, err := RecoverAutosave(path)
+	if err != nil {
+		fmt.Fprintf(con, "Could not recover the interrupted solve (%s)\n", err)
+		return nil
+	}
+	if g == nil {
+		return nil
+	}
+
+	q := fmt.Sprintf("Recover the interrupted %dx%d solve at %d moves? [y/N]: ", g.Board.Width(), g.Board.Height(), g.Moves)
+	if !ScanConfirm(con, q) {
+		os.Remove(path)
+		return nil
+	}
+
+	fmt.Fprintln(con, "Recovered the interrupted solve, its timer starts again with your next move")
+	return g
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "nd",
+		Summary: "play an experimental Loopover board with 3 dimensions, like 3x3x3",
+		Run:     runND,
+	})
+}
+
+/* Moves on boards with more dimensions
+move-nd = amount axis-nd number { "." number }
+axis-nd = horizontal-axis | vertical-axis | depth-axis
+depth-axis = "P" | "p"
+
+The numbers are the coordinates of the line being shifted on every other axis, in order. On a 3D board, "1R0.2" shifts the row at y 0 of layer 2 right, "1C1.2" the column at x 1 of layer 2 down, and "1P0.1" the pillar at x 0 and y 1 one layer deeper.
+On a 2D board this is the usual notation.
+*/
+
+// axisNames holds the letter of every axis of a BoardND, in order: R shifts along x, C along y and P (for pillar) along the depth.
+const axisNames = "RCP"
+
+// BoardND is an experimental Loopover board with 2 or 3 dimensions, where moves shift a line of tiles along one axis and wrap around.
+type BoardND struct {
+	Dims []int
+	// Tiles holds the tiles ordered by x, then y, then depth, the way they are numbered when solved.
+	Tiles []int
+}
+
+// MoveND is a move on a BoardND, shifting the line along Axis that goes through the coordinates Line on the other axes.
+type MoveND struct {
+	Axis   int
+	Line   []int
+	Amount int
+}
+
+// NewBoardND creates a new solved BoardND with the given dimensions.
+func NewBoardND(dims ...int) (*BoardND, error) {
+	if len(dims) < 2 || len(dims) > len(axisNames) {
+		return nil, fmt.Errorf("boards must have between 2 and %d dimensions", len(axisNames))
+	}
+
+	n := 1
+	for _, d := range dims {
+		if d <= 1 {
+			return nil, fmt.Errorf("board dimensions must be greater than 1")
+		}
+		n *= d
+	}
+
+	b := &BoardND{Dims: dims, Tiles: make([]int, n)}
+	b.Reset()
+
+	return b, nil
+}
+
+// ParseDimensions parses dimensions like "3x3x3".
+func ParseDimensions(input string) ([]int, error) {
+	if len(input) == 0 {
+		return nil, fmt.Errorf("empty input")
+	}
+
+	var dims []int
+	for _, s := range strings.FieldsFunc(input, func(r rune) bool { return r == 'x' || r == 'X' || r == '*' }) {
+		d, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q doesn't seem to be a valid dimension", input)
+		}
+		dims = append(dims, d)
+	}
+
+	return dims, nil
+}
+
+// Reset puts every tile back in its place.
+func (b *BoardND) Reset() {
+	for i := range b.Tiles {
+		b.Tiles[i] = i + 1
+	}
+}
+
+// IsSolved returns true if all tiles are in order.
+func (b *BoardND) IsSolved() bool {
+	for i, t := range b.Tiles {
+		if t != i+1 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// coords returns the coordinates of the tile at index `i` of Tiles.
+func (b *BoardND) coords(i int) []int {
+	c := make([]int, len(b.Dims))
+	for a, d := range b.Dims {
+		c[a] = i % d
+		i /= d
+	}
+
+	return c
+}
+
+// index returns the index in Tiles of the tile at the coordinates `c`.
+func (b *BoardND) index(c []int) int {
+	var i int
+	for a := len(b.Dims) - 1; a >= 0; a-- {
+		i = i*b.Dims[a] + c[a]
+	}
+
+	return i
+}
+
+// MakeMove makes a move on the board, returning how many single shifts it took.
+func (b *BoardND) MakeMove(m *MoveND) int {
+	l := b.Dims[m.Axis]
+
+	// the line's coordinates, with the move's axis left to be filled in.
+	c := make([]int, 0, len(b.Dims))
+	c = append(c, m.Line[:m.Axis]...)
+	c = append(c, 0)
+	c = append(c, m.Line[m.Axis:]...)
+
+	line := make([]int, l)
+	for i := range line {
+		c[m.Axis] = i
+		line[i] = b.Tiles[b.index(c)]
+	}
+
+	shift := ((m.Amount % l) + l) % l
+	for i, t := range line {
+		c[m.Axis] = (i + shift) % l
+		b.Tiles[b.index(c)] = t
+	}
+
+	return Abs(m.Amount)
+}
+
+// UnitMoves returns every move that shifts a line by a single tile.
+// Lines of length 2 are only shifted forward, since shifting them backward is the same.
+func (b *BoardND) UnitMoves() []MoveND {
+	var moves []MoveND
+	for a, l := range b.Dims {
+		others := make([]int, 0, len(b.Dims)-1)
+		others = append(others, b.Dims[:a]...)
+		others = append(others, b.Dims[a+1:]...)
+
+		amounts := []int{1, -1}
+		if l == 2 {
+			amounts = amounts[:1]
+		}
+
+		// go through the coordinates of every line along the axis.
+		line := make([]int, len(others))
+		for {
+			for _, amnt := range amounts {
+				moves = append(moves, MoveND{Axis: a, Line: append([]int(nil), line...), Amount: amnt})
+			}
+
+			i := 0
+			for ; i < len(line); i++ {
+				line[i]++
+				if line[i] < others[i] {
+					break
+				}
+				line[i] = 0
+			}
+			if i == len(line) {
+				break
+			}
+		}
+	}
+
+	return moves
+}
+
+// Shuffle shuffles the board by making `iterations` random single shifts, or ShuffleScale per tile if `iterations` is less or equal to 0.
+func (b *BoardND) Shuffle(iterations int) int {
+	if iterations <= 0 {
+		iterations = ShuffleScale * len(b.Tiles)
+	}
+
+	moves := b.UnitMoves()
+	for i := 0; i < iterations; i++ {
+		b.MakeMove(&moves[rng.Intn(len(moves))])
+	}
+
+	return iterations
+}
+
+// Distance returns the sum of how many single shifts every tile is away from its place, like Board's Distance.
+func (b *BoardND) Distance() int {
+	var d int
+	for i, t := range b.Tiles {
+		c, home := b.coords(i), b.coords(t-1)
+
+		for a, l := range b.Dims {
+			da := Abs(c[a] - home[a])
+			if da > l-da {
+				da = l - da
+			}
+			d += da
+		}
+	}
+
+	return d
+}
+
+// LowerBound returns a number of moves that solving the board takes at least, like the LowerBound of a Board.
+func (b *BoardND) LowerBound() int {
+	l := 0
+	for _, d := range b.Dims {
+		if d > l {
+			l = d
+		}
+	}
+
+	return (b.Distance() + l - 1) / l
+}
+
+// SolveWithin looks for the shortest solution of at most `maxMoves` single shifts with the same search as the SolveWithin of a Board.
+// Returns false if there is none within `maxMoves`.
+func (b *BoardND) SolveWithin(maxMoves int) ([]MoveND, *SolveResult, bool) {
+	p := &boardNDPuzzle{board: &BoardND{Dims: b.Dims, Tiles: append([]int(nil), b.Tiles...)}, moves: b.UnitMoves()}
+
+	res, path, found := searchWithin(p, maxMoves, nil)
+
+	var seq []MoveND
+	for _, i := range path {
+		seq = append(seq, p.moves[i])
+	}
+
+	return seq, res, found
+}
+
+// boardNDPuzzle is a puzzle on a BoardND, with its single shifts.
+type boardNDPuzzle struct {
+	board *BoardND
+	moves []MoveND
+}
+
+func (p *boardNDPuzzle) IsSolved() bool  { return p.board.IsSolved() }
+func (p *boardNDPuzzle) LowerBound() int { return p.board.LowerBound() }
+func (p *boardNDPuzzle) Moves() int      { return len(p.moves) }
+func (p *boardNDPuzzle) Make(i int)      { p.board.MakeMove(&p.moves[i]) }
+
+func (p *boardNDPuzzle) Unmake(i int) {
+	m := p.moves[i]
+	m.Amount = -m.Amount
+	p.board.MakeMove(&m)
+}
+
+// Redundant returns true if both moves shift the same line in opposite directions, or if the run of shifts goes past half of the line.
+func (p *boardNDPuzzle) Redundant(last, run, i int) bool {
+	l, m := p.moves[last], p.moves[i]
+	if l.Axis != m.Axis {
+		return false
+	}
+	for j := range l.Line {
+		if l.Line[j] != m.Line[j] {
+			return false
+		}
+	}
+
+	return redundantShift(l.Amount, run, m.Amount, p.board.Dims[m.Axis])
+}
+
+// String formats the move in the notation for boards with more dimensions, such as "-1R0.2".
+func (m MoveND) String() string {
+	line := make([]string, len(m.Line))
+	for i, c := range m.Line {
+		line[i] = strconv.Itoa(c)
+	}
+
+	return strconv.Itoa(m.Amount) + string(axisNames[m.Axis]) + strings.Join(line, ".")
+}
+
+// ParseMoveND parses a move in the notation for boards with more dimensions, such as "-1R0.2".
+func ParseMoveND(input string, b *BoardND) (*MoveND, error) {
+	ai := strings.IndexAny(strings.ToUpper(input), axisNames[:len(b.Dims)])
+	if ai == -1 {
+		return nil, fmt.Errorf("no move character in move %q", input)
+	}
+
+	amount, err := strconv.Atoi(input[:ai])
+	if err != nil || amount == 0 {
+		return nil, fmt.Errorf("invalid amount in move %q", input)
+	}
+
+	m := &MoveND{Axis: strings.IndexByte(axisNames, strings.ToUpper(input)[ai]), Amount: amount}
+
+	coords := strings.Split(input[ai+1:], ".")
+	if len(coords) != len(b.Dims)-1 {
+		return nil, fmt.Errorf("move %q needs %d coordinates", input, len(b.Dims)-1)
+	}
+
+	for i, s := range coords {
+		c, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coordinate %q in move %q", s, input)
+		}
+
+		// the coordinates skip the move's axis.
+		a := i
+		if a >= m.Axis {
+			a++
+		}
+		if c < 0 || c >= b.Dims[a] {
+			return nil, fmt.Errorf("coordinate %d must be between 0 and %d in move %q", c, b.Dims[a]-1, input)
+		}
+
+		m.Line = append(m.Line, c)
+	}
+
+	return m, nil
+}
+
+// SprintBoardND formats the board as its layers stacked one after the other, each printed like SprintBoard prints a Board.
+func SprintBoardND(b *BoardND) string {
+	w, h := b.Dims[0], b.Dims[1]
+	pad := strconv.Itoa(len(strconv.Itoa(len(b.Tiles))))
+
+	var sb strings.Builder
+	layers := len(b.Tiles) / (w * h)
+	for z := 0; z < layers; z++ {
+		if layers > 1 {
+			fmt.Fprintf(&sb, "Layer %d:\n", z)
+		}
+
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				fmt.Fprintf(&sb, " %"+pad+"d", b.Tiles[z*w*h+y*w+x])
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// runND runs the nd command, a minimal interactive game on a BoardND.
+func runND(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: nd SIZE, like nd 3x3x3")
+	}
+
+	dims, err := ParseDimensions(args[0])
+	if err != nil {
+		return err
+	}
+
+	b, err := NewBoardND(dims...)
+	if err != nil {
+		return err
+	}
+
+	con := NewConsole(os.Stdin, os.Stdout)
+	var moves int
+	for {
+		fmt.Fprintln(con)
+		fmt.Fprintln(con, "Board state:")
+		fmt.Fprintln(con, SprintBoardND(b))
+		fmt.Fprintf(con, "%d moves so far\n", moves)
+		if b.IsSolved() {
+			fmt.Fprintln(con, "Solved")
+		}
+
+		fmt.Fprint(con, "Move: ")
+		scanned := false
+		for con.Scan() {
+			cmd, arg := SplitCommand(con.Text())
+
+			switch strings.ToLower(cmd) {
+			case "shuffle":
+				fmt.Fprintf(con, "Shuffled board with %d iterations\n", b.Shuffle(0))
+				moves = 0
+			case "reset":
+				b.Reset()
+				moves = 0
+				fmt.Fprintln(con, "Board reset")
+			case "solve":
+				maxMoves, err := strconv.Atoi(arg)
+				if err != nil || maxMoves < 0 {
+					fmt.Fprint(con, "Usage is \"solve N\" with N the most moves to look for a solution in, try again: ")
+					continue
+				}
+
+				seq, res, ok := b.SolveWithin(maxMoves)
+				if !ok {
+					fmt.Fprintf(con, "No solution in %d moves or less (%s)\n", maxMoves, res.Time.Round(time.Millisecond))
+					break
+				}
+
+				s := make([]string, len(seq))
+				for i, m := range seq {
+					s[i] = m.String()
+				}
+				fmt.Fprintf(con, "Shortest solution (%d moves): %s\n", len(seq), strings.Join(s, " "))
+			default:
+				m, err := ParseMoveND(con.Text(), b)
+				if err != nil {
+					fmt.Fprintf(con, "Invalid move (%s), try again: ", err)
+					continue
+				}
+
+				moves += b.MakeMove(m)
+			}
+
+			scanned = true
+			break
+		}
+
+		if !scanned {
+			fmt.Fprintln(con)
+			return con.Err()
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+)
+
+// MaxSearchPositions is the most positions a breadth-first search keeps in memory before giving up.
+var MaxSearchPositions = 1000000
+
+// Challenge is a position that takes exactly Depth single shifts to solve.
+type Challenge struct {
+	Board Board
+	Depth int
+}
+
+// NewChallenge returns a random width*height position that takes exactly `depth` single shifts to solve, using only the moves `r` allows.
+// Positions are found by a breadth-first search from the solved board, so only small boards and depths are feasible.
+func NewChallenge(width, height, depth int, r *Restrictions) (*Challenge, error) {
+	b, err := NewBoard(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	frontier, err := searchLayer(b, depth, r)
+	if err != nil {
+		return nil, err
+	}
+	if len(frontier) == 0 {
+		return nil, fmt.Errorf("no %dx%d position takes %d moves to solve", width, height, depth)
+	}
+
+	return &Challenge{Board: frontier[rng.Intn(len(frontier))], Depth: depth}, nil
+}
+
+// Check returns nil if making the moves of `seq` on the challenge's position solves it in Depth single shifts, which is the least possible.
+func (c *Challenge) Check(seq []Move) error {
+	b := c.Board.Clone()
+	for i := range seq {
+		b.MakeMove(&seq[i])
+	}
+
+	if !b.IsSolved() {
+		return fmt.Errorf("the moves don't solve the board")
+	}
+	if n := MovesLength(seq); n > c.Depth {
+		return fmt.Errorf("solved in %d moves, but it can be done in %d", n, c.Depth)
+	}
+
+	return nil
+}
+
+// UnitMoves returns every move in AllMoves that shifts a slice by a single tile.
+func UnitMoves(b *Board) []Move {
+	all := AllMoves(b)
+
+	moves := all[:0]
+	for _, m := range all {
+		if Abs(m.Amount) == 1 {
+			moves = append(moves, m)
+		}
+	}
+
+	return moves
+}
+
+// searchLayer returns every position that takes exactly `depth` single shifts allowed by `r` to reach from `start`, found with a breadth-first search.
+func searchLayer(start Board, depth int, r *Restrictions) ([]Board, error) {
+	moves := r.Filter(UnitMoves(&start))
+	visited := newVisitedSet(&start)
+	visited.Add(&start)
+
+	frontier := []Board{start}
+	for d := 0; d < depth && len(frontier) != 0; d++ {
+		var next []Board
+		for _, b := range frontier {
+			for i := range moves {
+				n := b.Applied(&moves[i])
+
+				if !visited.Add(&n) {
+					continue
+				}
+				next = append(next, n)
+
+				if visited.Len > MaxSearchPositions {
+					return nil, fmt.Errorf("search went over %d positions, try a smaller board or depth", MaxSearchPositions)
+				}
+			}
+		}
+
+		frontier = next
+	}
+
+	return frontier, nil
+}
+
+// visitedSet is the set of positions a search went through.
+// Positions of boards with up to MaxTablebaseTiles tiles are kept in a bitset indexed by their rank, and others in a map of their keys.
+type visitedSet struct {
+	// Len is how many positions are in the set.
+	Len int
+
+	layout *PackedLayout
+	ranks  Bitset
+	keys   map[string]bool
+}
+
+// newVisitedSet returns an empty set for positions of boards the size of `b`.
+func newVisitedSet(b *Board) *visitedSet {
+	w, h := b.Width(), b.Height()
+	if w*h > MaxTablebaseTiles {
+		return &visitedSet{keys: map[string]bool{}}
+	}
+
+	l, _ := NewPackedLayout(w, h)
+	return &visitedSet{layout: l, ranks: NewBitset(factorial(w * h))}
+}
+
+// Add adds a position to the set, returning false if it was already in it.
+func (v *visitedSet) Add(b *Board) bool {
+	if v.layout != nil {
+		if !v.ranks.Add(v.layout.Rank(v.layout.Pack(b))) {
+			return false
+		}
+	} else {
+		k := boardKey(b)
+		if v.keys[k] {
+			return false
+		}
+		v.keys[k] = true
+	}
+
+	v.Len++
+	return true
+}
+
+// boardKey returns a compact string identifying the board's tiles, for use as a map key.
+// Boards must have the same dimensions for their keys to be comparable.
+func boardKey(b *Board) string {
+	key := make([]byte, 0, 2*b.Width()*b.Height())
+	for x := range *b {
+		for _, t := range (*b)[x] {
+			key = append(key, byte(t), byte(t>>8))
+		}
+	}
+
+	return string(key)
+}
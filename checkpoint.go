@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Checkpoint is a bookmarked position of a game, which can be jumped back to later in the session.
+type Checkpoint struct {
+	Board    Board
+	Scramble Board
+	Moves    int
+	// History and Node are the history of the game and the move in it that led to the position, so undoing keeps working after jumping back.
+	History *History
+	Node    *HistoryNode
+}
+
+// SaveCheckpoint bookmarks the current position under `name`, replacing any checkpoint with the same name.
+func (g *Game) SaveCheckpoint(name string) {
+	if g.Checkpoints == nil {
+		g.Checkpoints = map[string]*Checkpoint{}
+	}
+
+	g.Checkpoints[name] = &Checkpoint{
+		Board:    g.Board.Clone(),
+		Scramble: g.Scramble.Clone(),
+		Moves:    g.Moves,
+		History:  g.History,
+		Node:     g.History.Current,
+	}
+}
+
+// RestoreCheckpoint jumps back to the position bookmarked under `name`, along with its scramble, move count and history.
+// Races and survival games end, since the position doesn't come from playing them.
+func (g *Game) RestoreCheckpoint(name string) error {
+	c, ok := g.Checkpoints[name]
+	if !ok {
+		return fmt.Errorf("no checkpoint named %q", name)
+	}
+
+	g.Race = nil
+	g.StopSurvival()
+
+	g.Board = c.Board.Clone()
+	g.Scramble = c.Scramble.Clone()
+	g.Moves = c.Moves
+	g.History = c.History
+	g.History.Current = c.Node
+	g.rememberSolvedSlices()
+	g.fitGoal()
+	g.Rig.State(&g.Board)
+
+	return nil
+}
+
+// CheckpointNames returns the names of every checkpoint, sorted.
+func (g *Game) CheckpointNames() []string {
+	names := make([]string, 0, len(g.Checkpoints))
+	for name := range g.Checkpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommands returns the commands that write to and read from the system clipboard: pbcopy and pbpaste on macOS,
+// PowerShell on Windows, and wl-copy and wl-paste on Wayland or xclip on X11 elsewhere.
+func clipboardCommands() (copyCmd, pasteCmd []string, err error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"pbcopy"}, []string{"pbpaste"}, nil
+	case "windows":
+		return []string{"clip"}, []string{"powershell", "-NoProfile", "-Command", "Get-Clipboard"}, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			return []string{"wl-copy"}, []string{"wl-paste", "--no-newline"}, nil
+		}
+		return []string{"xclip", "-selection", "clipboard"}, []string{"xclip", "-selection", "clipboard", "-o"}, nil
+	default:
+		return nil, nil, fmt.Errorf("the clipboard is not available on %s", runtime.GOOS)
+	}
+}
+
+// CopyToClipboard puts text on the system clipboard.
+func CopyToClipboard(text string) error {
+	args, _, err := clipboardCommands()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// PasteFromClipboard returns the text on the system clipboard, without surrounding spaces.
+func PasteFromClipboard() (string, error) {
+	_, args, err := clipboardCommands()
+	if err != nil {
+		return "", err
+	}
+
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", args[0], err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ParsePasted reads a board from pasted text: either a state code, or moves that scramble a solved board of the same size as `b`, like scrambles shared as moves.
+func ParsePasted(text string, b *Board) (Board, error) {
+	if strings.Contains(text, ":") {
+		return DecodeState(text)
+	}
+
+	nb, _ := NewBoard(b.Width(), b.Height())
+	seq, err := ParseMoves(strings.Join(strings.Fields(text), " "), &nb)
+	if err != nil {
+		return nil, fmt.Errorf("expected a state code or moves (%s)", err)
+	}
+	nb.ApplyMoves(seq)
+
+	return nb, nil
+}
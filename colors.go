@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ColorScheme is how the tiles of the board are colored on the terminal, with ANSI escape codes.
+type ColorScheme int
+
+const (
+	// NoColors leaves the board uncolored.
+	NoColors ColorScheme = iota
+	// PlacedColors colors the tiles that are where they belong green.
+	PlacedColors
+	// RowColors colors every tile by the row it belongs in, like the web version of Loopover, so that rows can be told apart at a glance.
+	RowColors
+)
+
+// rowColors are the ANSI foreground colors of the rows of RowColors, from the top, repeating on taller boards.
+var rowColors = []int{31, 33, 32, 36, 34, 35}
+
+func (c ColorScheme) String() string {
+	switch c {
+	case NoColors:
+		return "none"
+	case PlacedColors:
+		return "placed"
+	case RowColors:
+		return "rows"
+	default:
+		return fmt.Sprintf("ColorScheme(%d)", int(c))
+	}
+}
+
+// ParseColorScheme parses the name of a color scheme, as returned by ColorScheme.String.
+func ParseColorScheme(s string) (ColorScheme, error) {
+	for c := NoColors; c <= RowColors; c++ {
+		if strings.EqualFold(s, c.String()) {
+			return c, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown color scheme %q, expected none, placed or rows", s)
+}
+
+// SprintBoard formats the board like SprintBoard, with its tiles colored.
+func (c ColorScheme) SprintBoard(b *Board) string {
+	return c.SprintBoardFlash(b, nil)
+}
+
+// SprintBoardFlash formats the board like SprintBoard, with the tiles for which `flash` returns true in reverse video to draw attention to them.
+// Boards without colors are never flashed.
+func (c ColorScheme) SprintBoardFlash(b *Board, flash func(x, y int) bool) string {
+	return c.SprintGoalBoard(b, nil, flash)
+}
+
+// SprintGoalBoard formats the board like SprintBoardFlash, with its tiles numbered as in the goal, and colored by where they belong in it.
+func (c ColorScheme) SprintGoalBoard(b *Board, goal *Goal, flash func(x, y int) bool) string {
+	if c == NoColors {
+		l := goal.Label(b)
+		return SprintBoard(&l)
+	}
+
+	var sb strings.Builder
+	pad := len(strconv.Itoa(b.Width() * b.Height()))
+	for y := 0; y < b.Height(); y++ {
+		for x := 0; x < b.Width(); x++ {
+			t := (*b)[x][y]
+
+			code := 0
+			switch c {
+			case PlacedColors:
+				if t == b.defaultTileValue(x, y) {
+					code = 32
+				}
+			case RowColors:
+				code = rowColors[((t-1)/b.Width())%len(rowColors)]
+			}
+
+			codes := []string{}
+			if code != 0 {
+				codes = append(codes, strconv.Itoa(code))
+			}
+			if flash != nil && flash(x, y) {
+				codes = append(codes, "7")
+			}
+
+			if len(codes) == 0 {
+				fmt.Fprintf(&sb, " %*d", pad, goal.Tile(t))
+			} else {
+				fmt.Fprintf(&sb, " \x1b[%sm%*d\x1b[0m", strings.Join(codes, ";"), pad, goal.Tile(t))
+			}
+		}
+
+		if y != b.Height()-1 {
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
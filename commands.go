@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Command is a non-interactive subcommand, ran by passing its name as the first argument to the program.
+type Command struct {
+	Name string
+	// Summary is a one line description of the command.
+	Summary string
+	// Run runs the command with the rest of the arguments.
+	Run func(args []string) error
+}
+
+// commands holds all subcommands by name.
+var commands = map[string]Command{}
+
+// registerCommand adds a subcommand. It is meant to be called from init functions.
+func registerCommand(c Command) {
+	commands[c.Name] = c
+}
+
+// RunCommand runs the subcommand with the given name.
+func RunCommand(name string, args []string) error {
+	c, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q, available commands are:\n%s", name, sprintCommands())
+	}
+
+	return c.Run(args)
+}
+
+// sprintCommands formats the names and summaries of all subcommands, one per line.
+func sprintCommands() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = fmt.Sprintf("  %-12s %s", name, commands[name].Summary)
+	}
+
+	return strings.Join(lines, "\n")
+}
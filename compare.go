@@ -0,0 +1,267 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "compare",
+		Summary: "check two solutions to a scramble and show where they diverge",
+		Run:     runCompare,
+	})
+}
+
+// SliceMoves returns how many moves a sequence makes when every run of moves on the same slice counts as one, the way slice turn metrics count moves.
+func SliceMoves(seq []Move, b *Board) int {
+	var n int
+	for i, m := range seq {
+		if b.NormalizeMove(m).Amount == 0 {
+			continue
+		}
+		if i != 0 && seq[i-1].Axis == m.Axis && seq[i-1].Index == m.Index {
+			continue
+		}
+		n++
+	}
+
+	return n
+}
+
+// unitShifts splits the moves of a sequence into their single shifts, in normalized form.
+func unitShifts(seq []Move, b *Board) []Move {
+	var shifts []Move
+	for _, m := range seq {
+		m = b.NormalizeMove(m)
+
+		unit := Move{Axis: m.Axis, Index: m.Index, Amount: 1}
+		if m.Amount < 0 {
+			unit.Amount = -1
+		}
+		for i := 0; i < Abs(m.Amount); i++ {
+			shifts = append(shifts, unit)
+		}
+	}
+
+	return shifts
+}
+
+// Divergence returns how many single shifts two solutions to the same scramble take before the positions they reach differ.
+// Solutions that only make the same moves in a different order diverge once, and then meet again, so only the first divergence is found.
+// Returns -1 if they never diverge.
+func Divergence(scramble *Board, a, b []Move) int {
+	sa, sb := unitShifts(a, scramble), unitShifts(b, scramble)
+	pa, pb := scramble.Clone(), scramble.Clone()
+
+	for i := 0; i < len(sa) || i < len(sb); i++ {
+		if i < len(sa) {
+			pa.MakeMove(&sa[i])
+		}
+		if i < len(sb) {
+			pb.MakeMove(&sb[i])
+		}
+
+		if !pa.Equal(pb) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// sprintSideBySide formats boards next to each other, each under its title.
+func sprintSideBySide(titles []string, boards []Board) string {
+	max := boards[0].Width() * boards[0].Height()
+
+	var columns [][]string
+	width := 0
+	for i := range boards {
+		lines := append([]string{titles[i]}, strings.Split(sprintBoardUpTo(&boards[i], max), "\n")...)
+		for _, l := range lines {
+			if len(l) > width {
+				width = len(l)
+			}
+		}
+		columns = append(columns, lines)
+	}
+
+	var sb strings.Builder
+	for row := range columns[0] {
+		for i, c := range columns {
+			if i != len(columns)-1 {
+				fmt.Fprintf(&sb, "%-*s   ", width, c[row])
+			} else {
+				sb.WriteString(c[row])
+			}
+		}
+		if row != len(columns[0])-1 {
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// readScrambleFile reads a file holding either the state code of a scramble, or the moves in a notation that make it from a solved board of the given size.
+func readScrambleFile(path, size string, n Notation) (Board, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimSpace(string(data))
+	if strings.Contains(text, ":") {
+		b, err := DecodeState(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		return b, nil
+	}
+
+	w, h, err := ParseTwoDimensions(size)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewBoard(w, h)
+	if err != nil {
+		return nil, err
+	}
+
+	seq, err := ParseMovesIn(n, text, &b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if _, err := b.ApplyMoves(seq); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return b, nil
+}
+
+// readMovesFile reads a file holding a sequence of moves in a notation.
+func readMovesFile(path string, n Notation, b *Board) ([]Move, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seq, err := ParseMovesIn(n, string(data), b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return seq, nil
+}
+
+// runCompare runs the compare command.
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	scramblePath := fs.String("scramble", "", "file with the state code of the scramble, or the moves that make it from a solved board")
+	size := fs.String("size", "5x5", "board size, when the scramble is given as moves")
+	boards := fs.Bool("boards", false, "draw the boards side by side where the solutions diverge and where they end")
+	var from, f MoveFormat
+	fs.Func("from", "notation of the moves in the files: programmer, standard or english", func(s string) (err error) {
+		from.Notation, err = ParseNotation(s)
+		return err
+	})
+	fs.Func("notation", "notation to show moves in: programmer, standard or english", func(s string) (err error) {
+		f.Notation, err = ParseNotation(s)
+		return err
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: compare -scramble FILE [-size 5x5] [-from NOTATION] [-notation NOTATION] [-boards] A B")
+		fmt.Fprintln(fs.Output(), "Checks that the moves in the files A and B solve the scramble, compares their lengths and shows the first position where they diverge.")
+		fs.PrintDefaults()
+	}
+
+	// flags can come after the solution files too, like "compare a.txt b.txt -scramble s.txt".
+	var files []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(files) != 2 || *scramblePath == "" {
+		fs.Usage()
+		return fmt.Errorf("expected a scramble and two solutions")
+	}
+
+	scramble, err := readScrambleFile(*scramblePath, *size, from.Notation)
+	if err != nil {
+		return err
+	}
+
+	var solutions [2][]Move
+	var ends [2]Board
+	solved := true
+	for i, path := range files {
+		if solutions[i], err = readMovesFile(path, from.Notation, &scramble); err != nil {
+			return err
+		}
+
+		ends[i] = scramble.Clone()
+		if _, err := ends[i].ApplyMoves(solutions[i]); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		result := "solves the scramble"
+		if !ends[i].IsSolved() {
+			result, solved = "doesn't solve the scramble", false
+		}
+		fmt.Printf("%c (%s): %d single shifts, %d slice moves, %s\n", 'A'+i, path, MovesLength(solutions[i]), SliceMoves(solutions[i], &scramble), result)
+	}
+
+	d := Divergence(&scramble, solutions[0], solutions[1])
+	if d == -1 {
+		fmt.Println("The solutions go through the same positions")
+	} else {
+		sa, sb := unitShifts(solutions[0], &scramble), unitShifts(solutions[1], &scramble)
+		common := scramble.Clone()
+		common.ApplyMoves(sa[:d])
+
+		if d == 0 {
+			fmt.Println("The solutions diverge from their first single shift")
+		} else {
+			fmt.Printf("The solutions go through the same positions for %d single shifts: %s\n", d, f.FormatMoves(SimplifyMoves(sa[:d], &scramble), &scramble))
+		}
+		next := func(s []Move) string {
+			if d == len(s) {
+				return "stops"
+			}
+			return "continues with " + f.FormatMoves(s[d:d+1], &scramble)
+		}
+		fmt.Printf("Then A %s and B %s\n", next(sa), next(sb))
+
+		if *boards {
+			titles, positions := []string{"Last common position:"}, []Board{common}
+			for i, s := range [][]Move{sa, sb} {
+				if d < len(s) {
+					p := common.Clone()
+					p.MakeMove(&s[d])
+					titles, positions = append(titles, fmt.Sprintf("%c continues to:", 'A'+i)), append(positions, p)
+				}
+			}
+
+			fmt.Println()
+			fmt.Println(sprintSideBySide(titles, positions))
+		}
+	}
+
+	if *boards {
+		fmt.Println()
+		fmt.Println(sprintSideBySide([]string{"A ends with:", "B ends with:"}, ends[:]))
+	}
+
+	if !solved {
+		return fmt.Errorf("not every solution solves the scramble")
+	}
+	return nil
+}
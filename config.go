@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config holds the user's defaults for the game, saved as JSON in the data directory. The setup wizard writes it, and flags override it.
+type Config struct {
+	path string
+	// exists is true if the config was read from its file.
+	exists bool
+
+	// Size is the board size offered by default, like "5x5".
+	Size string `json:"size,omitempty"`
+	// Notation is the name of the notation moves are shown in.
+	Notation string `json:"notation,omitempty"`
+	// Colors is the name of the color scheme of the board.
+	Colors string `json:"colors,omitempty"`
+	// Player is the name races are rated and scores are recorded under.
+	Player string `json:"player,omitempty"`
+	// Presets holds the presets defined by the user, by name.
+	Presets map[string]*Preset `json:"presets,omitempty"`
+}
+
+// LoadConfig reads the config from the data directory. A missing config is empty.
+func LoadConfig() (*Config, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{path: filepath.Join(dir, "config.json")}
+
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %s", c.path, err)
+	}
+	c.exists = true
+
+	return c, nil
+}
+
+// Save writes the config to the data directory.
+func (c *Config) Save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, data, 0o644)
+}
+
+// Apply sets the settings of the config in `o`, except for the ones of the flags in `set`, which were given on the command line.
+func (c *Config) Apply(o *PlayOptions, set map[string]bool) error {
+	var err error
+	if c.Size != "" && !set["size"] {
+		if _, _, err := ParseTwoDimensions(c.Size); err != nil {
+			return fmt.Errorf("%s: invalid size: %s", c.path, err)
+		}
+		o.Size = c.Size
+	}
+	if c.Notation != "" && !set["notation"] {
+		if o.MoveFormat.Notation, err = ParseNotation(c.Notation); err != nil {
+			return fmt.Errorf("%s: %s", c.path, err)
+		}
+	}
+	if c.Colors != "" && !set["colors"] {
+		if o.Colors, err = ParseColorScheme(c.Colors); err != nil {
+			return fmt.Errorf("%s: %s", c.path, err)
+		}
+	}
+	if c.Player != "" && !set["player"] {
+		o.Player = c.Player
+	}
+
+	return nil
+}
+
+// setFlags returns the names of the flags of the command line that were given.
+func setFlags() map[string]bool {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	return set
+}
+
+// isTerminal returns true if the file is a terminal, to only ask questions to people rather than scripts.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ScanSetup runs the setup wizard, asking for the defaults of the game and a player name, offering to play the tutorial, and saving the config.
+// Empty answers keep the current settings. Returns false if the input ended before the wizard was done.
+func ScanSetup(con *Console, c *Config) bool {
+	fmt.Fprintln(con, "Welcome to Loopover! Let's set up the game, press enter to keep the setting in parentheses.")
+
+	ask := func(question, def string, valid func(string) error) (string, bool) {
+		fmt.Fprintf(con, "%s (%s): ", question, def)
+		for con.Scan() {
+			s := strings.TrimSpace(con.Text())
+			if s == "" {
+				return def, true
+			}
+			if err := valid(s); err != nil {
+				fmt.Fprintf(con, "Invalid answer (%s), try again: ", err)
+				continue
+			}
+
+			return s, true
+		}
+
+		return "", false
+	}
+	orDefault := func(s, def string) string {
+		if s == "" {
+			return def
+		}
+		return s
+	}
+
+	var ok bool
+	if c.Size, ok = ask("Default board size", orDefault(c.Size, "5x5"), func(s string) error {
+		_, _, err := ParseTwoDimensions(s)
+		return err
+	}); !ok {
+		return false
+	}
+
+	if c.Notation, ok = ask("Notation to show moves in: programmer, standard or english", orDefault(c.Notation, "programmer"), func(s string) error {
+		_, err := ParseNotation(s)
+		return err
+	}); !ok {
+		return false
+	}
+
+	sample, _ := NewBoard(3, 3)
+	sample.MakeMove(&Move{Axis: HorizontalAxis, Index: 2, Amount: 1})
+	for cs := NoColors; cs <= RowColors; cs++ {
+		fmt.Fprintf(con, "%s:\n%s\n", cs, cs.SprintBoard(&sample))
+	}
+	if c.Colors, ok = ask("Color scheme: none, placed or rows", orDefault(c.Colors, "none"), func(s string) error {
+		_, err := ParseColorScheme(s)
+		return err
+	}); !ok {
+		return false
+	}
+
+	if c.Player, ok = ask("Your name, to rate your races and record your solves under", orDefault(c.Player, "player"), func(s string) error {
+		return nil
+	}); !ok {
+		return false
+	}
+	if p, err := LoadProfiles(); err == nil {
+		p.Get(c.Player)
+		if err := p.Save(); err != nil {
+			fmt.Fprintf(con, "Could not create your profile (%s)\n", err)
+		}
+	}
+
+	if err := c.Save(); err != nil {
+		fmt.Fprintf(con, "Could not save the settings (%s)\n", err)
+	} else {
+		fmt.Fprintf(con, "Saved the settings to %s, run with -setup to change them\n", c.path)
+	}
+
+	if ScanConfirm(con, "Play the tutorial? [y/N]: ") {
+		return ScanTutorial(con)
+	}
+
+	return true
+}
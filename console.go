@@ -0,0 +1,18 @@
+package main
+
+import (
+	"bufio"
+	"io"
+)
+
+// Console is what the interactive game reads its input from, line by line, and writes its output to.
+// Games are played on the terminal, but a Console can be backed by anything else, such as a script of moves or a network connection.
+type Console struct {
+	*bufio.Scanner
+	io.Writer
+}
+
+// NewConsole creates a Console that reads lines from `r` and writes to `w`.
+func NewConsole(r io.Reader, w io.Writer) *Console {
+	return &Console{Scanner: bufio.NewScanner(r), Writer: w}
+}
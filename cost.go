@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CostModel prices moves by how long a physical puzzle, like a motorized build, takes to make them, so that solutions can be optimized for it rather than for move count.
+// A move costs Start, plus Row or Column for every single shift it makes. Consecutive moves of the same slice are made as one move.
+type CostModel struct {
+	// Row and Column are what a single shift of a row and of a column costs.
+	Row, Column float64
+	// Start is what every move costs on top of its shifts, like a robot moving over to the slice and gripping it.
+	Start float64
+}
+
+// ShiftCost is the cost model that counts single shifts, the way moves are counted while playing.
+var ShiftCost = CostModel{Row: 1, Column: 1}
+
+// ParseCostModel parses a comma separated list of costs like "row=1,column=1.5,start=2". Costs that are not listed are the ones of ShiftCost.
+func ParseCostModel(input string) (CostModel, error) {
+	c := ShiftCost
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return c, nil
+	}
+
+	for _, item := range strings.Split(strings.ToLower(input), ",") {
+		i := strings.IndexByte(item, '=')
+		if i == -1 {
+			return c, fmt.Errorf("invalid cost %q, expected a name and a cost like row=1", item)
+		}
+
+		name := strings.TrimSpace(item[:i])
+		v, err := strconv.ParseFloat(strings.TrimSpace(item[i+1:]), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return c, fmt.Errorf("invalid cost in %q", item)
+		}
+
+		switch name {
+		case "row":
+			c.Row = v
+		case "column":
+			c.Column = v
+		case "start":
+			c.Start = v
+		default:
+			return c, fmt.Errorf("unknown cost %q, expected row, column or start", name)
+		}
+	}
+
+	if c.Row <= 0 || c.Column <= 0 {
+		return c, fmt.Errorf("shifts must cost more than 0")
+	}
+	if c.Start < 0 {
+		return c, fmt.Errorf("starting a move can't cost less than 0")
+	}
+
+	return c, nil
+}
+
+func (c CostModel) String() string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	return fmt.Sprintf("row=%s,column=%s,start=%s", f(c.Row), f(c.Column), f(c.Start))
+}
+
+// shift returns what a single shift along the axis costs.
+func (c CostModel) shift(a Axis) float64 {
+	if a == HorizontalAxis {
+		return c.Row
+	}
+
+	return c.Column
+}
+
+// Cost returns what making the moves on the board costs, joining consecutive moves of the same slice into one.
+func (c CostModel) Cost(seq []Move, b *Board) float64 {
+	var cost float64
+	for i := 0; i < len(seq); {
+		m := seq[i]
+		for i++; i < len(seq) && seq[i].Axis == m.Axis && seq[i].Index == m.Index; i++ {
+			m.Amount += seq[i].Amount
+		}
+
+		if m = b.NormalizeMove(m); m.Amount != 0 {
+			cost += c.Start + float64(Abs(m.Amount))*c.shift(m.Axis)
+		}
+	}
+
+	return cost
+}
+
+// SolveCheapest looks for the cheapest solution in the cost model of at most `maxMoves` single shifts allowed by `r`, returning it along with the search statistics.
+// Like SolveWithin, solutions are searched for with iterative deepening, deepening by cost instead of by moves, so it is only feasible for short solutions.
+// Returns false if there is none within `maxMoves`.
+func SolveCheapest(b *Board, maxMoves int, c CostModel, r *Restrictions) (*SolveResult, bool) {
+	start := time.Now()
+	p := &boardPuzzle{board: b.Clone(), moves: r.Filter(UnitMoves(b))}
+	s := &costSearch{puzzle: p, cost: c, res: &SolveResult{}}
+
+	found := false
+	for limit := s.lowerBound(); limit <= math.MaxFloat64 && !found; {
+		s.next = math.Inf(1)
+		found = s.search(0, limit, maxMoves, -1, 0)
+		logger.Debug("cost search", "limit", limit, "nodes", s.res.Nodes, "found", found)
+		limit = s.next
+	}
+
+	// the path was pushed from the last move to the first.
+	for i := len(s.path) - 1; i >= 0; i-- {
+		s.res.Moves = append(s.res.Moves, p.moves[s.path[i]])
+	}
+	s.res.Depth = len(s.path)
+
+	s.res.Time = time.Since(start)
+	return s.res, found
+}
+
+// costSearch holds the state of SolveCheapest.
+type costSearch struct {
+	puzzle *boardPuzzle
+	cost   CostModel
+	path   []int
+	res    *SolveResult
+	// next is the lowest cost past the limit of the current iteration that a position was cut off at, the limit of the next iteration.
+	next float64
+}
+
+// lowerBound returns a cost that solving the current position takes at least: LowerBound single shifts of the cheapest axis.
+func (s *costSearch) lowerBound() float64 {
+	return float64(s.puzzle.LowerBound()) * math.Min(s.cost.Row, s.cost.Column)
+}
+
+// search looks for a solution costing at most `limit` in all, of at most `left` single shifts, from the current position reached at a cost of `spent`,
+// where `last` is the move that led to it, or -1 at the start, made `run` times in a row. If one is found, its moves are pushed to the path from the last one to the first.
+func (s *costSearch) search(spent, limit float64, left, last, run int) bool {
+	s.res.Nodes++
+
+	p := s.puzzle
+	if p.IsSolved() {
+		return true
+	}
+	if left == 0 {
+		return false
+	}
+	if f := spent + s.lowerBound(); f > limit {
+		s.next = math.Min(s.next, f)
+		return false
+	}
+
+	for i := 0; i < p.Moves(); i++ {
+		if last != -1 && p.Redundant(last, run, i) {
+			continue
+		}
+
+		m := p.moves[i]
+		cost := s.cost.shift(m.Axis)
+		if last == -1 || p.moves[last].Axis != m.Axis || p.moves[last].Index != m.Index {
+			cost += s.cost.Start
+		}
+		if spent+cost > limit {
+			s.next = math.Min(s.next, spent+cost)
+			continue
+		}
+
+		p.Make(i)
+		next := 1
+		if i == last {
+			next = run + 1
+		}
+		found := s.search(spent+cost, limit, left-1, i, next)
+		p.Unmake(i)
+
+		if found {
+			s.path = append(s.path, i)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ImportCSV reads a board grid from comma or tab separated values, one row of tiles per line.
+// The separator is detected from the first line.
+func ImportCSV(r io.Reader) (Board, error) {
+	br := bufio.NewReader(r)
+
+	first, err := br.Peek(br.Size())
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	if i := bytes.IndexByte(first, '\n'); i != -1 {
+		first = first[:i]
+	}
+
+	cr := csv.NewReader(br)
+	if bytes.IndexByte(first, '\t') != -1 {
+		cr.Comma = '\t'
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	h := len(records)
+	if h == 0 {
+		return nil, fmt.Errorf("empty input")
+	}
+
+	w := len(records[0])
+	tiles := make([]int, 0, w*h)
+	for y, rec := range records {
+		if len(rec) != w {
+			return nil, fmt.Errorf("row %d has %d tiles but row 1 has %d", y+1, len(rec), w)
+		}
+
+		for x, f := range rec {
+			t, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at row %d, column %d", f, y+1, x+1)
+			}
+
+			tiles = append(tiles, t)
+		}
+	}
+
+	return NewBoardFromTiles(w, h, tiles)
+}
+
+// ExportCSV writes the board grid as comma separated values, one row of tiles per line.
+func (b *Board) ExportCSV(w io.Writer) error {
+	return b.exportSeparated(w, ',')
+}
+
+// ExportTSV writes the board grid as tab separated values, one row of tiles per line.
+func (b *Board) ExportTSV(w io.Writer) error {
+	return b.exportSeparated(w, '\t')
+}
+
+// exportSeparated writes the board grid as values separated by `comma`.
+func (b *Board) exportSeparated(w io.Writer, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	rec := make([]string, b.Width())
+	for y := 0; y < b.Height(); y++ {
+		for x := range rec {
+			rec[x] = strconv.Itoa((*b)[x][y])
+		}
+
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// IsGridFile returns true if `path` has a .csv or .tsv extension.
+func IsGridFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".csv" || ext == ".tsv"
+}
+
+// ImportFile reads a board grid from a CSV or TSV file.
+func ImportFile(path string) (Board, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := ImportCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return b, nil
+}
+
+// ExportFile writes the board grid to a file, as TSV if `path` ends in .tsv and as CSV otherwise.
+func ExportFile(b *Board, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".tsv" {
+		err = b.ExportTSV(f)
+	} else {
+		err = b.ExportCSV(f)
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "generate-image",
+		Summary: "draw a PNG or SVG diagram of a board, for tutorials",
+		Run:     runGenerateImage,
+	})
+}
+
+// diagramTileSize is the width and height of a tile in diagrams, in pixels.
+const diagramTileSize = 40
+
+// DiagramStyle is how diagrams of boards are drawn.
+type DiagramStyle struct {
+	// Tile is the color of tiles, and Placed the one of tiles that are where they belong.
+	Tile, Placed color.RGBA
+	// Border is the color of the lines between tiles.
+	Border color.RGBA
+	// Text is the color of the tile labels.
+	Text color.RGBA
+	// Arrow is the color of the arrow drawn over a move.
+	Arrow color.RGBA
+	// Labels labels every tile with its number if true.
+	Labels bool
+}
+
+// DefaultDiagramStyle is the style of the diagrams in reconstructions.
+var DefaultDiagramStyle = DiagramStyle{
+	Tile:   color.RGBA{0xdd, 0xdd, 0xdd, 0xff},
+	Placed: color.RGBA{0x8f, 0xd1, 0x8f, 0xff},
+	Border: color.RGBA{0x55, 0x55, 0x55, 0xff},
+	Text:   color.RGBA{0x00, 0x00, 0x00, 0xff},
+	Arrow:  color.RGBA{0xd0, 0x30, 0x30, 0xff},
+	Labels: true,
+}
+
+// ParseColor parses a color written as a hexadecimal #rrggbb code, with or without the #.
+func ParseColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected a code like #8fd18f", s)
+	}
+
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, nil
+}
+
+// hexColor formats a color as a #rrggbb code.
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// tileColor returns the color of the tile at x, y.
+func (s DiagramStyle) tileColor(b *Board, x, y int) color.RGBA {
+	if (*b)[x][y] == b.defaultTileValue(x, y) {
+		return s.Placed
+	}
+
+	return s.Tile
+}
+
+// arrowLine returns where the arrow over a move starts and ends, in pixels, pointing the way the move shifts its slice.
+func arrowLine(b *Board, m *Move) (x1, y1, x2, y2 int) {
+	l := b.SliceLength(m.Axis) * diagramTileSize
+	c := m.Index*diagramTileSize + diagramTileSize/2
+	from, to := diagramTileSize/4, l-diagramTileSize/4
+	if m.Amount < 0 {
+		from, to = to, from
+	}
+
+	if m.Axis == HorizontalAxis {
+		return from, c, to, c
+	}
+	return c, from, c, to
+}
+
+// SVG draws the board as an SVG image, with an arrow over the slice `arrow` moves if it isn't nil.
+func (s DiagramStyle) SVG(b *Board, arrow *Move) string {
+	var sb strings.Builder
+	w, h := b.Width()*diagramTileSize, b.Height()*diagramTileSize
+
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="16">`, w, h, w, h)
+	for y := 0; y < b.Height(); y++ {
+		for x := 0; x < b.Width(); x++ {
+			px, py := x*diagramTileSize, y*diagramTileSize
+			fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="%s"/>`, px, py, diagramTileSize, diagramTileSize, hexColor(s.tileColor(b, x, y)), hexColor(s.Border))
+			if s.Labels {
+				fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="middle" dominant-baseline="central" fill="%s">%d</text>`, px+diagramTileSize/2, py+diagramTileSize/2, hexColor(s.Text), (*b)[x][y])
+			}
+		}
+	}
+
+	if arrow != nil && arrow.Amount != 0 {
+		x1, y1, x2, y2 := arrowLine(b, arrow)
+		c := hexColor(s.Arrow)
+		sb.WriteString(`<defs><marker id="head" markerWidth="4" markerHeight="4" refX="2" refY="2" orient="auto"><path d="M0,0 L4,2 L0,4 z" fill="` + c + `"/></marker></defs>`)
+		fmt.Fprintf(&sb, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="4" marker-end="url(#head)"/>`, x1, y1, x2, y2, c)
+	}
+	sb.WriteString("</svg>")
+
+	return sb.String()
+}
+
+// digitFont holds a 3x5 pixel glyph for every digit, row by row from the top.
+var digitFont = [10][5]string{
+	{"111", "101", "101", "101", "111"},
+	{"010", "110", "010", "010", "111"},
+	{"111", "001", "111", "100", "111"},
+	{"111", "001", "111", "001", "111"},
+	{"101", "101", "111", "001", "001"},
+	{"111", "100", "111", "001", "111"},
+	{"111", "100", "111", "101", "111"},
+	{"111", "001", "001", "001", "001"},
+	{"111", "101", "111", "101", "111"},
+	{"111", "101", "111", "001", "111"},
+}
+
+// digitScale is how many pixels wide every pixel of a digitFont glyph is drawn.
+const digitScale = 3
+
+// PNG draws the board as a PNG image like SVG, labeling tiles with a built-in pixel font.
+func (s DiagramStyle) PNG(w io.Writer, b *Board, arrow *Move) error {
+	return png.Encode(w, s.Image(b, arrow))
+}
+
+// Image draws the board as an image like PNG.
+func (s DiagramStyle) Image(b *Board, arrow *Move) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, b.Width()*diagramTileSize+1, b.Height()*diagramTileSize+1))
+	fill := func(x1, y1, x2, y2 int, c color.RGBA) {
+		for y := y1; y < y2; y++ {
+			for x := x1; x < x2; x++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+
+	for y := 0; y < b.Height(); y++ {
+		for x := 0; x < b.Width(); x++ {
+			px, py := x*diagramTileSize, y*diagramTileSize
+			fill(px, py, px+diagramTileSize+1, py+diagramTileSize+1, s.Border)
+			fill(px+1, py+1, px+diagramTileSize, py+diagramTileSize, s.tileColor(b, x, y))
+
+			if !s.Labels {
+				continue
+			}
+
+			label := strconv.Itoa((*b)[x][y])
+			gw, gh := 4*digitScale*len(label)-digitScale, 5*digitScale
+			lx, ly := px+(diagramTileSize-gw)/2, py+(diagramTileSize-gh)/2
+			for i, d := range label {
+				for row, bits := range digitFont[d-'0'] {
+					for col, bit := range bits {
+						if bit == '1' {
+							gx, gy := lx+(4*i+col)*digitScale, ly+row*digitScale
+							fill(gx, gy, gx+digitScale, gy+digitScale, s.Text)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if arrow != nil && arrow.Amount != 0 {
+		x1, y1, x2, y2 := arrowLine(b, arrow)
+		// the arrow is drawn by how far along and across the slice every pixel is, with its head at the end.
+		length, dir := Abs(x2-x1)+Abs(y2-y1), 1
+		if x2 < x1 || y2 < y1 {
+			dir = -1
+		}
+		set := func(along, across int) {
+			if x1 == x2 {
+				img.SetRGBA(x1+across, y1+dir*along, s.Arrow)
+			} else {
+				img.SetRGBA(x1+dir*along, y1+across, s.Arrow)
+			}
+		}
+
+		const head = 10
+		for along := 0; along < length-head; along++ {
+			for across := -2; across <= 2; across++ {
+				set(along, across)
+			}
+		}
+		for along := length - head; along < length; along++ {
+			half := length - along
+			for across := -half; across <= half; across++ {
+				set(along, across)
+			}
+		}
+	}
+
+	return img
+}
+
+// runGenerateImage runs the generate-image command.
+func runGenerateImage(args []string) error {
+	fs := flag.NewFlagSet("generate-image", flag.ContinueOnError)
+	size := fs.String("size", "5x5", "board size, when no state is given")
+	moves := fs.String("moves", "", "moves to make on the board before drawing it, like a scramble")
+	arrowStr := fs.String("arrow", "", "move to draw an arrow over, without making it")
+	asPNG := fs.Bool("png", false, "draw a PNG instead of an SVG")
+	out := fs.String("o", "", "file to write to instead of the standard output, as PNG if it ends in .png")
+	noLabels := fs.Bool("no-labels", false, "leave the tiles without their numbers")
+
+	style := DefaultDiagramStyle
+	for _, c := range []struct {
+		name, usage string
+		color       *color.RGBA
+	}{
+		{"tile-color", "color of tiles", &style.Tile},
+		{"placed-color", "color of tiles that are where they belong", &style.Placed},
+		{"border-color", "color of the lines between tiles", &style.Border},
+		{"text-color", "color of the tile numbers", &style.Text},
+		{"arrow-color", "color of the arrow", &style.Arrow},
+	} {
+		c := c
+		fs.Func(c.name, fmt.Sprintf("%s, like %s", c.usage, hexColor(*c.color)), func(s string) (err error) {
+			*c.color, err = ParseColor(s)
+			return err
+		})
+	}
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: generate-image [-size 5x5] [-moves MOVES] [-arrow MOVE] [-png] [-o FILE] [STATE]")
+		fmt.Fprintln(fs.Output(), "Draws the board with the given state code, or a solved board, after making the given moves.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	style.Labels = !*noLabels
+
+	var b Board
+	var err error
+	switch fs.NArg() {
+	case 0:
+		w, h, err := ParseTwoDimensions(*size)
+		if err != nil {
+			return err
+		}
+		if b, err = NewBoard(w, h); err != nil {
+			return err
+		}
+	case 1:
+		if b, err = DecodeState(fs.Arg(0)); err != nil {
+			return err
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("expected at most one state")
+	}
+
+	seq, err := ParseMoves(*moves, &b)
+	if err != nil {
+		return err
+	}
+	if _, err := b.ApplyMoves(seq); err != nil {
+		return err
+	}
+
+	var arrow *Move
+	if *arrowStr != "" {
+		if arrow, err = ParseMove(*arrowStr, &b); err != nil {
+			return err
+		}
+	}
+
+	write := func(w io.Writer) error {
+		if *asPNG {
+			return style.PNG(w, &b, arrow)
+		}
+
+		_, err := fmt.Fprintln(w, style.SVG(&b, arrow))
+		return err
+	}
+
+	if *out == "" {
+		return write(os.Stdout)
+	}
+
+	*asPNG = strings.ToLower(filepath.Ext(*out)) == ".png"
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ScanEdit scans user input to modify the tiles of a copy of the board, which replaces the board once it is a valid arrangement.
+// Returns true if the board was replaced.
+func ScanEdit(b *Board, con *Console) bool {
+	w, h := b.Width(), b.Height()
+	tiles := b.Tiles()
+	var row int
+
+	fmt.Fprintln(con, `Editing board. Paste the grid row by row, set a single tile with "set X Y VALUE",`)
+	fmt.Fprintln(con, `"show" the current edit, then type "done" to finish or "cancel" to discard the changes.`)
+	fmt.Fprint(con, "Edit: ")
+	for con.Scan() {
+		fields := strings.Fields(strings.ToLower(con.Text()))
+
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "cancel":
+			fmt.Fprintln(con, "Edit discarded")
+			return false
+		case fields[0] == "show":
+			eb, _ := NewBoard(w, h)
+			for i, t := range tiles {
+				eb[i%w][i/w] = t
+			}
+			fmt.Fprintln(con, SprintBoard(&eb))
+		case fields[0] == "set":
+			if len(fields) != 4 {
+				fmt.Fprint(con, "Usage is \"set X Y VALUE\", try again: ")
+				continue
+			}
+
+			var nums [3]int
+			var err error
+			for i := range nums {
+				if nums[i], err = strconv.Atoi(fields[i+1]); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				fmt.Fprint(con, "Invalid number, try again: ")
+				continue
+			}
+
+			x, y, v := nums[0], nums[1], nums[2]
+			if x < 0 || x >= w || y < 0 || y >= h {
+				fmt.Fprintf(con, "Tile (%d, %d) is outside of the %dx%d board, try again: ", x, y, w, h)
+				continue
+			}
+
+			tiles[x+y*w] = v
+		case fields[0] == "done":
+			if err := ValidateTiles(tiles, w*h); err != nil {
+				fmt.Fprintf(con, "Board is incomplete (%s), keep editing: ", err)
+				continue
+			}
+
+			eb, _ := NewBoardFromTiles(w, h, tiles)
+			if !eb.IsSolvable() && !ScanConfirm(con, "This arrangement can't be solved, keep it anyway? [y/N]: ") {
+				fmt.Fprint(con, "Keep editing: ")
+				continue
+			}
+
+			*b = eb
+			fmt.Fprintln(con, "Board edited")
+			return true
+		default:
+			if len(fields) != w {
+				fmt.Fprintf(con, "Expected a row of %d tiles, try again: ", w)
+				continue
+			}
+
+			vals := make([]int, w)
+			var err error
+			for x, f := range fields {
+				if vals[x], err = strconv.Atoi(f); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				fmt.Fprint(con, "Invalid number, try again: ")
+				continue
+			}
+
+			copy(tiles[row*w:], vals)
+			row = (row + 1) % h
+		}
+
+		fmt.Fprint(con, "Edit: ")
+	}
+
+	return false
+}
+
+// ScanConfirm asks a yes or no question and returns true if the answer is yes.
+func ScanConfirm(con *Console, question string) bool {
+	fmt.Fprint(con, question)
+	if !con.Scan() {
+		return false
+	}
+
+	s := strings.ToLower(strings.TrimSpace(con.Text()))
+	return s == "y" || s == "yes"
+}
+
+// ScanInt asks for a whole number between `min` and `max`, inclusive, until a valid one is given. An empty answer is `def`.
+// Returns false if the input ended before that.
+func ScanInt(con *Console, question string, def, min, max int) (int, bool) {
+	fmt.Fprint(con, question)
+	for con.Scan() {
+		s := strings.TrimSpace(con.Text())
+		if s == "" {
+			return def, true
+		}
+
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			fmt.Fprint(con, "Invalid number, try again: ")
+			continue
+		}
+		if n < min || n > max {
+			fmt.Fprintf(con, "Number must be between %d and %d, try again: ", min, max)
+			continue
+		}
+
+		return n, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,112 @@
+package main
+
+import "fmt"
+
+func ExampleNewBoard() {
+	b, err := NewBoard(4, 3)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(SprintBoard(&b))
+	fmt.Println(b.Width(), b.Height(), b.IsSolved())
+	// Output:
+	//  1  2  3  4
+	//   5  6  7  8
+	//   9 10 11 12
+	// 4 3 true
+}
+
+func ExampleNewBoard_invalid() {
+	_, err := NewBoard(1, 3)
+	fmt.Println(err)
+	// Output:
+	// board width must be greater than 1
+}
+
+func ExampleParseMove() {
+	b, err := NewBoard(3, 3)
+	if err != nil {
+		panic(err)
+	}
+
+	// shift the top row one tile to the right.
+	m, err := ParseMove("1R0", &b)
+	if err != nil {
+		panic(err)
+	}
+	b.MakeMove(m)
+
+	fmt.Println(m.Describe())
+	fmt.Println(SprintBoard(&b))
+	// Output:
+	// shift row 0 right by 1
+	//  3 1 2
+	//  4 5 6
+	//  7 8 9
+}
+
+func ExampleBoard_MakeMove() {
+	b, err := NewBoard(3, 3)
+	if err != nil {
+		panic(err)
+	}
+
+	// a move and its inverse cancel out.
+	seq, err := ParseMoves("2C1 -2C1", &b)
+	if err != nil {
+		panic(err)
+	}
+	for i := range seq {
+		b.MakeMove(&seq[i])
+	}
+
+	fmt.Println(b.IsSolved())
+	// Output:
+	// true
+}
+
+func ExampleSolveHuman() {
+	b, err := DecodeState("3x3:5,1,3,4,2,6,8,9,7")
+	if err != nil {
+		panic(err)
+	}
+
+	res, err := SolveHuman(&b)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := b.ApplyMoves(res.Moves); err != nil {
+		panic(err)
+	}
+
+	fmt.Println(b.IsSolved())
+	// Output:
+	// true
+}
+
+func ExampleEncodeState() {
+	b, err := NewBoard(3, 2)
+	if err != nil {
+		panic(err)
+	}
+	b.MakeMove(&Move{Axis: VerticalAxis, Index: 0, Amount: 1})
+
+	fmt.Println(EncodeState(&b))
+	// Output:
+	// 3x2:4,2,3,1,5,6
+}
+
+func ExampleDecodeState() {
+	b, err := DecodeState("3x2:4,2,3,1,5,6")
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(SprintBoard(&b))
+	fmt.Println(EncodeState(&b))
+	// Output:
+	// 4 2 3
+	//  1 5 6
+	// 3x2:4,2,3,1,5,6
+}
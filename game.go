@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Game is an interactive game on a Board, keeping track of the moves made and the time taken.
+type Game struct {
+	Board Board
+	// Moves is how many single shifts were made since the game was last restarted.
+	Moves int
+	// Start is when the first move since the game was last restarted was made, or the zero time if none was made yet.
+	Start time.Time
+
+	// Scramble is the board as it was when the game was last restarted.
+	Scramble Board
+	// History holds every move made since the game was last restarted, including undone ones.
+	History *History
+
+	// Race is the race against an engine opponent being played, if any.
+	Race *Race
+	// Ghost is the recorded solve being raced, if any.
+	Ghost *Ghost
+
+	// Challenge is the challenge being played, if any.
+	Challenge *Challenge
+
+	// Goal is the arrangement the board is solved into, if not nil. Otherwise tiles are ordered row by row.
+	Goal *Goal
+
+	// Restrictions limits which slices can be moved. nil allows every move.
+	Restrictions *Restrictions
+	// Variant changes the rules of the game, if not nil.
+	Variant Variant
+	// Score keeps the score in scoring mode, and is nil otherwise.
+	Score *Score
+	// Survival is the survival game being played, if any.
+	Survival *Survival
+
+	// Log is where the events of the session are logged, if not nil.
+	Log *SessionLog
+	// Rig mirrors the board on a physical Loopover build, if not nil.
+	Rig *Rig
+	// Autosave keeps the solve saved to recover it if the game is interrupted, if not nil.
+	Autosave *Autosave
+
+	// Checkpoints holds the positions bookmarked during the session by name. They are kept when the game restarts.
+	Checkpoints map[string]*Checkpoint
+
+	// Notifier sends a notification when the board becomes solved or the time limit is reached.
+	Notifier Notifier
+	// TimeLimit is how long a solve can take before a notification is sent. 0 means there is no limit.
+	TimeLimit     time.Duration
+	limitNotified bool
+
+	// Inspection is how long the scramble can be inspected before the solve starts, under competition rules. 0 means solves start with the first move.
+	Inspection      time.Duration
+	inspectionStart time.Time
+	// Penalty is the penalty of the solve in progress under competition rules.
+	Penalty Penalty
+
+	// Observers are told when rows and columns become solved.
+	Observers []Observer
+	// solvedSlices holds whether every row and column is solved, indexed by axis, to tell which ones a move solved.
+	solvedSlices [2][]bool
+}
+
+// NewGame creates a Game on a new Board with the given dimensions.
+func NewGame(width, height int) (*Game, error) {
+	b, err := NewBoard(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Game{Board: b, Scramble: b.Clone(), History: NewHistory()}
+	g.rememberSolvedSlices()
+
+	return g, nil
+}
+
+// Restart sets the moves done back to 0, stops the timer and clears the history, taking the current board as the new scramble.
+// It is called whenever the board is shuffled or replaced.
+func (g *Game) Restart() {
+	g.Scramble = g.Board.Clone()
+	g.Moves = 0
+	g.Start = time.Time{}
+	g.limitNotified = false
+	g.Penalty = Penalty{}
+	g.startInspection()
+	g.rememberSolvedSlices()
+	g.fitGoal()
+	g.History = NewHistory()
+	g.Race = nil
+	g.Ghost = nil
+	g.Challenge = nil
+	g.StopSurvival()
+	g.logEvent(LogEvent{Type: "scramble"})
+	g.Rig.State(&g.Board)
+	if g.Autosave != nil {
+		// the solve that was being saved was abandoned.
+		g.Autosave.Remove()
+	}
+
+	if g.Variant != nil {
+		g.Variant.Restart(&g.Board)
+	}
+	if g.Score != nil {
+		g.Score = NewScore(g.Board.Placed())
+	}
+}
+
+// CheckMove returns an error if the move can't be made under the game's restrictions and variant.
+func (g *Game) CheckMove(m *Move) error {
+	if !g.Restrictions.Allows(m) {
+		return fmt.Errorf("only %s can be moved", g.Restrictions)
+	}
+
+	if g.Variant != nil {
+		return g.Variant.Allows(&g.Board, m)
+	}
+
+	return nil
+}
+
+// MakeMove makes a move on the board, starting the timer if this is the first move. Moving during inspection starts the solve with a +2.
+func (g *Game) MakeMove(m *Move) int {
+	if g.Inspecting() {
+		g.Penalty.PlusTwos++
+		g.StartSolve()
+	} else if g.Start.IsZero() {
+		g.Start = time.Now()
+	}
+
+	n := g.Board.MakeMove(m)
+	g.Moves += n
+	g.History.Push(*m)
+	g.History.Current.Elapsed = g.Elapsed()
+	g.afterMove(m)
+
+	g.logEvent(LogEvent{Type: "move", Move: m.String()})
+	logger.Debug("move", "move", m, "moves", g.Moves, "placed", g.Board.Placed())
+	if g.Board.IsSolved() {
+		g.logEvent(LogEvent{Type: "solve"})
+	}
+
+	return n
+}
+
+// Undo undoes the last move, returning it. Returns false if there is nothing to undo.
+func (g *Game) Undo() (Move, bool) {
+	m, ok := g.History.Undo()
+	if !ok {
+		return m, false
+	}
+
+	inv := Move{Axis: m.Axis, Index: m.Index, Amount: -m.Amount}
+	g.Moves -= g.Board.MakeMove(&inv)
+	g.afterMove(&inv)
+	if g.Score != nil {
+		g.Score.Undo()
+	}
+
+	return m, true
+}
+
+// Redo makes the next move of a branch of the history again, returning it. A negative branch redoes the last played branch.
+// Returns false if there is no such branch.
+func (g *Game) Redo(branch int) (Move, bool) {
+	m, ok := g.History.Redo(branch)
+	if !ok {
+		return m, false
+	}
+
+	g.Moves += g.Board.MakeMove(&m)
+	g.afterMove(&m)
+
+	return m, true
+}
+
+// InjectMove makes a move the player didn't make, like the random moves of survival. It isn't counted or added to the history,
+// but the variant, the score, the observers, autosave and the rig see it like any other move.
+func (g *Game) InjectMove(m *Move) {
+	g.Board.MakeMove(m)
+	g.afterMove(m)
+}
+
+// afterMove lets the game's variant apply its effects after a move, scores it in scoring mode, tells the observers about the slices it solved, autosaves the solve
+// and mirrors the move on the rig.
+func (g *Game) afterMove(m *Move) {
+	if g.Variant != nil {
+		g.Variant.AfterMove(&g.Board, m)
+	}
+	if g.Score != nil {
+		g.Score.Move(g.Board.Placed())
+	}
+	g.observeSlices()
+	g.autosave()
+	g.Rig.Move(m)
+}
+
+// Elapsed returns the time since the first move was made.
+func (g *Game) Elapsed() time.Duration {
+	if g.Start.IsZero() {
+		return 0
+	}
+
+	return time.Since(g.Start)
+}
+
+// MarkSplit marks the end of a phase of the solve after the current move, naming the phase, or "Split N" if `name` is empty.
+// Returns the name of the phase, and false if no move was made yet.
+func (g *Game) MarkSplit(name string) (string, bool) {
+	if g.History.Current.Parent == nil {
+		return "", false
+	}
+
+	if name == "" {
+		n := 1
+		for node := g.History.Current; node.Parent != nil; node = node.Parent {
+			if node.Split != "" {
+				n++
+			}
+		}
+		name = fmt.Sprintf("Split %d", n)
+	}
+	g.History.Current.Split = name
+
+	return name, true
+}
+
+// Progress holds numbers describing how far along a game is.
+type Progress struct {
+	// Placed is how many tiles are where they belong, out of Total.
+	Placed int
+	Total  int
+	// Distance is the board's Distance from solved.
+	Distance int
+	// Elapsed is the time since the first move was made.
+	Elapsed time.Duration
+	// TPS is the number of moves made per second.
+	TPS float64
+}
+
+// Percent returns the percentage of tiles that are placed.
+func (p Progress) Percent() float64 {
+	return 100 * float64(p.Placed) / float64(p.Total)
+}
+
+// String formats the progress as a status line.
+func (p Progress) String() string {
+	return fmt.Sprintf("%.0f%% placed (%d/%d), distance %d, %.1fs, %.2f TPS",
+		p.Percent(), p.Placed, p.Total, p.Distance, p.Elapsed.Seconds(), p.TPS)
+}
+
+// Progress returns how far along the game is.
+func (g *Game) Progress() Progress {
+	p := Progress{
+		Placed:   g.Board.Placed(),
+		Total:    g.Board.Width() * g.Board.Height(),
+		Distance: g.Board.Distance(),
+		Elapsed:  g.Elapsed(),
+	}
+
+	if p.Elapsed > 0 {
+		p.TPS = float64(g.Moves) / p.Elapsed.Seconds()
+	}
+
+	return p
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
+
+// GamepadDriver generates moves from a gamepad, read through the joystick interface of Linux.
+// The bumpers select the previous and next row and X and Y the previous and next column, starting from row 0 and column 0.
+// The D-pad then shifts the selected row left or right, or the selected column up or down.
+type GamepadDriver struct {
+	f *os.File
+	// Row and Column are the selected slices.
+	Row, Column int
+
+	dpad [2]int16
+}
+
+// Buttons and axes of the joystick interface, numbered like those of Xbox controllers.
+const (
+	gamepadButtonX      = 2
+	gamepadButtonY      = 3
+	gamepadButtonLeft   = 4
+	gamepadButtonRight  = 5
+	gamepadAxisDPadX    = 6
+	gamepadAxisDPadY    = 7
+	gamepadEventButton  = 0x01
+	gamepadEventAxis    = 0x02
+	gamepadEventInitial = 0x80
+)
+
+// gamepadEvent is an event of the joystick interface.
+type gamepadEvent struct {
+	Time   uint32
+	Value  int16
+	Type   uint8
+	Number uint8
+}
+
+// OpenGamepad opens a joystick device, like /dev/input/js0.
+func OpenGamepad(path string) (*GamepadDriver, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("gamepads are only available on Linux")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GamepadDriver{f: f}, nil
+}
+
+func (d *GamepadDriver) Name() string {
+	return "gamepad " + d.f.Name()
+}
+
+func (d *GamepadDriver) Run(moves chan<- Move, messages io.Writer) error {
+	for {
+		var e gamepadEvent
+		if err := binary.Read(d.f, binary.LittleEndian, &e); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if e.Type&gamepadEventInitial != 0 {
+			continue
+		}
+
+		if m, ok := d.Event(e); ok {
+			moves <- m
+		} else if e.Type == gamepadEventButton && e.Value == 1 && e.Number >= gamepadButtonX && e.Number <= gamepadButtonRight {
+			fmt.Fprintf(messages, "\nGamepad: row %d and column %d selected\nMove: ", d.Row, d.Column)
+		}
+	}
+}
+
+func (d *GamepadDriver) Close() error {
+	return d.f.Close()
+}
+
+// Event handles an event, returning the move it makes, or false if it makes none.
+func (d *GamepadDriver) Event(e gamepadEvent) (Move, bool) {
+	switch e.Type {
+	case gamepadEventButton:
+		if e.Value != 1 {
+			return Move{}, false
+		}
+
+		switch e.Number {
+		case gamepadButtonLeft:
+			if d.Row > 0 {
+				d.Row--
+			}
+		case gamepadButtonRight:
+			d.Row++
+		case gamepadButtonX:
+			if d.Column > 0 {
+				d.Column--
+			}
+		case gamepadButtonY:
+			d.Column++
+		}
+	case gamepadEventAxis:
+		if e.Number != gamepadAxisDPadX && e.Number != gamepadAxisDPadY {
+			return Move{}, false
+		}
+
+		// the D-pad reports a press as the axis leaving the center, and its release as the axis going back.
+		i := e.Number - gamepadAxisDPadX
+		was := d.dpad[i]
+		d.dpad[i] = e.Value
+		if was != 0 || e.Value == 0 {
+			return Move{}, false
+		}
+
+		amount := 1
+		if e.Value < 0 {
+			amount = -1
+		}
+		if e.Number == gamepadAxisDPadX {
+			return Move{Axis: HorizontalAxis, Index: d.Row, Amount: amount}, true
+		}
+		return Move{Axis: VerticalAxis, Index: d.Column, Amount: amount}, true
+	}
+
+	return Move{}, false
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Goal is an arrangement of the tiles to solve the board into instead of ordering them row by row, like a spiral.
+// Games with a goal keep their board numbered as usual, with the tile that belongs at (x, y) in the goal numbered like the tile that usually belongs at (x, y),
+// so that the solved check, scrambles and the solvers work unchanged. Only the board shown to the player is numbered as in the goal.
+type Goal struct {
+	Name string
+	// Tiles is the board solved into the goal.
+	Tiles Board
+}
+
+// GoalTemplate is a built-in pattern of goals, for boards of any size.
+type GoalTemplate struct {
+	Name, Description string
+	// order returns the coordinates tiles 1, 2, 3 and so on belong at, in the goal of a width*height board.
+	order func(width, height int) [][2]int
+}
+
+// GoalTemplates are the built-in goals.
+var GoalTemplates = []GoalTemplate{
+	{Name: "rows", Description: "row by row from the top left, the usual goal", order: rowsGoalOrder},
+	{Name: "snake", Description: "row by row from the top left, with every other row going right to left", order: snakeGoalOrder},
+	{Name: "spiral", Description: "clockwise from the top left, spiralling inwards", order: spiralGoalOrder},
+	{Name: "checkerboard", Description: "row by row on the light squares of a checkerboard, then row by row on the dark ones", order: checkerboardGoalOrder},
+}
+
+func rowsGoalOrder(width, height int) [][2]int {
+	order := make([][2]int, 0, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			order = append(order, [2]int{x, y})
+		}
+	}
+
+	return order
+}
+
+func snakeGoalOrder(width, height int) [][2]int {
+	order := make([][2]int, 0, width*height)
+	for y := 0; y < height; y++ {
+		for i := 0; i < width; i++ {
+			x := i
+			if y%2 == 1 {
+				x = width - 1 - i
+			}
+			order = append(order, [2]int{x, y})
+		}
+	}
+
+	return order
+}
+
+func spiralGoalOrder(width, height int) [][2]int {
+	order := make([][2]int, 0, width*height)
+	left, top, right, bottom := 0, 0, width-1, height-1
+	for left <= right && top <= bottom {
+		for x := left; x <= right; x++ {
+			order = append(order, [2]int{x, top})
+		}
+		for y := top + 1; y <= bottom; y++ {
+			order = append(order, [2]int{right, y})
+		}
+		if top < bottom {
+			for x := right - 1; x >= left; x-- {
+				order = append(order, [2]int{x, bottom})
+			}
+		}
+		if left < right {
+			for y := bottom - 1; y > top; y-- {
+				order = append(order, [2]int{left, y})
+			}
+		}
+		left, top, right, bottom = left+1, top+1, right-1, bottom-1
+	}
+
+	return order
+}
+
+func checkerboardGoalOrder(width, height int) [][2]int {
+	order := make([][2]int, 0, width*height)
+	for _, parity := range []int{0, 1} {
+		for _, p := range rowsGoalOrder(width, height) {
+			if (p[0]+p[1])%2 == parity {
+				order = append(order, p)
+			}
+		}
+	}
+
+	return order
+}
+
+// NewGoal returns the goal of the template named `name` for a width*height board.
+func NewGoal(name string, width, height int) (*Goal, error) {
+	b, err := NewBoard(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range GoalTemplates {
+		if strings.EqualFold(name, t.Name) {
+			for i, p := range t.order(width, height) {
+				b[p[0]][p[1]] = i + 1
+			}
+			return &Goal{Name: t.Name, Tiles: b}, nil
+		}
+	}
+
+	names := make([]string, 0, len(GoalTemplates))
+	for _, t := range GoalTemplates {
+		names = append(names, t.Name)
+	}
+	return nil, fmt.Errorf("unknown goal %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// Tile returns the number tile `t` of a board has in the goal: the number of the tile at the place it belongs in.
+// A nil goal numbers tiles as usual.
+func (g *Goal) Tile(t int) int {
+	if g == nil {
+		return t
+	}
+
+	w := g.Tiles.Width()
+	return g.Tiles[(t-1)%w][(t-1)/w]
+}
+
+// Label returns a copy of the board with its tiles numbered as in the goal, to show it to the player.
+func (g *Goal) Label(b *Board) Board {
+	l := b.Clone()
+	for x := range l {
+		for y := range l[x] {
+			l[x][y] = g.Tile(l[x][y])
+		}
+	}
+
+	return l
+}
+
+// fitGoal rebuilds the goal of the game for the size of its board, after the board was replaced by one of another size.
+func (g *Game) fitGoal() {
+	if g.Goal == nil || g.Goal.Tiles.Width() == g.Board.Width() && g.Goal.Tiles.Height() == g.Board.Height() {
+		return
+	}
+
+	goal, err := NewGoal(g.Goal.Name, g.Board.Width(), g.Board.Height())
+	if err != nil {
+		// the board always has a valid size.
+		panic(err)
+	}
+	g.Goal = goal
+}
+
+func (g *Goal) String() string {
+	if g == nil {
+		return "rows"
+	}
+
+	return g.Name
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "graphs",
+		Summary: "graph the moves per second and phase splits of a timed replay",
+		Run:     runGraphs,
+	})
+}
+
+// PhaseSplit is how long a phase of a timed solve took.
+type PhaseSplit struct {
+	Phase
+	// Moves is how many single shifts the phase made.
+	Moves int
+	// Time is the time from the end of the previous phase, or from the first move, to the end of the phase.
+	Time time.Duration
+}
+
+// TPS returns the moves per second made during the phase.
+func (s PhaseSplit) TPS() float64 {
+	if s.Time <= 0 {
+		return 0
+	}
+
+	return float64(s.Moves) / s.Time.Seconds()
+}
+
+// PhaseSplits returns the split of every phase of a timed replay.
+func PhaseSplits(r *Replay) []PhaseSplit {
+	var splits []PhaseSplit
+	var last time.Duration
+	for _, p := range SplitPhases(r) {
+		end := r.Times[p.End-1]
+		splits = append(splits, PhaseSplit{Phase: p, Moves: phaseMoves(r, p), Time: end - last})
+		last = end
+	}
+
+	return splits
+}
+
+// TPSOverTime splits the time a timed replay took into `buckets` equal parts, and returns the moves per second made during each.
+func TPSOverTime(r *Replay, buckets int) []float64 {
+	tps := make([]float64, buckets)
+	total := r.Times[len(r.Times)-1]
+	if total <= 0 {
+		return tps
+	}
+
+	for i, t := range r.Times {
+		b := int(int64(t) * int64(buckets) / int64(total))
+		if b == buckets {
+			b--
+		}
+		tps[b] += float64(Abs(r.Moves[i].Amount))
+	}
+
+	bucket := total.Seconds() / float64(buckets)
+	for i := range tps {
+		tps[i] /= bucket
+	}
+
+	return tps
+}
+
+// maxFloat returns the highest of the values, or 0 if there are none or all are negative.
+func maxFloat(values []float64) float64 {
+	var max float64
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+
+	return max
+}
+
+// sparkBars are the bars of a sparkline, from the lowest to the highest.
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as a line of bars as high as the values are relative to the highest.
+func Sparkline(values []float64) string {
+	max := maxFloat(values)
+
+	var sb strings.Builder
+	for _, v := range values {
+		i := 0
+		if max > 0 {
+			i = int(v / max * float64(len(sparkBars)-1))
+		}
+		sb.WriteRune(sparkBars[i])
+	}
+
+	return sb.String()
+}
+
+// SprintSplits formats the splits of a solve as a table, one phase per line, followed by a sparkline of its moves per second over `buckets` parts of its time.
+func SprintSplits(r *Replay, buckets int) string {
+	splits := PhaseSplits(r)
+
+	width := len("Total")
+	for _, s := range splits {
+		if len(s.Name) > width {
+			width = len(s.Name)
+		}
+	}
+
+	var sb strings.Builder
+	var moves int
+	for _, s := range splits {
+		moves += s.Moves
+		fmt.Fprintf(&sb, "%-*s %4d moves %7.2fs %6.2f TPS\n", width, s.Name, s.Moves, s.Time.Seconds(), s.TPS())
+	}
+	total := PhaseSplit{Moves: moves, Time: r.Times[len(r.Times)-1]}
+	fmt.Fprintf(&sb, "%-*s %4d moves %7.2fs %6.2f TPS\n", width, "Total", total.Moves, total.Time.Seconds(), total.TPS())
+
+	tps := TPSOverTime(r, buckets)
+	max := maxFloat(tps)
+	fmt.Fprintf(&sb, "TPS over time (peak %.1f): %s", max, Sparkline(tps))
+
+	return sb.String()
+}
+
+// tpsChartHeight is the height of TPS charts, in pixels.
+const tpsChartHeight = 120
+
+// TPSChartPNG draws the moves per second of a timed replay over `buckets` parts of its time as a PNG bar chart, `barWidth` pixels per bar,
+// with the background of every phase in an alternating shade.
+func TPSChartPNG(w io.Writer, r *Replay, buckets, barWidth int) error {
+	var (
+		background = [2]color.RGBA{{0xf4, 0xf4, 0xf4, 0xff}, {0xe2, 0xe2, 0xe2, 0xff}}
+		bar        = color.RGBA{0x30, 0x60, 0xc0, 0xff}
+	)
+
+	tps := TPSOverTime(r, buckets)
+	max := maxFloat(tps)
+
+	img := image.NewRGBA(image.Rect(0, 0, buckets*barWidth, tpsChartHeight))
+
+	// the phases are shaded by the time they end at, which maps to pixels the same way buckets do.
+	total := r.Times[len(r.Times)-1]
+	splits := PhaseSplits(r)
+	for x := 0; x < buckets*barWidth; x++ {
+		phase := 0
+		if total > 0 {
+			t := time.Duration(int64(total) * int64(x) / int64(buckets*barWidth))
+			var end time.Duration
+			for phase < len(splits)-1 {
+				end += splits[phase].Time
+				if t < end {
+					break
+				}
+				phase++
+			}
+		}
+
+		for y := 0; y < tpsChartHeight; y++ {
+			img.SetRGBA(x, y, background[phase%2])
+		}
+	}
+
+	for i, v := range tps {
+		h := 0
+		if max > 0 {
+			h = int(v / max * (tpsChartHeight - 10))
+		}
+		for x := i * barWidth; x < (i+1)*barWidth-1; x++ {
+			for y := tpsChartHeight - h; y < tpsChartHeight; y++ {
+				img.SetRGBA(x, y, bar)
+			}
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// runGraphs runs the graphs command.
+func runGraphs(args []string) error {
+	fs := flag.NewFlagSet("graphs", flag.ContinueOnError)
+	buckets := fs.Int("buckets", 40, "how many parts to split the time of the solve into for the TPS graph")
+	out := fs.String("png", "", "file to also draw the TPS graph to, as a PNG chart")
+	barWidth := fs.Int("bar-width", 8, "width of every bar of the PNG chart, in pixels")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: graphs [-buckets 40] [-png FILE] [-bar-width 8] REPLAY")
+		fmt.Fprintln(fs.Output(), "Prints the moves, time and TPS of every phase of a timed replay, along with a graph of its TPS over time.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a replay file")
+	}
+	if *buckets < 1 || *barWidth < 2 {
+		return fmt.Errorf("there must be at least one bucket, and bars must be at least 2 pixels wide")
+	}
+
+	r, err := LoadReplayFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if r.Times == nil || len(r.Moves) == 0 {
+		return fmt.Errorf("%s isn't a timed replay", fs.Arg(0))
+	}
+
+	fmt.Println(SprintSplits(r, *buckets))
+	if *out == "" {
+		return nil
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	err = TPSChartPNG(f, r, *buckets, *barWidth)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
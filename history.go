@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HistoryNode is a move in a History, along with every line of moves that was played after it.
+type HistoryNode struct {
+	Move     Move
+	Parent   *HistoryNode
+	Children []*HistoryNode
+	// Comment is an annotation on the position reached after the move.
+	Comment string
+	// Elapsed is the time since the first move of the solve when the move was made, or 0 if it wasn't timed.
+	Elapsed time.Duration
+	// Split is the name of the phase split marked after the move, if any.
+	Split string
+
+	// active is the index of the child that was last played, which is the one redone by default.
+	active int
+}
+
+// History is a tree of the moves made in a game. Undoing moves and making different ones starts a new branch instead of discarding the undone moves.
+type History struct {
+	Root    *HistoryNode
+	Current *HistoryNode
+}
+
+// NewHistory creates an empty History.
+func NewHistory() *History {
+	root := &HistoryNode{}
+	return &History{Root: root, Current: root}
+}
+
+// Push records a move made after the current one.
+// If the same move was already played from here, its branch is followed instead of starting a new one.
+func (h *History) Push(m Move) {
+	for i, c := range h.Current.Children {
+		if c.Move == m {
+			h.Current.active = i
+			h.Current = c
+			return
+		}
+	}
+
+	h.Current.Children = append(h.Current.Children, &HistoryNode{Move: m, Parent: h.Current})
+	h.Current.active = len(h.Current.Children) - 1
+	h.Current = h.Current.Children[h.Current.active]
+}
+
+// Undo steps back one move, returning the move that has to be undone.
+// Returns false if there are no moves to undo.
+func (h *History) Undo() (Move, bool) {
+	if h.Current.Parent == nil {
+		return Move{}, false
+	}
+
+	m := h.Current.Move
+	h.Current = h.Current.Parent
+
+	return m, true
+}
+
+// Redo steps forward into a branch, returning the move that has to be made again.
+// A negative branch redoes the last played branch. Returns false if there is no such branch.
+func (h *History) Redo(branch int) (Move, bool) {
+	if branch < 0 {
+		branch = h.Current.active
+	}
+	if branch >= len(h.Current.Children) {
+		return Move{}, false
+	}
+
+	h.Current.active = branch
+	h.Current = h.Current.Children[branch]
+
+	return h.Current.Move, true
+}
+
+// Branches returns the moves that were played after the current one, and the index of the one redone by default.
+func (h *History) Branches() ([]Move, int) {
+	moves := make([]Move, len(h.Current.Children))
+	for i, c := range h.Current.Children {
+		moves[i] = c.Move
+	}
+
+	return moves, h.Current.active
+}
+
+// Path returns the moves made from the start of the history up to the current one.
+func (h *History) Path() []Move {
+	var path []Move
+	for n := h.Current; n.Parent != nil; n = n.Parent {
+		path = append(path, n.Move)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return path
+}
+
+// MainLine returns the moves made from the start of the history up to the current one, followed by the last played branches from there on.
+func (h *History) MainLine() []*HistoryNode {
+	var line []*HistoryNode
+	for n := h.Current; n.Parent != nil; n = n.Parent {
+		line = append(line, n)
+	}
+
+	for i, j := 0, len(line)-1; i < j; i, j = i+1, j-1 {
+		line[i], line[j] = line[j], line[i]
+	}
+
+	for n := h.Current; len(n.Children) != 0; {
+		n = n.Children[n.active]
+		line = append(line, n)
+	}
+
+	return line
+}
+
+// String formats the whole tree in Programmer's Notation, like Format.
+func (h *History) String() string {
+	return h.Format(MoveFormat{}, nil)
+}
+
+// Format formats the whole tree with the moves made on the board `b` written in the format `f`, one line of moves per row, with branches indented and numbered under the move they start from.
+// The current move is marked with an asterisk.
+func (h *History) Format(f MoveFormat, b *Board) string {
+	var sb strings.Builder
+	h.sprintLine(&sb, h.Root, 0, "", f, b)
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// sprintLine writes the line of moves starting at `n`, following single children until the line branches, and then every branch.
+func (h *History) sprintLine(sb *strings.Builder, n *HistoryNode, depth int, label string, f MoveFormat, b *Board) {
+	sb.WriteString(strings.Repeat("  ", depth))
+	sb.WriteString(label)
+
+	for first := true; ; first = false {
+		if !first {
+			sb.WriteString(f.separator())
+		}
+
+		if n.Parent == nil {
+			sb.WriteString("start")
+		} else {
+			sb.WriteString(f.Format(n.Move, b))
+		}
+		if n == h.Current {
+			sb.WriteString("*")
+		}
+		if n.Comment != "" {
+			fmt.Fprintf(sb, " %q", n.Comment)
+		}
+		if n.Split != "" {
+			fmt.Fprintf(sb, " [%s]", n.Split)
+		}
+
+		if len(n.Children) != 1 {
+			break
+		}
+		n = n.Children[0]
+	}
+	sb.WriteString("\n")
+
+	for i, c := range n.Children {
+		h.sprintLine(sb, c, depth+1, fmt.Sprintf("%d: ", i), f, b)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// InputDriver is a hardware controller that generates moves for the game, like a MIDI pad or a gamepad.
+type InputDriver interface {
+	// Name names the controller in messages, like "gamepad /dev/input/js0".
+	Name() string
+	// Run reads the controller until it fails or is closed, sending the moves it generates to `moves` and writing messages for the player, like what it has selected, to `messages`.
+	Run(moves chan<- Move, messages io.Writer) error
+	Close() error
+}
+
+// OpenInputDriver opens the input driver described by `spec`: "midi" or "gamepad", optionally followed by a colon and the path of the device, like "midi:/dev/snd/midiC1D0".
+// Without a path, the first MIDI device and /dev/input/js0 are used.
+func OpenInputDriver(spec string) (InputDriver, error) {
+	kind, path := spec, ""
+	if i := strings.IndexByte(spec, ':'); i != -1 {
+		kind, path = spec[:i], spec[i+1:]
+	}
+
+	switch strings.ToLower(kind) {
+	case "midi":
+		if path == "" {
+			devices, _ := filepath.Glob("/dev/snd/midiC*D*")
+			if len(devices) == 0 {
+				return nil, fmt.Errorf("no MIDI device found, give its path like midi:/dev/snd/midiC1D0")
+			}
+			path = devices[0]
+		}
+		return OpenMIDI(path)
+	case "gamepad":
+		if path == "" {
+			path = "/dev/input/js0"
+		}
+		return OpenGamepad(path)
+	default:
+		return nil, fmt.Errorf("unknown input %q, expected midi or gamepad", kind)
+	}
+}
+
+// InputMux merges the lines typed on the keyboard and the moves of input drivers into the lines of a Console, as if the moves were typed,
+// so that the game takes moves from both. The moves are echoed to the output, to show up after the prompt like typed ones.
+type InputMux struct {
+	r   *io.PipeReader
+	w   *io.PipeWriter
+	out io.Writer
+
+	drivers []InputDriver
+	mu      sync.Mutex
+}
+
+// NewInputMux returns an InputMux reading lines from `keyboard` right away, and moves from the drivers once started.
+func NewInputMux(keyboard io.Reader, out io.Writer, drivers []InputDriver) *InputMux {
+	r, w := io.Pipe()
+	m := &InputMux{r: r, w: w, out: out, drivers: drivers}
+
+	go func() {
+		sc := bufio.NewScanner(keyboard)
+		for sc.Scan() {
+			if m.line(sc.Text(), false) != nil {
+				return
+			}
+		}
+
+		// the game ends with the keyboard's input.
+		w.Close()
+		for _, d := range drivers {
+			d.Close()
+		}
+	}()
+
+	return m
+}
+
+func (m *InputMux) Read(p []byte) (int, error) {
+	return m.r.Read(p)
+}
+
+// line passes a line on to the Console, echoing it first if it didn't come from the keyboard.
+func (m *InputMux) line(s string, echo bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if echo {
+		fmt.Fprintln(m.out, s)
+	}
+	_, err := io.WriteString(m.w, s+"\n")
+	return err
+}
+
+// Start starts reading moves from the drivers. Until then, only the keyboard is read, so that the board size and the like are typed.
+func (m *InputMux) Start() {
+	if m == nil {
+		return
+	}
+
+	for _, d := range m.drivers {
+		d := d
+		moves := make(chan Move)
+		go func() {
+			for mv := range moves {
+				if m.line(mv.String(), true) != nil {
+					return
+				}
+			}
+		}()
+		go func() {
+			defer close(moves)
+			if err := d.Run(moves, m.out); err != nil {
+				fmt.Fprintf(m.out, "\n%s stopped (%s)\n", d.Name(), err)
+			}
+		}()
+
+		fmt.Fprintf(m.out, "Reading moves from %s\n", d.Name())
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "insertions",
+		Summary: "find parts of a solution that shorter sequences could replace",
+		Run:     runInsertions,
+	})
+}
+
+// Shortcut is a part of a solution that a shorter sequence of moves can replace.
+type Shortcut struct {
+	// Start and End are the indices of the part's first single shift and of the one after its last, in the solution split into single shifts.
+	Start, End int
+	// Phase is the name of the phase of the solution the part starts in.
+	Phase string
+	Old   []Move
+	New   []Move
+	// CostSaving is how much cheaper the new sequence is than the old one in the cost model the shortcut was found with, which is Saving with ShiftCost.
+	CostSaving float64
+}
+
+// Saving returns how many single shifts the shortcut saves.
+func (s *Shortcut) Saving() int {
+	return MovesLength(s.Old) - MovesLength(s.New)
+}
+
+// FindShortcuts looks for parts of a solution, of at most `window` single shifts, that a cheaper sequence in the cost model can replace.
+// Moves shift positions around regardless of the tiles on them, so a part can be replaced by any sequence that has the same effect on a solved board,
+// and the cheapest one is found by solving the board the part leaves behind and inverting the solution.
+// With ShiftCost, cheaper sequences are the shorter ones. Parts are priced on their own, without the moves around them.
+// The search is greedy: going through the solution from the start, the part saving the most is replaced and the search goes on after it.
+// Returns the shortcuts taken and the improved solution, in single shifts.
+func FindShortcuts(scramble *Board, solution []Move, window int, c CostModel) ([]Shortcut, []Move) {
+	shifts := unitShifts(solution, scramble)
+
+	// the phase of every single shift, to say where the shortcuts are.
+	phases := make([]string, len(shifts))
+	for _, p := range SplitPhases(&Replay{Scramble: scramble.Clone(), Moves: shifts}) {
+		for i := p.Start; i < p.End; i++ {
+			phases[i] = p.Name
+		}
+	}
+
+	var shortcuts []Shortcut
+	var improved []Move
+	for i := 0; i < len(shifts); {
+		var best *Shortcut
+		for j := i + 2; j <= len(shifts) && j-i <= window; j++ {
+			part := shifts[i:j]
+
+			effect, _ := NewBoard(scramble.Width(), scramble.Height())
+			effect.ApplyMoves(part)
+
+			var s Shortcut
+			if c == ShiftCost {
+				res, ok := SolveWithin(&effect, len(part)-1, nil)
+				if !ok {
+					continue
+				}
+				s = Shortcut{Start: i, End: j, Phase: phases[i], Old: part, New: InverseMoves(res.Moves)}
+				s.CostSaving = float64(s.Saving())
+			} else {
+				res, ok := SolveCheapest(&effect, window, c, nil)
+				if !ok {
+					continue
+				}
+				s = Shortcut{Start: i, End: j, Phase: phases[i], Old: part, New: InverseMoves(res.Moves)}
+				if s.CostSaving = c.Cost(s.Old, scramble) - c.Cost(s.New, scramble); s.CostSaving <= 0 {
+					continue
+				}
+			}
+			if best == nil || s.CostSaving > best.CostSaving {
+				best = &s
+			}
+		}
+
+		if best == nil {
+			improved = append(improved, shifts[i])
+			i++
+			continue
+		}
+
+		logger.Debug("shortcut", "start", best.Start, "end", best.End, "saving", best.CostSaving)
+		shortcuts = append(shortcuts, *best)
+		improved = append(improved, best.New...)
+		i = best.End
+	}
+
+	return shortcuts, improved
+}
+
+// joinShifts joins consecutive single shifts of the same slice in the same direction into one move, undoing unitShifts.
+func joinShifts(shifts []Move) []Move {
+	var seq []Move
+	for _, m := range shifts {
+		if n := len(seq); n != 0 && seq[n-1].Axis == m.Axis && seq[n-1].Index == m.Index && (seq[n-1].Amount > 0) == (m.Amount > 0) {
+			seq[n-1].Amount += m.Amount
+			continue
+		}
+		seq = append(seq, m)
+	}
+
+	return seq
+}
+
+// runInsertions runs the insertions command.
+func runInsertions(args []string) error {
+	fs := flag.NewFlagSet("insertions", flag.ContinueOnError)
+	scramblePath := fs.String("scramble", "", "file with the state code of the scramble, or the moves that make it from a solved board, to analyze a solution file instead of a replay")
+	size := fs.String("size", "5x5", "board size, when the scramble is given as moves")
+	window := fs.Int("window", 6, "longest part of the solution to look for a shortcut in, in single shifts. The search takes exponentially longer the longer it is")
+	cost := ShiftCost
+	fs.Func("cost", "cost model to look for cheaper sequences in instead of shorter ones, for physical puzzles: what a row shift, a column shift and starting a move cost, like row=1,column=1.5,start=2", func(s string) (err error) {
+		cost, err = ParseCostModel(s)
+		return err
+	})
+	var from, f MoveFormat
+	fs.Func("from", "notation of the moves in the files: programmer, standard or english", func(s string) (err error) {
+		from.Notation, err = ParseNotation(s)
+		return err
+	})
+	fs.Func("notation", "notation to show moves in: programmer, standard or english", func(s string) (err error) {
+		f.Notation, err = ParseNotation(s)
+		return err
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: insertions [-window 6] [-cost MODEL] [-notation NOTATION] REPLAY | insertions -scramble FILE [-size 5x5] [-from NOTATION] [-window 6] [-cost MODEL] [-notation NOTATION] SOLUTION")
+		fmt.Fprintln(fs.Output(), "Looks for parts of a solution that shorter sequences of moves could replace, or cheaper ones with -cost, and reports the moves or cost they save and the improved solution.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a replay or solution file")
+	}
+	if *window < 2 {
+		return fmt.Errorf("the window must be at least 2 single shifts")
+	}
+
+	var scramble Board
+	var solution []Move
+	if *scramblePath == "" {
+		r, err := LoadReplayFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		scramble, solution = r.Scramble, r.Moves
+	} else {
+		var err error
+		if scramble, err = readScrambleFile(*scramblePath, *size, from.Notation); err != nil {
+			return err
+		}
+		if solution, err = readMovesFile(fs.Arg(0), from.Notation, &scramble); err != nil {
+			return err
+		}
+	}
+
+	end := scramble.Clone()
+	if _, err := end.ApplyMoves(solution); err != nil {
+		return err
+	}
+	if !end.IsSolved() {
+		fmt.Fprintln(os.Stderr, "Warning: the solution doesn't solve the scramble")
+	}
+
+	shortcuts, improved := FindShortcuts(&scramble, solution, *window, cost)
+	if len(shortcuts) == 0 {
+		fmt.Printf("No shortcuts of at most %d single shifts found in the %d single shifts of the solution\n", *window, MovesLength(solution))
+		return nil
+	}
+
+	for _, s := range shortcuts {
+		replacement := "dropped"
+		if len(s.New) != 0 {
+			replacement = "replaced by " + f.FormatMoves(joinShifts(s.New), &scramble)
+		}
+		saving := strconv.Itoa(s.Saving())
+		if cost != ShiftCost {
+			saving = fmt.Sprintf("%g of cost", s.CostSaving)
+		}
+		fmt.Printf("Shifts %d to %d (%s): %s can be %s, saving %s\n", s.Start+1, s.End, s.Phase, f.FormatMoves(joinShifts(s.Old), &scramble), replacement, saving)
+	}
+
+	improved = SimplifyMoves(improved, &scramble)
+	fmt.Printf("\nImproved solution: %s\n", f.FormatMoves(improved, &scramble))
+	fmt.Printf("%d single shifts instead of %d\n", MovesLength(improved), MovesLength(solution))
+	if cost != ShiftCost {
+		fmt.Printf("Cost %g instead of %g\n", cost.Cost(improved, &scramble), cost.Cost(solution, &scramble))
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "siamese",
+		Summary: "play two boards that share a slice, so moving it moves both",
+		Run:     runSiamese,
+	})
+}
+
+/* Moves on linked boards
+linked-move = board ":" move
+board       = "A" | "B" | ...
+
+Boards are named by letter in order. "B:-1C2" shifts column 2 of the second board up.
+*/
+
+// Link makes a slice of a board the same slice as one of another board, so moving either moves both.
+// Both slices run along Axis and must have the same length.
+type Link struct {
+	A, B           int
+	Axis           Axis
+	IndexA, IndexB int
+}
+
+// LinkedBoards is a set of boards where some slices are shared between boards through links.
+type LinkedBoards struct {
+	Boards []Board
+	Links  []Link
+
+	// solved holds every board as it is when all of them are solved.
+	solved []Board
+}
+
+// NewLinkedBoards creates solved linked boards from their dimensions and links.
+// Tiles are numbered across the boards in order, and the tiles of a shared slice are numbered by the first board.
+func NewLinkedBoards(dims [][2]int, links []Link) (*LinkedBoards, error) {
+	l := &LinkedBoards{Links: links}
+
+	n := 0
+	for _, d := range dims {
+		b, err := NewBoard(d[0], d[1])
+		if err != nil {
+			return nil, err
+		}
+
+		for x := range b {
+			for y := range b[x] {
+				b[x][y] += n
+			}
+		}
+		n += d[0] * d[1]
+
+		l.Boards = append(l.Boards, b)
+	}
+
+	for _, k := range links {
+		if k.A < 0 || k.A >= len(l.Boards) || k.B < 0 || k.B >= len(l.Boards) || k.A == k.B {
+			return nil, fmt.Errorf("links must be between two different boards")
+		}
+
+		a, b := &l.Boards[k.A], &l.Boards[k.B]
+		if a.SliceLength(k.Axis) != b.SliceLength(k.Axis) {
+			return nil, fmt.Errorf("linked slices must have the same length")
+		}
+		if k.IndexA < 0 || k.IndexA >= a.SliceCount(k.Axis) || k.IndexB < 0 || k.IndexB >= b.SliceCount(k.Axis) {
+			return nil, fmt.Errorf("linked slice out of bounds")
+		}
+	}
+
+	l.sync(0)
+	for i := range l.Boards {
+		l.solved = append(l.solved, l.Boards[i].Clone())
+	}
+
+	return l, nil
+}
+
+// slice returns pointers to the tiles of a slice of a board, in order.
+func slice(b *Board, a Axis, index int) []*int {
+	tiles := make([]*int, b.SliceLength(a))
+	for i := range tiles {
+		if a == HorizontalAxis {
+			tiles[i] = &(*b)[i][index]
+		} else {
+			tiles[i] = &(*b)[index][i]
+		}
+	}
+
+	return tiles
+}
+
+// sync copies the shared slices of board `changed` to the boards linked to it, and so on through their links.
+func (l *LinkedBoards) sync(changed int) {
+	done := map[int]bool{changed: true}
+	queue := []int{changed}
+	for len(queue) != 0 {
+		i := queue[0]
+		queue = queue[1:]
+
+		for _, k := range l.Links {
+			from, to, fromIndex, toIndex := k.A, k.B, k.IndexA, k.IndexB
+			if k.B == i {
+				from, to, fromIndex, toIndex = k.B, k.A, k.IndexB, k.IndexA
+			} else if k.A != i {
+				continue
+			}
+			if done[to] {
+				continue
+			}
+
+			dst := slice(&l.Boards[to], k.Axis, toIndex)
+			for j, t := range slice(&l.Boards[from], k.Axis, fromIndex) {
+				*dst[j] = *t
+			}
+
+			done[to] = true
+			queue = append(queue, to)
+		}
+	}
+}
+
+// MakeMove makes a move on board `i`, moving the shared slices of the linked boards along with it.
+func (l *LinkedBoards) MakeMove(i int, m *Move) int {
+	n := l.Boards[i].MakeMove(m)
+	l.sync(i)
+
+	return n
+}
+
+// IsSolved returns true if every board is solved.
+func (l *LinkedBoards) IsSolved() bool {
+	for i := range l.Boards {
+		if !l.Boards[i].Equal(l.solved[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Reset solves every board.
+func (l *LinkedBoards) Reset() {
+	for i := range l.Boards {
+		l.Boards[i] = l.solved[i].Clone()
+	}
+}
+
+// Shuffle makes `iterations` random moves on random boards, or DefaultShuffleIterations of every board if `iterations` is less or equal to 0.
+func (l *LinkedBoards) Shuffle(iterations int) int {
+	if iterations <= 0 {
+		for i := range l.Boards {
+			iterations += DefaultShuffleIterations(l.Boards[i].Width(), l.Boards[i].Height())
+		}
+	}
+
+	for n := 0; n < iterations; n++ {
+		i := rng.Intn(len(l.Boards))
+		moves := AllMoves(&l.Boards[i])
+		l.MakeMove(i, &moves[rng.Intn(len(moves))])
+	}
+
+	return iterations
+}
+
+// ParseLinkedMove parses a move on linked boards, such as "B:-1C2", returning the board's index and the move.
+func (l *LinkedBoards) ParseLinkedMove(input string) (int, *Move, error) {
+	i := strings.IndexByte(input, ':')
+	if i != 1 {
+		return 0, nil, fmt.Errorf("missing board in move %q, like A:1R0", input)
+	}
+
+	board := int(strings.ToUpper(input)[0] - 'A')
+	if board < 0 || board >= len(l.Boards) {
+		return 0, nil, fmt.Errorf("no board %c in move %q", input[0], input)
+	}
+
+	m, err := ParseMove(input[2:], &l.Boards[board])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return board, m, nil
+}
+
+// SprintLinkedBoards formats every board under its letter.
+func SprintLinkedBoards(l *LinkedBoards) string {
+	max := 0
+	for _, b := range l.solved {
+		for _, t := range b.Tiles() {
+			if t > max {
+				max = t
+			}
+		}
+	}
+
+	s := make([]string, len(l.Boards))
+	for i := range l.Boards {
+		s[i] = fmt.Sprintf("Board %c:\n%s", 'A'+i, sprintBoardUpTo(&l.Boards[i], max))
+	}
+
+	return strings.Join(s, "\n")
+}
+
+// runSiamese runs the siamese command, a minimal interactive game on two linked boards.
+func runSiamese(args []string) error {
+	fs := flag.NewFlagSet("siamese", flag.ContinueOnError)
+	size := fs.String("size", "3x3", "size of both boards")
+	columns := fs.Bool("columns", false, "share the last column of the first board with the first column of the second, instead of the last row with the first row")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	w, h, err := ParseTwoDimensions(*size)
+	if err != nil {
+		return err
+	}
+
+	link := Link{A: 0, B: 1, Axis: HorizontalAxis, IndexA: h - 1, IndexB: 0}
+	if *columns {
+		link = Link{A: 0, B: 1, Axis: VerticalAxis, IndexA: w - 1, IndexB: 0}
+	}
+
+	l, err := NewLinkedBoards([][2]int{{w, h}, {w, h}}, []Link{link})
+	if err != nil {
+		return err
+	}
+
+	con := NewConsole(os.Stdin, os.Stdout)
+	var moves int
+	for {
+		fmt.Fprintln(con)
+		fmt.Fprintln(con, SprintLinkedBoards(l))
+		fmt.Fprintf(con, "%d moves so far\n", moves)
+		if l.IsSolved() {
+			fmt.Fprintln(con, "Solved")
+		}
+
+		fmt.Fprint(con, "Move: ")
+		scanned := false
+		for con.Scan() {
+			switch s := strings.ToLower(strings.TrimSpace(con.Text())); s {
+			case "shuffle":
+				fmt.Fprintf(con, "Shuffled boards with %d iterations\n", l.Shuffle(0))
+				moves = 0
+			case "reset":
+				l.Reset()
+				moves = 0
+				fmt.Fprintln(con, "Boards reset")
+			default:
+				i, m, err := l.ParseLinkedMove(s)
+				if err != nil {
+					fmt.Fprintf(con, "Invalid move (%s), try again: ", err)
+					continue
+				}
+
+				moves += l.MakeMove(i, m)
+			}
+
+			scanned = true
+			break
+		}
+
+		if !scanned {
+			fmt.Fprintln(con)
+			return con.Err()
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ParseLogLevel parses the name of a log level: debug, info, warn or error.
+// Debug messages follow what the engine does step by step: parsed input, moves made and solver phases. Warnings report problems the program worked around, like an autosave that couldn't be written.
+func ParseLogLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", s)
+	}
+
+	return l, nil
+}
+
+// LogFormat is how log messages are written.
+type LogFormat int
+
+const (
+	// TextLogFormat writes messages as lines of key=value pairs.
+	TextLogFormat LogFormat = iota
+	// JSONLogFormat writes messages as lines of JSON objects.
+	JSONLogFormat
+)
+
+// ParseLogFormat parses a log format: text or json.
+func ParseLogFormat(s string) (LogFormat, error) {
+	switch strings.ToLower(s) {
+	case "text":
+		return TextLogFormat, nil
+	case "json":
+		return JSONLogFormat, nil
+	default:
+		return 0, fmt.Errorf("unknown log format %q, expected text or json", s)
+	}
+}
+
+// logLevel is the least important level of the messages logger writes, set with the -log-level flag. Only warnings and errors are written by default.
+var logLevel = func() *slog.LevelVar {
+	l := new(slog.LevelVar)
+	l.Set(slog.LevelWarn)
+	return l
+}()
+
+// logger is where the program logs to, for debugging the engine, configured with the -log-level and -log-format flags.
+// Messages are written with a message and key-value pairs of attributes, like logger.Debug("move", "move", m, "moves", n).
+var logger = newLogger(TextLogFormat)
+
+// newLogger returns a logger writing to the standard error in the format, at the level of logLevel.
+func newLogger(f LogFormat) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: logLevel, ReplaceAttr: logStringer}
+	if f == JSONLogFormat {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
+
+// logStringer writes the values of attributes that have a String method, like moves, as text rather than as their fields.
+func logStringer(groups []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+	if _, ok := a.Value.Any().(error); ok {
+		return a
+	}
+	if s, ok := a.Value.Any().(fmt.Stringer); ok {
+		a.Value = slog.StringValue(s.String())
+	}
+
+	return a
+}
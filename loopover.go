@@ -8,12 +8,14 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
-	"math/rand"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 )
 
@@ -79,6 +81,35 @@ func (b *Board) Height() int {
 	return cap((*b)[0])
 }
 
+// SliceCount returns how many slices the board has along an axis, that is, the number of rows for HorizontalAxis and the number of columns for VerticalAxis.
+func (b *Board) SliceCount(a Axis) int {
+	if a == HorizontalAxis {
+		return b.Height()
+	}
+
+	return b.Width()
+}
+
+// SliceLength returns how many tiles a slice along an axis has.
+func (b *Board) SliceLength(a Axis) int {
+	if a == HorizontalAxis {
+		return b.Width()
+	}
+
+	return b.Height()
+}
+
+// Clone returns a copy of the board that can be modified independently.
+func (b *Board) Clone() Board {
+	c := make(Board, b.Width(), b.Width())
+	for x := range c {
+		c[x] = make([]int, b.Height(), b.Height())
+		copy(c[x], (*b)[x])
+	}
+
+	return c
+}
+
 // Reset resets the board by setting all tiles in the default order.
 func (b *Board) Reset() {
 	for x := 0; x < b.Width(); x++ {
@@ -88,7 +119,24 @@ func (b *Board) Reset() {
 	}
 }
 
+// ShuffleScale is how many Shuffle iterations per tile are made when no number of iterations is given.
+// Measuring with the mixing command, boards from 3x3 to 5x5 stop getting further from solved on average after about 1.5 iterations per tile, so 2 leaves some margin.
+var ShuffleScale = 2
+
+// MaxShuffleIterations is the most Shuffle iterations that can be asked for when shuffling interactively.
+const MaxShuffleIterations = 1000000
+
+// MaxScrambleDepth is the most random moves that can be asked for when scrambling interactively.
+const MaxScrambleDepth = 100000
+
+// DefaultShuffleIterations returns how many iterations Shuffle makes on a width*height board when no number of iterations is given.
+func DefaultShuffleIterations(width, height int) int {
+	return ShuffleScale * width * height
+}
+
 // FastShuffle shuffles the board by going through all the tiles and swaping them with a different, random tile.
+// This is quicker than Shuffle on big boards but not uniformly random, as some arrangements come up more often than others.
+// Since swaps don't need to be reachable by moves, it can also leave boards with both dimensions odd in an unsolvable arrangement, in which case two tiles are swapped back.
 func (b *Board) FastShuffle() {
 	board := *b
 
@@ -98,44 +146,94 @@ func (b *Board) FastShuffle() {
 			for x2, y2 = x1, y1; x1 == x2 || y1 == y2; {
 				// keep generating a random x2 and y2 if x1 is equal to x2 and y1 is equal to y2.
 				// this is done to avoid keeping a number on the same tile.
-				x2 = rand.Intn(b.Width())
-				y2 = rand.Intn(b.Height())
+				x2 = rng.Intn(b.Width())
+				y2 = rng.Intn(b.Height())
 			}
 
 			board[x1][y1], board[x2][y2] = board[x2][y2], board[x1][y1]
 		}
 	}
+
+	if !b.IsSolvable() {
+		board[0][0], board[1][0] = board[1][0], board[0][0]
+	}
 }
 
-// Shuffle shuffles the board by applying `iterations` anmount of Moves generated with random parameters. If `iterations` is less or equal to 0, b.Width() + b.Height() is used instead.
-// While this might be slower with more iterations, it is more truthful to what a human would do if they were to shuffle manually.
+// Shuffle shuffles the board by applying `iterations` anmount of Moves generated with random parameters. If `iterations` is less or equal to 0, DefaultShuffleIterations is used instead.
+// While this might be slower with more iterations, it is more truthful to what a human would do if they were to shuffle manually, and every solvable arrangement becomes equally likely as iterations grow.
 func (b *Board) Shuffle(iterations int) int {
-	if iterations == 0 {
-		iterations = b.Width() + b.Height()
+	if iterations <= 0 {
+		iterations = DefaultShuffleIterations(b.Width(), b.Height())
 	}
 
 	var moves int
 	for moves = 0; moves < iterations; moves++ {
-		var a Axis
-		var max int
-		if rand.Intn(2) == 0 {
-			a = HorizontalAxis
-			max = b.Width()
-		} else {
-			a = VerticalAxis
-			max = b.Height()
-		}
+		a := Axis(rng.Intn(2))
 
 		b.MakeMove(&Move{
 			Axis:   a,
-			Index:  rand.Intn(max),
-			Amount: rand.Intn(max-1) + 1,
+			Index:  rng.Intn(b.SliceCount(a)),
+			Amount: rng.Intn(b.SliceLength(a)-1) + 1,
 		})
 	}
 
 	return moves
 }
 
+// MinScrambleDistance returns the Distance a width*height board has to exceed to count as scrambled, which is about half of the average distance of a uniformly random board.
+func MinScrambleDistance(width, height int) int {
+	return width * height * (width + height) / 16
+}
+
+// ScrambleUntil shuffles the board with Shuffle's default number of iterations and then keeps making random moves until its Distance is greater than `minDistance`.
+// To avoid looping forever on thresholds that can't be reached, it gives up after making 100 times the default number of iterations.
+// Returns the number of moves made.
+func (b *Board) ScrambleUntil(minDistance int) int {
+	moves := b.Shuffle(0)
+
+	for limit := 100 * moves; b.Distance() <= minDistance && moves < limit; {
+		moves += b.Shuffle(1)
+	}
+
+	return moves
+}
+
+// ScrambleDepth resets the board and applies exactly `k` random single shifts to it, returning the applied sequence.
+// No shift undoes the previous one, nor keeps shifting the same slice past half of its length, as that would be shorter to do the other way around.
+func (b *Board) ScrambleDepth(k int) []Move {
+	b.Reset()
+
+	var seq []Move
+	var streak int
+	for len(seq) < k {
+		a := Axis(rng.Intn(2))
+		m := Move{
+			Axis:   a,
+			Index:  rng.Intn(b.SliceCount(a)),
+			Amount: 1 - 2*rng.Intn(2),
+		}
+
+		if len(seq) != 0 {
+			last := seq[len(seq)-1]
+			if last.Axis == m.Axis && last.Index == m.Index {
+				if last.Amount != m.Amount || 2*(streak+1) > b.SliceLength(a) {
+					continue
+				}
+				streak++
+			} else {
+				streak = 1
+			}
+		} else {
+			streak = 1
+		}
+
+		b.MakeMove(&m)
+		seq = append(seq, m)
+	}
+
+	return seq
+}
+
 // IsSolved returns true if all tiles are in order.
 // In other words, if for every (x, y) the tile at (x, y) equals x + y * b.Width() + 1.
 func (b *Board) IsSolved() bool {
@@ -150,6 +248,124 @@ func (b *Board) IsSolved() bool {
 	return true
 }
 
+// Equal returns true if both boards have the same dimensions and the same tiles in the same places.
+func (b *Board) Equal(other Board) bool {
+	if b.Width() != other.Width() || b.Height() != other.Height() {
+		return false
+	}
+
+	for x := 0; x < b.Width(); x++ {
+		for y := 0; y < b.Height(); y++ {
+			if (*b)[x][y] != other[x][y] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// SolvedRegion returns how many rows, starting from the top, and how many columns, starting from the left, have all of their tiles in place.
+func (b *Board) SolvedRegion() (rows, cols int) {
+	for rows < b.Height() && b.isRowSolved(rows) {
+		rows++
+	}
+	for cols < b.Width() && b.isColumnSolved(cols) {
+		cols++
+	}
+
+	return
+}
+
+// isRowSolved returns true if all tiles of the row at `y` are in place.
+func (b *Board) isRowSolved(y int) bool {
+	return b.Region(0, y, b.Width(), 1).IsSolvedRegion()
+}
+
+// isColumnSolved returns true if all tiles of the column at `x` are in place.
+func (b *Board) isColumnSolved(x int) bool {
+	return b.Region(x, 0, 1, b.Height()).IsSolvedRegion()
+}
+
+// Distance returns an estimate of how far the board is from being solved: the sum of how many single shifts away every tile is from where it belongs, wrapping around the edges.
+// A solved board has a distance of 0.
+func (b *Board) Distance() int {
+	var d int
+	for x := range *b {
+		for y := range (*b)[x] {
+			d += b.tileDistance(x, y)
+		}
+	}
+
+	return d
+}
+
+// tileDistance returns how many single shifts away the tile at (x, y) is from where it belongs, wrapping around the edges.
+func (b *Board) tileDistance(x, y int) int {
+	w, h := b.Width(), b.Height()
+	v := (*b)[x][y] - 1
+	dx, dy := Abs(x-v%w), Abs(y-v/w)
+
+	if dx > w-dx {
+		dx = w - dx
+	}
+	if dy > h-dy {
+		dy = h - dy
+	}
+
+	return dx + dy
+}
+
+// Placed returns how many tiles are where they belong.
+func (b *Board) Placed() int {
+	return b.Region(0, 0, b.Width(), b.Height()).Placed()
+}
+
+// Tiles returns the values of all tiles row by row, starting from the top left.
+func (b *Board) Tiles() []int {
+	tiles := make([]int, 0, b.Width()*b.Height())
+	for y := 0; y < b.Height(); y++ {
+		for x := 0; x < b.Width(); x++ {
+			tiles = append(tiles, (*b)[x][y])
+		}
+	}
+
+	return tiles
+}
+
+// IsSolvable returns true if the board can be brought back to its solved state by making moves.
+// Shifting a slice of even length is an odd permutation, so if either dimension is even every arrangement can be solved.
+// Otherwise every move is an even permutation and only arrangements with an even permutation parity can be solved.
+func (b *Board) IsSolvable() bool {
+	if b.Width()%2 == 0 || b.Height()%2 == 0 {
+		return true
+	}
+
+	return PermutationParity(b.Tiles()) == 0
+}
+
+// PermutationParity returns 0 if the permutation of 1 through len(tiles) is even and 1 if it is odd.
+func PermutationParity(tiles []int) int {
+	visited := make([]bool, len(tiles))
+
+	var parity int
+	for i := range tiles {
+		if visited[i] {
+			continue
+		}
+
+		// a cycle of length l is made of l-1 transpositions.
+		for j := i; !visited[j]; j = tiles[j] - 1 {
+			visited[j] = true
+			if j != i {
+				parity ^= 1
+			}
+		}
+	}
+
+	return parity
+}
+
 // MakeMove modifies the Board by applying a move. A move can shift the contents of a column or a row forward or backwards.
 func (b *Board) MakeMove(m *Move) int {
 	board := *b
@@ -187,16 +403,109 @@ func (b *Board) MakeMove(m *Move) int {
 	return amnt
 }
 
+// Cell is the position of a tile on a board along with its value.
+type Cell struct {
+	X, Y  int
+	Value int
+}
+
+// MoveResult describes what a move did to a board.
+type MoveResult struct {
+	// Move is the move as it was made, and Normalized the shortest move with the same effect.
+	Move, Normalized Move
+	// Moves is how many moves were made, as MakeMove counts them.
+	Moves int
+	// Shift is how far the tiles of the slice ended up from where they were, the amount of Normalized: forward is right or down.
+	Shift int
+	// Cells are the tiles the move changed, with their values from before it.
+	Cells []Cell
+}
+
+// MakeMoveResult makes a move like MakeMove, returning everything it changed.
+func (b *Board) MakeMoveResult(m *Move) *MoveResult {
+	r := &MoveResult{Move: *m, Normalized: b.NormalizeMove(*m)}
+	r.Shift = r.Normalized.Amount
+
+	if r.Shift != 0 {
+		r.Cells = make([]Cell, b.SliceLength(m.Axis))
+		for i := range r.Cells {
+			x, y := i, m.Index
+			if m.Axis == VerticalAxis {
+				x, y = m.Index, i
+			}
+			r.Cells[i] = Cell{X: x, Y: y, Value: (*b)[x][y]}
+		}
+	}
+
+	r.Moves = b.MakeMove(m)
+	return r
+}
+
+// Undo puts back the tiles the move changed on the board it was made on.
+func (r *MoveResult) Undo(b *Board) {
+	for _, c := range r.Cells {
+		(*b)[c.X][c.Y] = c.Value
+	}
+}
+
+// Applied returns a copy of the board with a move made, leaving the board itself untouched.
+func (b *Board) Applied(m *Move) Board {
+	c := b.Clone()
+	c.MakeMove(m)
+
+	return c
+}
+
 // Axis can either be Horizontal or Vertical.
-type Axis bool
+type Axis int
 
 const (
 	// HorizontalAxis goes from left to right or vice-versa.
-	HorizontalAxis = iota == 0
+	HorizontalAxis Axis = iota
 	// VerticalAxis goes from top to bottom or vice-versa.
 	VerticalAxis
 )
 
+// String returns the name of the axis: "horizontal" or "vertical".
+func (a Axis) String() string {
+	switch a {
+	case HorizontalAxis:
+		return "horizontal"
+	case VerticalAxis:
+		return "vertical"
+	default:
+		return "Axis(" + strconv.Itoa(int(a)) + ")"
+	}
+}
+
+// ParseAxis parses the name of an axis, either "horizontal" or "vertical", or the name of its slices, "row" or "column".
+// Initials and plurals are accepted too, as well as the axis characters of Programmer's Notation.
+func ParseAxis(s string) (Axis, error) {
+	switch strings.ToLower(s) {
+	case "horizontal", "h", "row", "rows", "r":
+		return HorizontalAxis, nil
+	case "vertical", "v", "column", "columns", "c":
+		return VerticalAxis, nil
+	default:
+		return 0, fmt.Errorf("unknown axis %q", s)
+	}
+}
+
+// MarshalText encodes the axis as its name, which is how it's written in JSON, also as a map key.
+func (a Axis) MarshalText() ([]byte, error) {
+	if a != HorizontalAxis && a != VerticalAxis {
+		return nil, fmt.Errorf("invalid axis %d", int(a))
+	}
+
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText decodes an axis with ParseAxis.
+func (a *Axis) UnmarshalText(text []byte) (err error) {
+	*a, err = ParseAxis(string(text))
+	return err
+}
+
 // Move represents a parsed Move from input.
 type Move struct {
 	Axis   Axis
@@ -252,7 +561,7 @@ func ParseMove(input string, board *Board) (*Move, error) {
 	// parse index.
 	indexStr := input[ai+1:]
 	if len(indexStr) == 0 {
-		return nil, fmt.Errorf("missing amount in move %q", input)
+		return nil, fmt.Errorf("missing index in move %q", input)
 	}
 
 	var reverseIndex bool
@@ -267,27 +576,17 @@ func ParseMove(input string, board *Board) (*Move, error) {
 	}
 
 	if reverseIndex {
-		if axis == HorizontalAxis {
-			index = board.Width() - 1 - index
-		} else {
-			index = board.Height() - 1 - index
-		}
+		index = board.SliceCount(axis) - 1 - index
 	}
 
 	// check if index is in bounds.
 	if index < 0 {
-		return nil, fmt.Errorf("amount must be greater or equal to 0 in move %q", input)
-	}
-
-	var max int
-	if axis == HorizontalAxis {
-		max = board.Width()
-	} else {
-		max = board.Height()
+		return nil, fmt.Errorf("index must be greater or equal to 0 in move %q", input)
 	}
 
+	max := board.SliceCount(axis)
 	if index >= max {
-		return nil, fmt.Errorf("amount must be lesser to %d in move %q", max, input)
+		return nil, fmt.Errorf("index must be lesser to %d in move %q", max, input)
 	}
 
 	return &Move{
@@ -335,8 +634,13 @@ func ParseTwoDimensions(input string) (width, height int, err error) {
 
 // SprintBoard formats the board into a grid of rows and columns.
 func SprintBoard(b *Board) string {
+	return sprintBoardUpTo(b, b.Width()*b.Height())
+}
+
+// sprintBoardUpTo formats the board like SprintBoard, padding the tiles to the width of `max`.
+func sprintBoardUpTo(b *Board, max int) string {
 	var r string
-	pad := strconv.Itoa(len(strconv.Itoa(b.Width() * b.Height())))
+	pad := strconv.Itoa(len(strconv.Itoa(max)))
 
 	for y := 0; y < b.Height(); y++ {
 		for x := 0; x < b.Width(); x++ {
@@ -351,118 +655,1059 @@ func SprintBoard(b *Board) string {
 	return r
 }
 
+// SplitCommand splits an input line into its first word and the rest of the line.
+func SplitCommand(input string) (cmd, arg string) {
+	input = strings.TrimSpace(input)
+
+	i := strings.IndexFunc(input, unicode.IsSpace)
+	if i == -1 {
+		return input, ""
+	}
+
+	return input[:i], strings.TrimSpace(input[i+1:])
+}
+
 // ScanShuffle scans user input to answer certain questions and execute either a fast or a normal shuffle.
-func ScanShuffle(b *Board, scanner *bufio.Scanner) {
-	var done bool
+// Returns false if the input ended before the board was shuffled.
+func ScanShuffle(b *Board, con *Console) bool {
+	fmt.Fprint(con, "Fast shuffle? [Y/n]: ")
+	if !con.Scan() {
+		return false
+	}
 
-	fmt.Print("Fast shuffle? [Y/n]: ")
-	for !done && scanner.Scan() {
-		s := strings.ToLower(scanner.Text())
-		if s != "n" {
-			b.FastShuffle()
-			fmt.Println("Fast shuffled board")
+	if strings.ToLower(strings.TrimSpace(con.Text())) != "n" {
+		b.FastShuffle()
+		fmt.Fprintln(con, "Fast shuffled board")
 
-			done = true
-			break
+		if min := MinScrambleDistance(b.Width(), b.Height()); b.Distance() <= min {
+			fmt.Fprintf(con, "Board was too close to solved, scrambled it further with %d iterations\n", b.ScrambleUntil(min))
 		}
 
-		var iters int
-		fmt.Print("How many iterations? 0 uses the default number of iterations: ")
-		for scanner.Scan() {
-			s = scanner.Text()
+		return true
+	}
 
-			// empty input means 0 iterations.
-			if len(s) != 0 {
-				iters, err := strconv.Atoi(s)
-				if err != nil {
-					fmt.Print("Invalid number, try again: ")
-					continue
-				}
+	iters, ok := ScanInt(con, "How many iterations? 0 uses the default number of iterations: ", 0, 0, MaxShuffleIterations)
+	if !ok {
+		return false
+	}
 
-				if iters < 0 {
-					iters = 0
-				}
-			}
+	var finalIters int
+	if iters == 0 {
+		finalIters = b.ScrambleUntil(MinScrambleDistance(b.Width(), b.Height()))
+	} else {
+		finalIters = b.Shuffle(iters)
+	}
+	fmt.Fprintf(con, "Shuffled board with %d iterations\n", finalIters)
 
-			break
+	return true
+}
+
+func main() {
+	cryptoRand := flag.Bool("crypto-rand", false, "draw the randomness of shuffles and scrambles from crypto/rand, for competitions")
+
+	setup := flag.Bool("setup", false, "run the setup wizard again to change the default board size, notation, colors and player name")
+	preset := flag.String("preset", "", "play with the settings of a preset, like wc-5x5, or of a preset file: board size, scramble, metric and time limit")
+
+	var o PlayOptions
+	o.Cost = ShiftCost
+	flag.StringVar(&o.Size, "size", "5x5", "board size offered by default")
+	flag.Func("colors", "color scheme of the board: none, placed colors the tiles in place green and rows colors tiles by the row they belong in", func(s string) (err error) {
+		o.Colors, err = ParseColorScheme(s)
+		return err
+	})
+	flag.BoolVar(&o.Notifier.Bell, "bell", false, "ring the terminal bell when the board is solved or the time limit is reached")
+	flag.BoolVar(&o.Notifier.Desktop, "notify", false, "show a desktop notification when the board is solved or the time limit is reached")
+	flag.BoolVar(&o.Duel, "duel", false, "after each solve, compare your move count with the engine's solution to the same scramble")
+	flag.StringVar(&o.Player, "player", "player", "name to rate your races and record your scores under")
+	flag.BoolVar(&o.Verbose, "verbose", false, "print statistics on how the engine and tablebase solutions were found")
+	flag.BoolVar(&o.Scoring, "score", false, "score points for placing tiles, with combos for placing tiles on consecutive moves, penalties for undos and a bonus for solving quickly")
+	flag.Func("variant", "play a variant with different rules: lockin locks tiles moved into their place, blocking their row and column", func(s string) (err error) {
+		o.Variant, err = NewVariant(s)
+		return err
+	})
+	flag.Func("movable", "comma separated list of the only slices that can be moved, like rows, columns, or r0,r2", func(s string) (err error) {
+		o.Movable, err = ParseRestrictions(s)
+		return err
+	})
+	flag.BoolVar(&o.Strict, "strict", false, "only accept moves written exactly as Programmer's Notation describes them")
+	flag.BoolVar(&o.Echo, "echo", false, "echo every move back in normalized form along with what it does")
+	flag.StringVar(&o.LogFile, "log", "", "append every command, move, scramble and solve of the session to this file as lines of JSON")
+	flag.Func("notation", "notation to show moves in: programmer, standard or english, moves are always typed in Programmer's Notation", func(s string) (err error) {
+		o.MoveFormat.Notation, err = ParseNotation(s)
+		return err
+	})
+	flag.BoolVar(&o.MoveFormat.ReverseIndex, "reverse-index", false, "show indices in Programmer's Notation counted from the bottom or right when that makes them smaller, like 1R0'")
+	flag.IntVar(&o.AutosaveEvery, "autosave", DefaultAutosaveEvery, "save the solve every this many moves, to recover it if the game is interrupted, or 0 to not autosave")
+	flag.DurationVar(&o.TimeLimit, "time-limit", 0, "send a notification when a solve takes longer than this, like 2m30s")
+	flag.Func("scramble", "how shuffle scrambles the board: ask, random-state, fast, moves, few-moves, rows or corner", func(s string) (err error) {
+		o.Scramble, err = ParseScrambleKind(s)
+		return err
+	})
+	flag.Func("cost", "cost model solve looks for the cheapest solution in instead of the shortest, for physical puzzles: what a row shift, a column shift and starting a move cost, like row=1,column=1.5,start=2", func(s string) (err error) {
+		o.Cost, err = ParseCostModel(s)
+		return err
+	})
+	flag.DurationVar(&o.Inspection, "inspection", 0, "inspect every scramble for up to this long before starting the solve, like 15s, with penalties for starting late or moving during inspection")
+	var inputs []string
+	flag.Func("input", "also take moves from a hardware controller: midi or gamepad, optionally followed by the path of the device like midi:/dev/snd/midiC1D0. Can be given more than once", func(s string) error {
+		inputs = append(inputs, s)
+		return nil
+	})
+	flag.StringVar(&o.Rig, "rig", "", "mirror the board on a physical Loopover build at this TCP address, like rig.local:9000, or serial port, like /dev/ttyUSB0@115200")
+	flag.DurationVar(&o.RigPace, "rig-pace", 0, "least time between two commands sent to the rig, like 200ms")
+	flag.StringVar(&o.SolveTrace, "solve-trace", "", "with -log-level debug, record the search of every solve command to this file, to inspect it with the trace command")
+	flag.Func("log-level", "write debugging logs of this level and above to the standard error: debug, info, warn or error", func(s string) error {
+		l, err := ParseLogLevel(s)
+		if err != nil {
+			return err
+		}
+		logLevel.Set(l)
+		return nil
+	})
+	flag.Func("log-format", "format of the debugging logs: text or json", func(s string) error {
+		f, err := ParseLogFormat(s)
+		if err != nil {
+			return err
 		}
+		logger = newLogger(f)
+		return nil
+	})
+	flag.Parse()
 
-		finalIters := b.Shuffle(iters)
-		fmt.Printf("Shuffled board with %d iterations\n", finalIters)
+	if *cryptoRand {
+		UseCryptoRand()
+	}
 
-		done = true
+	if flag.NArg() > 0 {
+		if err := RunCommand(flag.Arg(0), flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
-}
 
-func main() {
-	var b Board
-	scanner := bufio.NewScanner(os.Stdin)
+	con := NewConsole(os.Stdin, os.Stdout)
+	if len(inputs) != 0 {
+		drivers := make([]InputDriver, 0, len(inputs))
+		for _, spec := range inputs {
+			d, err := OpenInputDriver(spec)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Could not open input %s (%s)\n", spec, err)
+				os.Exit(1)
+			}
+			drivers = append(drivers, d)
+		}
 
-	// scan board size.
-	fmt.Print("Input board size (default is 5x5): ")
-	for scanner.Scan() {
-		var w, h int
-		var err error
+		o.Input = NewInputMux(os.Stdin, os.Stdout, drivers)
+		con = NewConsole(o.Input, os.Stdout)
+	}
 
-		s := scanner.Text()
+	// the setup wizard runs on the first game played on a terminal, and writes the config the next games start from.
+	cfg, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the settings (%s)\n", err)
+	} else {
+		if *setup || !cfg.exists && isTerminal(os.Stdin) {
+			if !ScanSetup(con, cfg) {
+				return
+			}
+		}
+		if err := cfg.Apply(&o, setFlags()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
 
-		if s == "" {
-			w = 5
-			h = 5
-		} else {
-			var err error
-			w, h, err = ParseTwoDimensions(s)
+		if *preset != "" {
+			p, err := cfg.FindPreset(*preset)
+			if err == nil {
+				err = p.Apply(&o, setFlags())
+			}
 			if err != nil {
-				fmt.Printf("Invalid size (%s), try again: ", err)
-				continue
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
 			}
+			fmt.Printf("Playing preset %s\n", p)
+		}
+	}
+
+	play(con, o)
+}
+
+// PlayOptions holds the settings of the interactive game.
+type PlayOptions struct {
+	// Notifier sends notifications when the board is solved or a solve takes longer than TimeLimit.
+	Notifier  Notifier
+	TimeLimit time.Duration
+	// Echo echoes every move back in normalized form along with what it does.
+	Echo bool
+	// Strict only accepts moves that match the grammar of Programmer's Notation exactly.
+	Strict bool
+	// Duel compares every solve with the engine's solution to the same scramble.
+	Duel bool
+	// Verbose prints statistics on how solutions were found along with them.
+	Verbose bool
+	// Scoring keeps a score of every solve.
+	Scoring bool
+	// Player is the name races are rated and scores are recorded under.
+	Player string
+	// Movable limits which slices can be moved, if not nil.
+	Movable *Restrictions
+	// Variant changes the rules, if not nil.
+	Variant Variant
+	// Input merges the moves of hardware controllers with the keyboard's input, if not nil.
+	Input *InputMux
+	// Rig is the address of a physical Loopover build to mirror the board on, if not empty, and RigPace the least time between two of its commands.
+	Rig     string
+	RigPace time.Duration
+	// LogFile is the file the session is logged to, if not empty.
+	LogFile string
+	// MoveFormat is how moves are shown. Moves are always typed in Programmer's Notation.
+	MoveFormat MoveFormat
+	// AutosaveEvery is how many moves are made between autosaves of the solve, or 0 to not autosave.
+	AutosaveEvery int
+	// Size is the board size offered by default.
+	Size string
+	// Colors is how the board is colored.
+	Colors ColorScheme
+	// Scramble is how the shuffle command scrambles the board.
+	Scramble ScrambleKind
+	// Cost is the cost model solutions are optimized for.
+	Cost CostModel
+	// SolveTrace is the file the searches of the solve command are recorded to in debug mode, if not empty.
+	SolveTrace string
+	// Metric is how moves are counted.
+	Metric Metric
+	// Inspection is how long scrambles can be inspected before the solve starts, or 0 to start solves with the first move.
+	Inspection time.Duration
+}
+
+// play runs the interactive game on a console with the given settings.
+func play(con *Console, o PlayOptions) {
+	// a telemetry report that's due is sent while playing, if the user opted in.
+	go SendTelemetry()
+
+	echo := o.Echo
+	var g *Game
+
+	var autosavePath string
+	if o.AutosaveEvery > 0 {
+		if path, err := AutosavePath(); err == nil {
+			autosavePath = path
+			g = scanRecoverAutosave(con, path)
+		}
+	}
+
+	// scan board size, unless a solve was recovered.
+	if g == nil {
+		fmt.Fprintf(con, "Input board size (default is %s): ", o.Size)
+	}
+	for g == nil && con.Scan() {
+		s := con.Text()
+
+		if s == "" {
+			s = o.Size
+		}
+		w, h, err := ParseTwoDimensions(s)
+		if err != nil {
+			fmt.Fprintf(con, "Invalid size (%s), try again: ", err)
+			continue
 		}
 
-		b, err = NewBoard(w, h)
+		g, err = NewGame(w, h)
 		if err != nil {
-			fmt.Printf("Error creating board (%s), try again: ", err)
+			fmt.Fprintf(con, "Error creating board (%s), try again: ", err)
 			continue
 		}
 
 		break
 	}
+	if g == nil {
+		// the input ended before a board size was given.
+		return
+	}
+
+	b := &g.Board
+	var engine []Move
+
+	var plugins Plugins
+	if dir, err := PluginsDir(); err == nil {
+		var errs []error
+		plugins, errs = StartPlugins(dir)
+		for _, err := range errs {
+			fmt.Fprintf(con, "Could not start plugin (%s)\n", err)
+		}
+	}
+	defer plugins.Close()
+	g.Notifier = o.Notifier
+	g.Notifier.Out = con
+	if autosavePath != "" {
+		g.Autosave = &Autosave{Path: autosavePath, Every: o.AutosaveEvery}
+	}
+	g.TimeLimit = o.TimeLimit
+	g.Inspection = o.Inspection
+	g.Restrictions = o.Movable
+	if o.Movable != nil {
+		fmt.Fprintf(con, "Only %s can be moved\n", o.Movable)
+	}
+	g.Variant = o.Variant
+	if o.Variant != nil {
+		o.Variant.Restart(b)
+		fmt.Fprintf(con, "Playing the %s variant\n", o.Variant.Name())
+	}
+	if o.Scoring {
+		g.Score = NewScore(b.Placed())
+	}
+	if o.Rig != "" {
+		r, err := DialRig(o.Rig, b.Width(), b.Height())
+		if err != nil {
+			fmt.Fprintf(con, "Could not connect to the rig (%s)\n", err)
+		} else {
+			defer r.Close(5 * time.Second)
+			r.Pace = o.RigPace
+			r.State(b)
+			g.Rig = r
+			fmt.Fprintf(con, "Mirroring the board on the rig at %s\n", o.Rig)
+		}
+	}
+	if o.LogFile != "" {
+		l, err := OpenSessionLog(o.LogFile)
+		if err != nil {
+			fmt.Fprintf(con, "Could not open session log (%s)\n", err)
+		} else {
+			defer l.Close()
+			g.Log = l
+		}
+	}
+
+	o.Input.Start()
+
+	// the rows and columns solved since the board was last shown are announced and flashed.
+	type completion struct {
+		Axis    Axis
+		Index   int
+		Elapsed time.Duration
+	}
+	var completed []completion
+	g.Observers = append(g.Observers, ObserverFunc(func(g *Game, a Axis, index int) {
+		completed = append(completed, completion{a, index, g.Elapsed()})
+	}))
 
-	n := 0
+	// the game is only unlocked while waiting for input, so that survival can make its moves.
+	sg := NewSafeGame(g)
+	sg.Lock()
 
 	// game loop.
 	for {
-		fmt.Println()
+		fmt.Fprintln(con)
 
 		// present board state.
-		fmt.Println("Board state:")
-		fmt.Println(SprintBoard(&b))
+		fmt.Fprintln(con, "Board state:")
+		if b.IsSolved() {
+			fmt.Fprintln(con, o.Colors.SprintGoalBoard(b, g.Goal, nil))
+		} else {
+			fmt.Fprintln(con, o.Colors.SprintGoalBoard(b, g.Goal, func(x, y int) bool {
+				for _, c := range completed {
+					if c.Axis == HorizontalAxis && c.Index == y || c.Axis == VerticalAxis && c.Index == x {
+						return true
+					}
+				}
+				return false
+			}))
 
-		fmt.Printf("%d moves so far\n", n)
+			for _, c := range completed {
+				slice := "column"
+				if c.Axis == HorizontalAxis {
+					slice = "row"
+				}
+				fmt.Fprintf(con, "Completed %s %d at %.2fs\n", slice, c.Index, c.Elapsed.Seconds())
+			}
+		}
+		completed = nil
+
+		if o.Metric == SliceMetric {
+			fmt.Fprintf(con, "%d slice moves so far (%d single shifts)\n", g.MovesIn(SliceMetric), g.Moves)
+		} else {
+			fmt.Fprintf(con, "%d moves so far\n", g.Moves)
+		}
+		fmt.Fprintln(con, g.Progress())
+		if g.Inspecting() {
+			fmt.Fprintf(con, "Inspecting: %.1fs of %s, type start to start the solve\n", g.InspectionElapsed().Seconds(), g.Inspection)
+		}
+		if p := g.Penalty.String(); p != "" {
+			fmt.Fprintf(con, "Penalty: %s\n", p)
+		}
+		if g.Score != nil {
+			fmt.Fprintln(con, g.Score)
+		}
+		if r := g.Race; r != nil {
+			fmt.Fprintf(con, "%s: %d/%d moves\n", r.Opponent.Name(), r.OpponentMoves(g.Elapsed()), r.Moves)
+		}
+		if gh := g.Ghost; gh != nil {
+			rows, _ := b.SolvedRegion()
+			fmt.Fprintf(con, "Ghost of %s: %d/%d moves, %d rows solved to your %d\n", gh.Name, gh.MovesAt(g.Elapsed()), len(gh.Moves), gh.RowsAt(g.Elapsed()), rows)
+		}
 
 		if b.IsSolved() {
-			fmt.Println("Solved")
+			fmt.Fprintln(con, "Solved")
+		}
+
+		if c := g.History.Current.Comment; c != "" {
+			fmt.Fprintf(con, "Comment: %s\n", c)
 		}
 
 		// scan for moves.
-		fmt.Print("Move: ")
-		for scanner.Scan() {
-			switch s := strings.ToLower(scanner.Text()); s {
+		fmt.Fprint(con, "Move: ")
+		for {
+			if !scanUnlocked(con, sg) {
+				// the input ended.
+				g.StopSurvival()
+				sg.Unlock()
+				return
+			}
+
+			s := strings.ToLower(con.Text())
+			cmd, arg := SplitCommand(con.Text())
+			cmd = strings.ToLower(cmd)
+			isMove := false
+
+			switch cmd {
 			case "shuffle":
-				ScanShuffle(&b, scanner)
+				style := o.Scramble
+				if arg = strings.TrimSpace(arg); arg != "" {
+					var err error
+					if style, err = ParseScrambleKind(arg); err != nil {
+						fmt.Fprintf(con, "%s, try again: ", err)
+						continue
+					}
+				}
+
+				if g.Restrictions != nil {
+					// fast shuffles and scrambles can reach arrangements that restricted moves can't solve.
+					fmt.Fprintf(con, "Shuffled board with %d iterations of movable slices\n", b.ShuffleRestricted(g.Restrictions, 0))
+				} else if style != AskScramble {
+					fmt.Fprintln(con, style.Scramble(b))
+				} else {
+					if !ScanShuffle(b, con) {
+						continue
+					}
+				}
+				g.Restart()
+			case "start":
+				if !g.Inspecting() {
+					fmt.Fprint(con, "Not inspecting a scramble, try again: ")
+					continue
+				}
+
+				took := g.StartSolve()
+				fmt.Fprintf(con, "Solve started after %.1fs of inspection\n", took.Seconds())
+			case "dnf":
+				if !g.GiveUp() {
+					fmt.Fprint(con, "No solve in progress, try again: ")
+					continue
+				}
+
+				if err := g.RecordSolve(o.Player); err != nil {
+					logger.Warn("could not record solve", "err", err)
+				}
+				if g.Ghost != nil {
+					fmt.Fprintln(con, g.FinishGhostRace())
+				}
+				if g.Race != nil {
+					res, err := g.FinishRace(o.Player)
+					fmt.Fprintln(con, res)
+					if err != nil {
+						fmt.Fprintf(con, "Could not record race (%s)\n", err)
+					}
+				}
+				b.Reset()
+				g.Restart()
+				fmt.Fprintln(con, "Solve given up and recorded as a DNF")
+			case "split":
+				name, ok := g.MarkSplit(strings.TrimSpace(arg))
+				if !ok {
+					fmt.Fprint(con, "Splits are marked after a move, try again: ")
+					continue
+				}
+
+				fmt.Fprintf(con, "%s at %.2fs\n", name, g.History.Current.Elapsed.Seconds())
 			case "reset":
 				b.Reset()
-				n = 0
-				fmt.Println("Board reset")
+				g.Restart()
+				fmt.Fprintln(con, "Board reset")
+			case "scramble":
+				k, err := strconv.Atoi(arg)
+				if err != nil || k < 0 {
+					fmt.Fprint(con, "Usage is \"scramble K\" with K a positive number, try again: ")
+					continue
+				}
+				if k > MaxScrambleDepth {
+					fmt.Fprintf(con, "Scrambles can be at most %d moves, try again: ", MaxScrambleDepth)
+					continue
+				}
+				if g.Restrictions != nil {
+					fmt.Fprint(con, "Scrambles can't be made with restricted moves, use \"shuffle\" or \"challenge\" instead, try again: ")
+					continue
+				}
+
+				b.ScrambleDepth(k)
+				g.Restart()
+				fmt.Fprintf(con, "Scrambled board %d moves away from solved, try solving it in %d moves or less\n", k, k)
+			case "order":
+				seq, err := ParseMoves(arg, b)
+				if err != nil {
+					fmt.Fprintf(con, "Invalid move (%s), try again: ", err)
+					continue
+				}
+
+				fmt.Fprintf(con, "Sequence order is %d\n", Order(seq, b))
+			case "undo":
+				m, ok := g.Undo()
+				if !ok {
+					fmt.Fprint(con, "Nothing to undo, try again: ")
+					continue
+				}
+
+				fmt.Fprintf(con, "Undid %s\n", m)
+			case "redo":
+				branch := -1
+				if arg != "" {
+					var err error
+					if branch, err = strconv.Atoi(arg); err != nil || branch < 0 {
+						fmt.Fprint(con, "Usage is \"redo\" or \"redo BRANCH\", try again: ")
+						continue
+					}
+				}
+
+				m, ok := g.Redo(branch)
+				if !ok {
+					fmt.Fprint(con, "Nothing to redo, try again: ")
+					continue
+				}
+
+				fmt.Fprintf(con, "Redid %s\n", m)
+			case "branches":
+				moves, active := g.History.Branches()
+				if len(moves) == 0 {
+					fmt.Fprintln(con, "No moves were made from here")
+					break
+				}
+
+				for i, m := range moves {
+					mark := " "
+					if i == active {
+						mark = "*"
+					}
+					fmt.Fprintf(con, "%s %d: %s\n", mark, i, o.MoveFormat.Format(m, b))
+				}
+			case "history":
+				fmt.Fprintln(con, g.History.Format(o.MoveFormat, b))
+			case "comment":
+				g.History.Current.Comment = strings.Trim(arg, `"`)
+				if arg == "" {
+					fmt.Fprintln(con, "Comment removed")
+				} else {
+					fmt.Fprintln(con, "Comment added")
+				}
+			case "save":
+				if err := SaveReplay(arg, g.Replay()); err != nil {
+					fmt.Fprintf(con, "Could not save replay (%s), try again: ", err)
+					continue
+				}
+
+				fmt.Fprintf(con, "Replay saved to %s\n", arg)
+			case "load":
+				r, err := LoadReplayFile(arg)
+				if err != nil {
+					fmt.Fprintf(con, "Could not load replay (%s), try again: ", err)
+					continue
+				}
+
+				g.LoadReplay(r)
+				fmt.Fprintf(con, "Replay of %d moves loaded, use redo to play it back\n", len(r.Moves))
+			case "ghost":
+				r, err := LoadReplayFile(arg)
+				if err != nil {
+					fmt.Fprintf(con, "Could not load replay (%s), try again: ", err)
+					continue
+				}
+				gh, err := NewGhost(strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg)), r)
+				if err != nil {
+					fmt.Fprintf(con, "Could not race the replay (%s), try again: ", err)
+					continue
+				}
+
+				g.StartGhostRace(gh)
+				fmt.Fprintf(con, "Racing the ghost of %s, who solved this scramble in %.2fs, its clock starts with your first move\n", gh.Name, gh.Time().Seconds())
+			case "reconstruct":
+				if err := WriteReconstruction(arg, g.Replay(), o.MoveFormat); err != nil {
+					fmt.Fprintf(con, "Could not write reconstruction (%s), try again: ", err)
+					continue
+				}
+
+				fmt.Fprintf(con, "Reconstruction written to %s\n", arg)
+			case "challenge":
+				depth, err := strconv.Atoi(arg)
+				if err != nil || depth < 1 {
+					fmt.Fprint(con, "Usage is \"challenge N\" with N a positive number, try again: ")
+					continue
+				}
+
+				c, err := NewChallenge(b.Width(), b.Height(), depth, g.Restrictions)
+				if err != nil {
+					fmt.Fprintf(con, "Could not create challenge (%s), try again: ", err)
+					continue
+				}
+
+				*b = c.Board
+				g.Restart()
+				g.Challenge = c
+				fmt.Fprintf(con, "Solve this board in exactly %d moves, it can't be done in less\n", depth)
+			case "checkpoint":
+				action, name := SplitCommand(arg)
+				switch {
+				case action == "save" && name != "":
+					g.SaveCheckpoint(name)
+					fmt.Fprintf(con, "Saved checkpoint %q\n", name)
+				case action == "restore" && name != "":
+					if err := g.RestoreCheckpoint(name); err != nil {
+						fmt.Fprintf(con, "Could not restore checkpoint (%s), try again: ", err)
+						continue
+					}
+					fmt.Fprintf(con, "Restored checkpoint %q\n", name)
+				case action == "list" && name == "":
+					for _, name := range g.CheckpointNames() {
+						c := g.Checkpoints[name]
+						fmt.Fprintf(con, "%s: %d moves in, %d/%d placed\n", name, c.Moves, c.Board.Placed(), c.Board.Width()*c.Board.Height())
+					}
+				default:
+					fmt.Fprint(con, "Usage is \"checkpoint save NAME\", \"checkpoint restore NAME\" or \"checkpoint list\", try again: ")
+					continue
+				}
+			case "goal":
+				action, name := SplitCommand(arg)
+				switch {
+				case action == "":
+					fmt.Fprintf(con, "Solving into %s\n", g.Goal)
+				case action == "set" && name != "":
+					goal, err := NewGoal(name, b.Width(), b.Height())
+					if err != nil {
+						fmt.Fprintf(con, "%s, try again: ", err)
+						continue
+					}
+
+					g.Goal = goal
+					fmt.Fprintf(con, "Solving into %s from now on, with the tiles numbered to match\n", goal)
+				case action == "list" && name == "":
+					for _, t := range GoalTemplates {
+						fmt.Fprintf(con, "%s: %s\n", t.Name, t.Description)
+					}
+				default:
+					fmt.Fprint(con, "Usage is \"goal\", \"goal set NAME\" or \"goal list\", try again: ")
+					continue
+				}
+			case "survival":
+				interval := 5 * time.Second
+				if arg != "" {
+					var err error
+					if interval, err = time.ParseDuration(arg); err != nil || interval <= 0 {
+						fmt.Fprint(con, "Usage is \"survival\" or \"survival INTERVAL\" like 3s, try again: ")
+						continue
+					}
+				}
+
+				err := g.StartSurvival(interval, sg, func(m Move, lost bool) {
+					fmt.Fprintf(con, "\nChaos made %s:\n%s\n", m, NoColors.SprintGoalBoard(b, g.Goal, nil))
+					if lost {
+						fmt.Fprintf(con, "Chaos wins, the board got %d away from solved\n", g.Board.Distance())
+					}
+					fmt.Fprint(con, "Move: ")
+				})
+				if err != nil {
+					fmt.Fprintf(con, "Could not start survival (%s), try again: ", err)
+					continue
+				}
+				fmt.Fprintf(con, "Survival started: a random move is made every %s, solve the board before it gets %d away from solved\n", interval, g.Survival.Limit)
+			case "race":
+				e, err := ParseOpponent(strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(arg, "--vs"), "-vs")))
+				if err != nil {
+					fmt.Fprintf(con, "Invalid opponent (%s), try again: ", err)
+					continue
+				}
+
+				if err := g.StartRace(e); err != nil {
+					fmt.Fprintf(con, "Could not start race (%s), try again: ", err)
+					continue
+				}
+
+				fmt.Fprintf(con, "Racing %s, its clock starts with your first move\n", e.Name())
+			case "engine":
+				if arg != "" {
+					p, ok := plugins.Solver(arg)
+					if !ok {
+						fmt.Fprintf(con, "No plugin adds a solver named %q, try again: ", arg)
+						continue
+					}
+
+					seq, err := p.Solve(arg, b)
+					if err != nil {
+						fmt.Fprintf(con, "Could not solve (%s), try again: ", err)
+						continue
+					}
+
+					fmt.Fprintf(con, "Solution by %s (%d moves): %s\n", arg, MovesLength(seq), o.MoveFormat.FormatMoves(seq, b))
+					break
+				}
+
+				if engine == nil {
+					fmt.Fprint(con, "No engine solution yet, start the game with -duel to get one after each solve, try again: ")
+					continue
+				}
+
+				fmt.Fprintln(con, o.MoveFormat.FormatMoves(engine, b))
+			case "solve":
+				maxMoves, err := strconv.Atoi(arg)
+				if err != nil || maxMoves < 0 {
+					fmt.Fprint(con, "Usage is \"solve N\" with N the most moves to look for a solution in, try again: ")
+					continue
+				}
+
+				if o.Cost != ShiftCost {
+					res, ok := SolveCheapest(b, maxMoves, o.Cost, g.Restrictions)
+					if ok {
+						fmt.Fprintf(con, "Cheapest solution (cost %g, %d moves): %s\n", o.Cost.Cost(res.Moves, b), len(res.Moves), o.MoveFormat.FormatMoves(SimplifyMoves(res.Moves, b), b))
+					} else {
+						fmt.Fprintf(con, "No solution in %d moves or less\n", maxMoves)
+					}
+					if o.Verbose {
+						fmt.Fprintln(con, res)
+					}
+					break
+				}
+
+				// in debug mode, the search is recorded for the trace command.
+				var t *SearchTrace
+				if o.SolveTrace != "" && logLevel.Level() <= slog.LevelDebug {
+					t = &SearchTrace{}
+				}
+				res, ok := SolveWithinSeeded(b, maxMoves, g.Restrictions, 0, t)
+				if t != nil {
+					if err := SaveSearchTrace(o.SolveTrace, t); err != nil {
+						logger.Warn("could not save search trace", "path", o.SolveTrace, "err", err)
+					} else {
+						logger.Debug("search trace saved", "path", o.SolveTrace, "positions", len(t.Nodes))
+					}
+				}
+				if ok {
+					fmt.Fprintf(con, "Shortest solution (%d moves): %s\n", len(res.Moves), o.MoveFormat.FormatMoves(res.Moves, b))
+				} else {
+					fmt.Fprintf(con, "No solution in %d moves or less\n", maxMoves)
+				}
+				if o.Verbose {
+					fmt.Fprintln(con, res)
+				}
+			case "watch":
+				maxMoves, delay, err := parseWatchArgs(arg)
+				if err != nil {
+					fmt.Fprint(con, "Usage is \"watch N [DELAY]\" with N the most moves to look for a solution in, and optionally a pause on every position like 100ms, try again: ")
+					continue
+				}
+
+				v := &SearchView{Out: con, Colors: o.Colors, Format: o.MoveFormat, Interval: 50 * time.Millisecond, Delay: delay}
+				res, ok := SolveWithinWatched(b, maxMoves, g.Restrictions, v)
+				if ok {
+					fmt.Fprintf(con, "Shortest solution (%d moves): %s\n", len(res.Moves), o.MoveFormat.FormatMoves(res.Moves, b))
+				} else {
+					fmt.Fprintf(con, "No solution in %d moves or less\n", maxMoves)
+				}
+				fmt.Fprintln(con, res)
+			case "tablebase":
+				if g.Restrictions != nil {
+					fmt.Fprint(con, "Tablebases are made with every move allowed, try again: ")
+					continue
+				}
+
+				t, err := LoadTablebase(b.Width(), b.Height())
+				if err != nil {
+					fmt.Fprintf(con, "Could not load tablebase (%s), try again: ", err)
+					continue
+				}
+
+				res, err := t.Solve(b)
+				if err != nil {
+					fmt.Fprintf(con, "Could not probe tablebase (%s), try again: ", err)
+					continue
+				}
+
+				fmt.Fprintf(con, "Optimal continuation (%d moves): %s\n", len(res.Moves), o.MoveFormat.FormatMoves(res.Moves, b))
+				if o.Verbose {
+					fmt.Fprintln(con, res)
+				}
+			case "preview":
+				m, err := ParseMove(strings.ToLower(arg), b)
+				if err != nil {
+					fmt.Fprintf(con, "Invalid move (%s), try again: ", err)
+					continue
+				}
+
+				pb := b.Applied(m)
+				fmt.Fprintf(con, "Board after %s:\n", arg)
+				fmt.Fprintln(con, NoColors.SprintGoalBoard(&pb, g.Goal, nil))
+			case "echo":
+				switch arg {
+				case "on":
+					echo = true
+				case "off":
+					echo = false
+				default:
+					fmt.Fprint(con, "Usage is \"echo on\" or \"echo off\", try again: ")
+					continue
+				}
+
+				fmt.Fprintf(con, "Move echo is %s\n", arg)
+			case "plugins":
+				if len(plugins) == 0 {
+					fmt.Fprintln(con, "No plugins")
+					break
+				}
+
+				fmt.Fprintln(con, plugins)
+			case "render":
+				p, ok := plugins.Renderer(arg)
+				if !ok {
+					fmt.Fprintf(con, "No plugin adds a renderer named %q, try again: ", arg)
+					continue
+				}
+
+				out, err := p.Render(arg, b)
+				if err != nil {
+					fmt.Fprintf(con, "Could not render the board (%s), try again: ", err)
+					continue
+				}
+
+				fmt.Fprintln(con, strings.TrimRight(out, "\n"))
+			case "edit":
+				if ScanEdit(b, con) {
+					g.Restart()
+				}
+			case "qr":
+				q, err := EncodeQR([]byte(EncodeState(b)))
+				if err != nil {
+					fmt.Fprintf(con, "Could not make a QR code (%s), try again: ", err)
+					continue
+				}
+
+				// start on a new line so the prompt doesn't shift the first row.
+				fmt.Fprintf(con, "\n%s\n", q.Terminal(arg == "invert"))
+			case "export":
+				if arg == "" {
+					fmt.Fprintln(con, EncodeState(b))
+					break
+				}
+
+				if err := ExportFile(b, arg); err != nil {
+					fmt.Fprintf(con, "Could not export board (%s), try again: ", err)
+					continue
+				}
+				fmt.Fprintf(con, "Board exported to %s\n", arg)
+			case "copy":
+				var text string
+				switch arg {
+				case "", "state":
+					text = EncodeState(b)
+				case "scramble":
+					text = EncodeState(&g.Scramble)
+				case "moves":
+					text = o.MoveFormat.FormatMoves(g.History.Path(), b)
+				default:
+					fmt.Fprint(con, "Usage is \"copy\", \"copy scramble\" or \"copy moves\", try again: ")
+					continue
+				}
+
+				if err := CopyToClipboard(text); err != nil {
+					fmt.Fprintf(con, "Could not copy (%s), try again: ", err)
+					continue
+				}
+				fmt.Fprintf(con, "Copied %s\n", text)
+			case "paste":
+				text, err := PasteFromClipboard()
+				if err != nil {
+					fmt.Fprintf(con, "Could not paste (%s), try again: ", err)
+					continue
+				}
+
+				nb, err := ParsePasted(text, b)
+				if err != nil {
+					fmt.Fprintf(con, "Invalid paste (%s), try again: ", err)
+					continue
+				}
+
+				*b = nb
+				g.Restart()
+				fmt.Fprintln(con, "Board pasted")
+			case "import":
+				var nb Board
+				var err error
+				if IsGridFile(arg) {
+					nb, err = ImportFile(arg)
+				} else if IsImageFile(arg) {
+					var res *OCRResult
+					if res, err = ImportScreenshot(arg, b.Width(), b.Height()); err == nil {
+						nb = res.Board
+						if res.Unsure() {
+							fmt.Fprintln(con, "Some tiles barely matched, check the board against the screenshot")
+						}
+					}
+				} else {
+					nb, err = DecodeState(arg)
+				}
+				if err != nil {
+					fmt.Fprintf(con, "Invalid state (%s), try again: ", err)
+					continue
+				}
+
+				*b = nb
+				g.Restart()
+				fmt.Fprintln(con, "Board imported")
 			default:
-				m, err := ParseMove(s, &b)
+				if p, ok := plugins.Command(cmd); ok {
+					res, err := p.RunCommand(cmd, arg, b)
+					if err != nil {
+						fmt.Fprintf(con, "Could not run %s (%s), try again: ", cmd, err)
+						continue
+					}
+
+					if res.Output != "" {
+						fmt.Fprintln(con, strings.TrimRight(res.Output, "\n"))
+					}
+					if res.State != "" {
+						nb, err := DecodeState(res.State)
+						if err != nil {
+							fmt.Fprintf(con, "Plugin %s gave an invalid state (%s)\n", p.Name, err)
+							break
+						}
+
+						*b = nb
+						g.Restart()
+					}
+					if res.Moves != "" {
+						seq, err := ParseMoves(res.Moves, b)
+						if err != nil {
+							fmt.Fprintf(con, "Plugin %s gave invalid moves (%s)\n", p.Name, err)
+							break
+						}
+
+						for i := range seq {
+							if err := g.CheckMove(&seq[i]); err != nil {
+								fmt.Fprintf(con, "Plugin %s made an invalid move (%s)\n", p.Name, err)
+								break
+							}
+							g.MakeMove(&seq[i])
+						}
+					}
+					break
+				}
+
+				isMove = true
+				input, parse := strings.TrimSpace(s), ParseMove
+				if o.Strict {
+					input, parse = con.Text(), ParseMoveStrict
+				}
+
+				m, err := parse(input, b)
 				if err != nil {
-					fmt.Printf("Invalid move (%s), try again: ", err)
+					logger.Debug("invalid move", "input", input, "strict", o.Strict, "err", err)
+					if suggestion, ok := SuggestMove(input, b); ok && !o.Strict {
+						fmt.Fprintf(con, "Invalid move (%s), did you mean %s? Try again: ", err, suggestion)
+					} else {
+						fmt.Fprintf(con, "Invalid move (%s), try again: ", err)
+					}
+					continue
+				}
+				logger.Debug("parsed move", "input", input, "move", m)
+				if err := g.CheckMove(m); err != nil {
+					fmt.Fprintf(con, "Invalid move (%s), try again: ", err)
 					continue
 				}
 
-				n += b.MakeMove(m)
+				if echo {
+					nm := b.NormalizeMove(*m)
+					fmt.Fprintf(con, "%s: %s\n", nm, nm.Describe())
+				}
+
+				if g.Inspecting() {
+					fmt.Fprintln(con, "Moved during inspection, +2")
+				}
+				g.MakeMove(m)
+				if err := g.notifyMove(); err != nil {
+					fmt.Fprintf(con, "Could not send notification (%s)\n", err)
+				}
+
+				if b.IsSolved() {
+					if !g.Penalty.DNF {
+						if err := RecordTelemetry(b.Width(), b.Height(), g.Penalty.Add(g.Elapsed()), g.Moves); err != nil {
+							logger.Warn("could not record telemetry", "err", err)
+						}
+					}
+					if r := g.Replay(); r.Times != nil {
+						fmt.Fprintln(con, SprintSplits(r, 30))
+					}
+					if err := g.RecordSolve(o.Player); err != nil {
+						logger.Warn("could not record solve", "err", err)
+					}
+				}
+
+				if g.Ghost != nil && b.IsSolved() {
+					fmt.Fprintln(con, g.FinishGhostRace())
+				}
+
+				if g.Race != nil && b.IsSolved() {
+					res, err := g.FinishRace(o.Player)
+					fmt.Fprintln(con, res)
+					if err != nil {
+						fmt.Fprintf(con, "Could not record race (%s)\n", err)
+					}
+				}
+
+				if s := g.Survival; s != nil && b.IsSolved() {
+					fmt.Fprintf(con, "You survived %d chaos moves in %.1fs!\n", s.Injected, time.Since(s.Start).Seconds())
+					g.StopSurvival()
+				}
+
+				if g.Score != nil && !g.Score.Finished && b.IsSolved() {
+					res, err := g.FinishScore(o.Player)
+					fmt.Fprintln(con, res)
+					if err != nil {
+						fmt.Fprintf(con, "Could not record score (%s)\n", err)
+					}
+				}
+
+				if c := g.Challenge; c != nil && b.IsSolved() {
+					if err := c.Check(g.History.Path()); err != nil {
+						fmt.Fprintf(con, "Challenge failed: %s\n", err)
+					} else {
+						fmt.Fprintf(con, "Challenge complete, solved in the least possible %d moves!\n", c.Depth)
+					}
+					g.Challenge = nil
+				}
+
+				if o.Duel && b.IsSolved() && g.Restrictions == nil {
+					res, err := SolveHuman(&g.Scramble)
+					if err != nil {
+						fmt.Fprintf(con, "Engine could not solve the scramble (%s)\n", err)
+						break
+					}
+					engine = res.Moves
+
+					fmt.Fprintf(con, "You: %d moves / engine: %d moves / optimal: at least %d moves\n", g.Moves, MovesLength(engine), LowerBound(&g.Scramble))
+					if o.Verbose {
+						fmt.Fprintln(con, res)
+					}
+					fmt.Fprintln(con, `Type "engine" to see the engine's solution`)
+				}
 			}
 
+			if !isMove {
+				g.logEvent(LogEvent{Type: "command", Command: cmd, Arg: arg})
+			}
 			break
 		}
 	}
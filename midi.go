@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
+
+// MIDIDriver generates moves from the notes played on a MIDI controller, like a pad controller, read from a raw MIDI device.
+// Every slice has two notes, counted from Base: Base shifts row 0 right and Base+1 shifts it left, Base+2 shifts column 0 down and Base+3 shifts it up,
+// then Base+4 to Base+7 do the same for row 1 and column 1, and so on.
+type MIDIDriver struct {
+	f *os.File
+	// Base is the note the moves start from, 36 by default, the first pad of most pad controllers.
+	Base int
+}
+
+// OpenMIDI opens a raw MIDI device, like /dev/snd/midiC1D0 on Linux.
+func OpenMIDI(path string) (*MIDIDriver, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("raw MIDI devices are only available on Linux")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MIDIDriver{f: f, Base: 36}, nil
+}
+
+func (d *MIDIDriver) Name() string {
+	return "MIDI " + d.f.Name()
+}
+
+func (d *MIDIDriver) Run(moves chan<- Move, messages io.Writer) error {
+	return readMIDINotes(bufio.NewReader(d.f), func(note byte) {
+		if m, ok := d.NoteMove(note); ok {
+			moves <- m
+		}
+	})
+}
+
+func (d *MIDIDriver) Close() error {
+	return d.f.Close()
+}
+
+// NoteMove returns the move of a note, and false if the note is below Base.
+func (d *MIDIDriver) NoteMove(note byte) (Move, bool) {
+	k := int(note) - d.Base
+	if k < 0 {
+		return Move{}, false
+	}
+
+	m := Move{Axis: HorizontalAxis, Index: k / 4, Amount: 1}
+	if k%4 >= 2 {
+		m.Axis = VerticalAxis
+	}
+	if k%2 == 1 {
+		m.Amount = -1
+	}
+
+	return m, true
+}
+
+// readMIDINotes reads a stream of MIDI messages, calling `note` with every note that starts playing, until the stream ends.
+// Running status is followed, and system exclusive and real time messages are skipped.
+func readMIDINotes(r io.ByteReader, note func(n byte)) error {
+	var status byte
+	var data []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		switch {
+		case b >= 0xf8:
+			// real time messages can come in between the bytes of other messages.
+			continue
+		case b == 0xf0:
+			for b != 0xf7 {
+				if b, err = r.ReadByte(); err != nil {
+					return err
+				}
+			}
+			status = 0
+			continue
+		case b >= 0x80:
+			status, data = b, data[:0]
+			if midiDataLength(status) == 0 {
+				status = 0
+			}
+			continue
+		case status == 0:
+			continue
+		}
+
+		data = append(data, b)
+		if len(data) < midiDataLength(status) {
+			continue
+		}
+
+		// a note on with a velocity of 0 is a note off.
+		if status&0xf0 == 0x90 && data[1] != 0 {
+			note(data[0])
+		}
+		data = data[:0]
+		if status >= 0xf0 {
+			status = 0
+		}
+	}
+}
+
+// midiDataLength returns how many data bytes follow a status byte.
+func midiDataLength(status byte) int {
+	switch {
+	case status&0xf0 == 0xc0 || status&0xf0 == 0xd0 || status == 0xf1 || status == 0xf3:
+		return 1
+	case status < 0xf0 || status == 0xf2:
+		return 2
+	default:
+		return 0
+	}
+}
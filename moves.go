@@ -0,0 +1,562 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// String formats the move in Programmer's Notation, such as "-2R1".
+func (m Move) String() string {
+	axis := "C"
+	if m.Axis == HorizontalAxis {
+		axis = "R"
+	}
+
+	return strconv.Itoa(m.Amount) + axis + strconv.Itoa(m.Index)
+}
+
+// Describe explains what the move does in plain English, such as "shift row 1 left by 2".
+func (m Move) Describe() string {
+	slice, forward, backward := "column", "down", "up"
+	if m.Axis == HorizontalAxis {
+		slice, forward, backward = "row", "right", "left"
+	}
+
+	if m.Amount == 0 {
+		return fmt.Sprintf("leave %s %d as it is", slice, m.Index)
+	}
+
+	dir := forward
+	if m.Amount < 0 {
+		dir = backward
+	}
+
+	return fmt.Sprintf("shift %s %d %s by %d", slice, m.Index, dir, Abs(m.Amount))
+}
+
+// Standard formats the move in the notation most Loopover players use: a direction letter, R or L for rows and D or U for columns, followed by the 1-based index of the slice.
+// The move is normalized first and written once for every single shift it makes, such as "L2 L2" for "-2R1". A move that does nothing is an empty string.
+func (m Move) Standard(b *Board) string {
+	m = b.NormalizeMove(m)
+
+	dir := "D"
+	switch {
+	case m.Axis == HorizontalAxis && m.Amount > 0:
+		dir = "R"
+	case m.Axis == HorizontalAxis:
+		dir = "L"
+	case m.Amount < 0:
+		dir = "U"
+	}
+
+	s := make([]string, Abs(m.Amount))
+	for i := range s {
+		s[i] = dir + strconv.Itoa(m.Index+1)
+	}
+
+	return strings.Join(s, " ")
+}
+
+// Notation is a way of writing moves down.
+type Notation int
+
+const (
+	// ProgrammersNotation writes moves like Move.String. It is the notation moves are typed in during the game.
+	ProgrammersNotation Notation = iota
+	// StandardNotation writes moves like Move.Standard.
+	StandardNotation
+	// EnglishNotation writes moves like Move.Describe, with sequences separated by commas.
+	EnglishNotation
+)
+
+// ParseNotation parses the name of a notation: "programmer" or its short forms like "prog", "standard" or "english".
+func ParseNotation(s string) (Notation, error) {
+	switch strings.ToLower(s) {
+	case "programmer", "programmers", "prog", "pn":
+		return ProgrammersNotation, nil
+	case "standard":
+		return StandardNotation, nil
+	case "english":
+		return EnglishNotation, nil
+	default:
+		return 0, fmt.Errorf("unknown notation %q, expected programmer, standard or english", s)
+	}
+}
+
+// MoveFormat is how moves are written down.
+type MoveFormat struct {
+	Notation Notation
+	// ReverseIndex writes the index of a move in Programmer's Notation counting from the bottom or the right, followed by the reverse-index-indicator, when that makes it smaller, such as "1R0'" for "1R4" on a 5x5 board.
+	ReverseIndex bool
+}
+
+// Format writes a move made on the board.
+func (f MoveFormat) Format(m Move, b *Board) string {
+	switch f.Notation {
+	case StandardNotation:
+		return m.Standard(b)
+	case EnglishNotation:
+		return m.Describe()
+	}
+
+	if f.ReverseIndex {
+		if r := b.SliceCount(m.Axis) - 1 - m.Index; r < m.Index {
+			m.Index = r
+			return m.String() + "'"
+		}
+	}
+
+	return m.String()
+}
+
+// FormatMoves writes a sequence of moves made on the board, separated by spaces.
+func (f MoveFormat) FormatMoves(seq []Move, b *Board) string {
+	s := make([]string, 0, len(seq))
+	for _, m := range seq {
+		if fm := f.Format(m, b); fm != "" {
+			s = append(s, fm)
+		}
+	}
+
+	return strings.Join(s, f.separator())
+}
+
+// separator returns what goes between the moves of a sequence: a space, or a comma and a space between descriptions in English.
+func (f MoveFormat) separator() string {
+	if f.Notation == EnglishNotation {
+		return ", "
+	}
+
+	return " "
+}
+
+// NormalizeMove returns an equivalent move whose amount is the shortest way to get the same shift, between -length/2 (exclusive) and length/2 (inclusive).
+// A move that does nothing has an amount of 0.
+func (b *Board) NormalizeMove(m Move) Move {
+	l := b.SliceLength(m.Axis)
+
+	m.Amount %= l
+	if m.Amount < 0 {
+		m.Amount += l
+	}
+	if 2*m.Amount > l {
+		m.Amount -= l
+	}
+
+	return m
+}
+
+// AllMoves returns every distinct single move that can be made on the board, in normalized form.
+// Rows come first, then columns, each ordered by index and then amount.
+func AllMoves(b *Board) []Move {
+	var moves []Move
+	for _, a := range []Axis{HorizontalAxis, VerticalAxis} {
+		l := b.SliceLength(a)
+
+		for i := 0; i < b.SliceCount(a); i++ {
+			for amnt := -(l - 1) / 2; amnt <= l/2; amnt++ {
+				if amnt != 0 {
+					moves = append(moves, Move{Axis: a, Index: i, Amount: amnt})
+				}
+			}
+		}
+	}
+
+	return moves
+}
+
+// Neighbors returns a copy of the board for every move in AllMoves, in the same order, with that move made.
+func (b *Board) Neighbors() []Board {
+	moves := AllMoves(b)
+
+	boards := make([]Board, len(moves))
+	for i := range moves {
+		boards[i] = b.Clone()
+		boards[i].MakeMove(&moves[i])
+	}
+
+	return boards
+}
+
+// SuccessorMoves returns the moves in AllMoves that are worth trying after `last` during a search.
+// Moves on the same slice as `last` are left out, since together with it they would undo it or amount to a single move.
+func SuccessorMoves(b *Board, last Move) []Move {
+	all := AllMoves(b)
+
+	moves := all[:0]
+	for _, m := range all {
+		if m.Axis != last.Axis || m.Index != last.Index {
+			moves = append(moves, m)
+		}
+	}
+
+	return moves
+}
+
+// ParseMovesIn parses a sequence of moves written in any notation. Moves in Programmer's Notation and standard notation are separated by spaces or commas,
+// and the plain English descriptions of Move.Describe by commas or semicolons.
+func ParseMovesIn(n Notation, input string, board *Board) ([]Move, error) {
+	var parse func(string, *Board) (*Move, error)
+	seps := ", \t\n"
+	switch n {
+	case StandardNotation:
+		parse = ParseStandardMove
+	case EnglishNotation:
+		parse, seps = ParseEnglishMove, ",;\n"
+	default:
+		return ParseMoves(input, board)
+	}
+
+	fields := strings.FieldsFunc(input, func(r rune) bool { return strings.ContainsRune(seps, r) })
+	seq := make([]Move, 0, len(fields))
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+
+		m, err := parse(f, board)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, *m)
+	}
+
+	return seq, nil
+}
+
+// ParseStandardMove parses a single shift in standard notation, like "L2": a direction letter followed by the 1-based index of the slice.
+func ParseStandardMove(input string, board *Board) (*Move, error) {
+	if len(input) < 2 {
+		return nil, fmt.Errorf("invalid move %q, expected a direction and a slice like R1", input)
+	}
+
+	var m Move
+	switch unicode.ToUpper(rune(input[0])) {
+	case 'R':
+		m.Axis, m.Amount = HorizontalAxis, 1
+	case 'L':
+		m.Axis, m.Amount = HorizontalAxis, -1
+	case 'D':
+		m.Axis, m.Amount = VerticalAxis, 1
+	case 'U':
+		m.Axis, m.Amount = VerticalAxis, -1
+	default:
+		return nil, fmt.Errorf("invalid direction %q in move %q, expected R, L, D or U", input[0], input)
+	}
+
+	index, err := strconv.Atoi(input[1:])
+	if err != nil || index < 1 {
+		return nil, fmt.Errorf("invalid slice in move %q, expected a number from 1", input)
+	}
+	m.Index = index - 1
+
+	// indices are 1-based in standard notation, so they're checked here to report them as they were written.
+	if n := board.SliceCount(m.Axis); m.Index >= n {
+		slices := "rows"
+		if m.Axis == VerticalAxis {
+			slices = "columns"
+		}
+		return nil, fmt.Errorf("slice %d of move %q is out of bounds, the board has %d %s", index, input, n, slices)
+	}
+
+	return &m, nil
+}
+
+// ParseEnglishMove parses a move described in plain English like Move.Describe does, such as "shift row 1 left by 2".
+func ParseEnglishMove(input string, board *Board) (*Move, error) {
+	words := strings.Fields(strings.ToLower(input))
+	invalid := fmt.Errorf("invalid move %q, expected a description like \"shift row 1 left by 2\"", input)
+
+	var m Move
+	switch {
+	case len(words) == 6 && words[0] == "shift" && words[4] == "by":
+		amount, err := strconv.Atoi(words[5])
+		if err != nil || amount < 0 {
+			return nil, invalid
+		}
+		m.Amount = amount
+	case len(words) == 6 && words[0] == "leave" && words[3] == "as" && words[4] == "it" && words[5] == "is":
+	default:
+		return nil, invalid
+	}
+
+	switch words[1] {
+	case "row":
+		m.Axis = HorizontalAxis
+	case "column":
+		m.Axis = VerticalAxis
+	default:
+		return nil, invalid
+	}
+
+	index, err := strconv.Atoi(words[2])
+	if err != nil || index < 0 {
+		return nil, invalid
+	}
+	m.Index = index
+
+	if words[0] == "shift" {
+		switch {
+		case m.Axis == HorizontalAxis && words[3] == "left", m.Axis == VerticalAxis && words[3] == "up":
+			m.Amount = -m.Amount
+		case m.Axis == HorizontalAxis && words[3] == "right", m.Axis == VerticalAxis && words[3] == "down":
+		default:
+			return nil, fmt.Errorf("invalid direction %q for a %s in move %q", words[3], words[1], input)
+		}
+	}
+
+	if err := board.ValidateMove(&m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// ParseMoves creates a sequence of parsed Moves from an input string of moves in Programmer's Notation separated by spaces or commas.
+func ParseMoves(input string, board *Board) ([]Move, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+
+	seq := make([]Move, 0, len(fields))
+	for _, f := range fields {
+		m, err := ParseMove(f, board)
+		if err != nil {
+			return nil, err
+		}
+
+		seq = append(seq, *m)
+	}
+
+	return seq, nil
+}
+
+// ValidateMove returns an error if the move can't be made on the board because of its axis or index.
+func (b *Board) ValidateMove(m *Move) error {
+	if m.Axis != HorizontalAxis && m.Axis != VerticalAxis {
+		return fmt.Errorf("invalid axis %d", int(m.Axis))
+	}
+	if n := b.SliceCount(m.Axis); m.Index < 0 || m.Index >= n {
+		slices := "rows"
+		if m.Axis == VerticalAxis {
+			slices = "columns"
+		}
+		return fmt.Errorf("index %d is out of bounds, the board has %d %s", m.Index, n, slices)
+	}
+
+	return nil
+}
+
+// ApplyMoves makes the moves of `seq` in order, stopping at the first one that can't be made on the board.
+// Returns how many of the moves were made, along with why the next one couldn't be.
+func (b *Board) ApplyMoves(seq []Move) (applied int, err error) {
+	for i := range seq {
+		if err := b.ValidateMove(&seq[i]); err != nil {
+			return i, fmt.Errorf("move %d (%s): %s", i+1, seq[i], err)
+		}
+
+		b.MakeMove(&seq[i])
+	}
+
+	return len(seq), nil
+}
+
+// ApplyMovesAtomic makes the moves of `seq` like ApplyMoves, but checks all of them first, so that either every move is made or none is.
+func (b *Board) ApplyMovesAtomic(seq []Move) (applied int, err error) {
+	for i := range seq {
+		if err := b.ValidateMove(&seq[i]); err != nil {
+			return 0, fmt.Errorf("move %d (%s): %s", i+1, seq[i], err)
+		}
+	}
+
+	return b.ApplyMoves(seq)
+}
+
+// ApplyMovesUnchecked makes the moves of `seq` like ApplyMoves, without validating them first, returning how many single shifts they took.
+// It doesn't allocate, for the hot loops of the solvers, but a move outside of the board panics, so it is only for moves known to be valid.
+func (b *Board) ApplyMovesUnchecked(seq []Move) int {
+	var n int
+	for i := range seq {
+		n += b.MakeMove(&seq[i])
+	}
+
+	return n
+}
+
+// SimplifyMoves returns a sequence with the same effect as `seq` on a board with the same dimensions as `b`, with redundant moves removed.
+// Moves on slices of the same axis don't affect each other, so every run of moves along one axis is reduced to a single normalized move per slice,
+// ordered by index, with the ones that cancel out dropped. This is repeated until nothing changes, as dropping moves can join runs.
+func SimplifyMoves(seq []Move, b *Board) []Move {
+	for {
+		out := make([]Move, 0, len(seq))
+		for start := 0; start < len(seq); {
+			end := start
+			for end < len(seq) && seq[end].Axis == seq[start].Axis {
+				end++
+			}
+
+			amounts := make([]int, b.SliceCount(seq[start].Axis))
+			for _, m := range seq[start:end] {
+				amounts[m.Index] += m.Amount
+			}
+			for i, amount := range amounts {
+				if m := b.NormalizeMove(Move{Axis: seq[start].Axis, Index: i, Amount: amount}); m.Amount != 0 {
+					out = append(out, m)
+				}
+			}
+
+			start = end
+		}
+
+		if len(out) == len(seq) {
+			return out
+		}
+		seq = out
+	}
+}
+
+// IsIdentity returns true if making all moves of `seq` leaves every tile of a board with the same dimensions as `b` where it was.
+func IsIdentity(seq []Move, b *Board) bool {
+	return Order(seq, b) == 1
+}
+
+// Order returns how many times `seq` must be repeated on a board with the same dimensions as `b` for every tile to end up where it was.
+func Order(seq []Move, b *Board) int {
+	p, _ := NewBoard(b.Width(), b.Height())
+	for i := range seq {
+		p.MakeMove(&seq[i])
+	}
+
+	// the order of a permutation is the least common multiple of the length of its cycles.
+	tiles := p.Tiles()
+	visited := make([]bool, len(tiles))
+
+	order := 1
+	for i := range tiles {
+		var l int
+		for j := i; !visited[j]; j = tiles[j] - 1 {
+			visited[j] = true
+			l++
+		}
+
+		if l > 1 {
+			order = order / gcd(order, l) * l
+		}
+	}
+
+	return order
+}
+
+// gcd returns the greatest common divisor of `a` and `b`.
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+// ParseMoveStrict parses a move exactly as the grammar of Programmer's Notation describes it, with no signs, spaces or other characters the grammar doesn't allow.
+// Syntax errors name the production that failed to match. Once the syntax is valid, the move is checked against the board like ParseMove does.
+func ParseMoveStrict(input string, board *Board) (*Move, error) {
+	i := 0
+	expect := func(production string) error {
+		if i == len(input) {
+			return fmt.Errorf("expected %s at the end of move %q", production, input)
+		}
+		return fmt.Errorf("expected %s at position %d of move %q, got %q", production, i, input, input[i])
+	}
+	digits := func() int {
+		start := i
+		for i < len(input) && input[i] >= '0' && input[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+
+	// amount = [ backwards-indicator ] number
+	if i < len(input) && input[i] == '-' {
+		i++
+	}
+	if digits() == 0 {
+		return nil, expect("number of amount")
+	}
+
+	// axis = horizontal-axis | vertical-axis
+	if i == len(input) || strings.IndexByte("RrCc", input[i]) == -1 {
+		return nil, expect("axis")
+	}
+	i++
+
+	// index = number
+	if digits() == 0 {
+		return nil, expect("number of index")
+	}
+
+	// [ reverse-index-indicator ]
+	if i < len(input) && input[i] == '\'' {
+		i++
+	}
+	if i != len(input) {
+		return nil, expect("reverse-index-indicator or the end of the move")
+	}
+
+	return ParseMove(input, board)
+}
+
+// SuggestMove returns the valid move whose notation is the closest to `input` by edit distance, for telling the player what they probably meant.
+// Returns false if no move is within 2 edits of it.
+func SuggestMove(input string, board *Board) (string, bool) {
+	input = strings.ToLower(strings.TrimSpace(input))
+
+	best, bestDist := "", 3
+	for _, a := range []Axis{HorizontalAxis, VerticalAxis} {
+		l := board.SliceLength(a)
+
+		for i := 0; i < board.SliceCount(a); i++ {
+			// smaller and positive amounts come first, so they win ties.
+			for k := 1; k < l; k++ {
+				for _, amnt := range []int{k, -k} {
+					s := Move{Axis: a, Index: i, Amount: amnt}.String()
+					if d := editDistance(input, strings.ToLower(s)); d < bestDist {
+						best, bestDist = s, d
+					}
+				}
+			}
+		}
+	}
+
+	return best, best != ""
+}
+
+// editDistance returns the Levenshtein distance between two strings: how many single character insertions, deletions and substitutions turn one into the other.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = prev[j-1] + cost
+			if d := prev[j] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if d := cur[j-1] + 1; d < cur[j] {
+				cur[j] = d
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "normalize",
+		Summary: "simplify a sequence of moves and print the state it leads to",
+		Run:     runNormalize,
+	})
+}
+
+// StateHash returns a short hash of the board's state code, for checking at a glance that two boards are the same.
+func StateHash(b *Board) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(EncodeState(b))))
+}
+
+// runNormalize runs the normalize command.
+func runNormalize(args []string) error {
+	fs := flag.NewFlagSet("normalize", flag.ContinueOnError)
+	size := fs.String("size", "5x5", "size of the board the moves are made on")
+	var from, to MoveFormat
+	fs.Func("from", "notation of the moves: programmer, standard or english", func(s string) (err error) {
+		from.Notation, err = ParseNotation(s)
+		return err
+	})
+	fs.Func("notation", "notation to write the simplified moves in: programmer, standard or english", func(s string) (err error) {
+		to.Notation, err = ParseNotation(s)
+		return err
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: normalize [-size 5x5] [-from NOTATION] [-notation NOTATION] MOVES")
+		fmt.Fprintln(fs.Output(), "Simplifies a sequence of moves, like a scramble, by merging moves on the same slice, dropping the ones that cancel out and making every amount the shortest.")
+		fmt.Fprintln(fs.Output(), "Prints the simplified moves, the state code of the board they lead to from solved and a hash of it.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected moves to simplify")
+	}
+
+	w, h, err := ParseTwoDimensions(*size)
+	if err != nil {
+		return err
+	}
+	b, err := NewBoard(w, h)
+	if err != nil {
+		return err
+	}
+
+	seq, err := ParseMovesIn(from.Notation, strings.Join(fs.Args(), " "), &b)
+	if err != nil {
+		return err
+	}
+
+	simple := SimplifyMoves(seq, &b)
+	if _, err := b.ApplyMoves(simple); err != nil {
+		return err
+	}
+
+	fmt.Printf("Moves: %s\n", to.FormatMoves(simple, &b))
+	fmt.Printf("Length: %d single shifts, from %d\n", MovesLength(simple), MovesLength(seq))
+	fmt.Printf("State: %s\n", EncodeState(&b))
+	fmt.Printf("Hash: %s\n", StateHash(&b))
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// Notifier sends notifications about the game, such as the board becoming solved.
+type Notifier struct {
+	// Bell rings the terminal bell.
+	Bell bool
+	// Desktop shows a desktop notification, where available.
+	Desktop bool
+	// Out is where the bell is rung, the standard output if nil.
+	Out io.Writer
+}
+
+// Notify sends a notification with the given title and message through every enabled channel.
+// Returns an error if the desktop notification could not be shown.
+func (n Notifier) Notify(title, message string) error {
+	if n.Bell {
+		out := n.Out
+		if out == nil {
+			out = os.Stdout
+		}
+		fmt.Fprint(out, "\a")
+	}
+
+	if n.Desktop {
+		return desktopNotify(title, message)
+	}
+
+	return nil
+}
+
+// desktopNotify shows a desktop notification using notify-send on Linux and BSDs and osascript on macOS.
+func desktopNotify(title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title %q", message, title))
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("notify-send", title, message)
+	default:
+		return fmt.Errorf("desktop notifications are not available on %s", runtime.GOOS)
+	}
+
+	return cmd.Run()
+}
+
+// notifyMove sends the notifications due after a move: the board becoming solved, or the time limit being reached.
+func (g *Game) notifyMove() error {
+	if g.Board.IsSolved() {
+		return g.Notifier.Notify("Loopover", fmt.Sprintf("Solved in %d moves and %.2fs", g.Moves, g.Elapsed().Seconds()))
+	}
+
+	if g.TimeLimit > 0 && !g.limitNotified && g.Elapsed() >= g.TimeLimit {
+		g.limitNotified = true
+		return g.Notifier.Notify("Loopover", fmt.Sprintf("Time limit of %s reached", g.TimeLimit.Round(time.Second)))
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+// Observer is told about what happens on a game's board as moves are made, undone and redone.
+// Observers are called with the game locked, from whichever goroutine made the move.
+type Observer interface {
+	// SliceSolved is called when a row (HorizontalAxis) or column (VerticalAxis) becomes solved, with all of its tiles in place.
+	SliceSolved(g *Game, a Axis, index int)
+}
+
+// ObserverFunc is a function that observes solved slices.
+type ObserverFunc func(g *Game, a Axis, index int)
+
+func (f ObserverFunc) SliceSolved(g *Game, a Axis, index int) {
+	f(g, a, index)
+}
+
+// isSliceSolved returns true if all tiles of the slice at `index` along the axis are in place.
+func (b *Board) isSliceSolved(a Axis, index int) bool {
+	if a == HorizontalAxis {
+		return b.isRowSolved(index)
+	}
+
+	return b.isColumnSolved(index)
+}
+
+// rememberSolvedSlices records which rows and columns are solved without telling the observers, for when the board is replaced,
+// so that slices solved by a scramble or a restored checkpoint aren't reported.
+func (g *Game) rememberSolvedSlices() {
+	b := &g.Board
+	g.solvedSlices = [2][]bool{make([]bool, b.Height()), make([]bool, b.Width())}
+	for a := range g.solvedSlices {
+		for i := range g.solvedSlices[a] {
+			g.solvedSlices[a][i] = b.isSliceSolved(Axis(a), i)
+		}
+	}
+}
+
+// observeSlices tells the observers about every row and column that became solved since the last move.
+func (g *Game) observeSlices() {
+	b := &g.Board
+	for a := range g.solvedSlices {
+		for i := range g.solvedSlices[a] {
+			solved := b.isSliceSolved(Axis(a), i)
+			if solved && !g.solvedSlices[a][i] {
+				for _, o := range g.Observers {
+					o.SliceSolved(g, Axis(a), i)
+				}
+			}
+			g.solvedSlices[a][i] = solved
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "ocr",
+		Summary: "read a board from a cropped screenshot of its grid (experimental)",
+		Run:     runOCR,
+	})
+}
+
+// ocrSamples is how many samples across and down every tile is reduced to for matching.
+const ocrSamples = 16
+
+// ocrInset is the fraction of a tile's width and height left out on every side, to skip the borders between tiles.
+const ocrInset = 0.1
+
+// TileSample is a tile of a screenshot reduced to ocrSamples*ocrSamples brightness samples, row by row,
+// normalized to a mean of 0 and a standard deviation of 1 so that only the shape of the label matters, not the colors of the tile.
+type TileSample []float64
+
+// sampleTile reduces the part of the image inside `r` to a TileSample, averaging the brightness of the pixels of every sample.
+func sampleTile(img image.Image, r image.Rectangle) TileSample {
+	dx, dy := int(float64(r.Dx())*ocrInset), int(float64(r.Dy())*ocrInset)
+	r = image.Rect(r.Min.X+dx, r.Min.Y+dy, r.Max.X-dx, r.Max.Y-dy)
+
+	s := make(TileSample, ocrSamples*ocrSamples)
+	for sy := 0; sy < ocrSamples; sy++ {
+		for sx := 0; sx < ocrSamples; sx++ {
+			x1, x2 := r.Min.X+sx*r.Dx()/ocrSamples, r.Min.X+(sx+1)*r.Dx()/ocrSamples
+			y1, y2 := r.Min.Y+sy*r.Dy()/ocrSamples, r.Min.Y+(sy+1)*r.Dy()/ocrSamples
+
+			var sum float64
+			var n int
+			for y := y1; y < y2 || n == 0 && y == y1; y++ {
+				for x := x1; x < x2 || n == 0 && x == x1; x++ {
+					cr, cg, cb, _ := img.At(x, y).RGBA()
+					sum += 0.299*float64(cr) + 0.587*float64(cg) + 0.114*float64(cb)
+					n++
+				}
+			}
+			s[sy*ocrSamples+sx] = sum / float64(n)
+		}
+	}
+
+	var mean, variance float64
+	for _, v := range s {
+		mean += v
+	}
+	mean /= float64(len(s))
+	for _, v := range s {
+		variance += (v - mean) * (v - mean)
+	}
+	std := math.Sqrt(variance / float64(len(s)))
+
+	for i := range s {
+		s[i] -= mean
+		if std > 0 {
+			s[i] /= std
+		}
+	}
+
+	return s
+}
+
+// distance returns how different two samples are, as the mean of the squared differences of their samples.
+func (s TileSample) distance(other TileSample) float64 {
+	var d float64
+	for i := range s {
+		d += (s[i] - other[i]) * (s[i] - other[i])
+	}
+
+	return d / float64(len(s))
+}
+
+// sampleGrid splits the image into width*height tiles of the same size and samples every one, row by row.
+func sampleGrid(img image.Image, width, height int) []TileSample {
+	r := img.Bounds()
+
+	samples := make([]TileSample, 0, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			samples = append(samples, sampleTile(img, image.Rect(
+				r.Min.X+x*r.Dx()/width, r.Min.Y+y*r.Dy()/height,
+				r.Min.X+(x+1)*r.Dx()/width, r.Min.Y+(y+1)*r.Dy()/height,
+			)))
+		}
+	}
+
+	return samples
+}
+
+// OCRTemplates holds what every tile of a board size looks like, indexed by tile number minus 1, to recognize tiles in screenshots.
+type OCRTemplates struct {
+	Width, Height int
+	Tiles         []TileSample
+}
+
+// DiagramTemplates returns the templates of the tiles of the diagrams drawn by generate-image.
+func DiagramTemplates(width, height int) (*OCRTemplates, error) {
+	b, err := NewBoard(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return CalibrateOCR(DefaultDiagramStyle.Image(&b, nil), width, height), nil
+}
+
+// CalibrateOCR returns the templates of the tiles of a screenshot of a solved board, to read screenshots of other boards from the same source.
+func CalibrateOCR(solved image.Image, width, height int) *OCRTemplates {
+	return &OCRTemplates{Width: width, Height: height, Tiles: sampleGrid(solved, width, height)}
+}
+
+// ocrTemplatesPath returns the path the calibrated templates of a board size are saved at.
+func ocrTemplatesPath(width, height int) (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "ocr", fmt.Sprintf("%dx%d.json", width, height)), nil
+}
+
+// LoadOCRTemplates reads the calibrated templates of a board size, falling back to the ones of generate-image diagrams if the size wasn't calibrated.
+func LoadOCRTemplates(width, height int) (*OCRTemplates, error) {
+	path, err := ocrTemplatesPath(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DiagramTemplates(width, height)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var t OCRTemplates
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if t.Width != width || t.Height != height || len(t.Tiles) != width*height {
+		return nil, fmt.Errorf("%s: templates are not of a %dx%d board", path, width, height)
+	}
+
+	return &t, nil
+}
+
+// Save writes the templates to the data directory, to be used for their board size from then on.
+func (t *OCRTemplates) Save() error {
+	path, err := ocrTemplatesPath(t.Width, t.Height)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// OCRResult is a board read from a screenshot.
+type OCRResult struct {
+	Board Board
+	// Worst is the distance of the tile that matched its template the least, from 0 for a perfect match to about 4 for no resemblance.
+	Worst float64
+}
+
+// ocrUnsureDistance is the distance past which a tile is unlikely to have been read right.
+const ocrUnsureDistance = 0.5
+
+// Unsure returns true if some tile matched its template so poorly that the board was likely read wrong.
+func (r *OCRResult) Unsure() bool {
+	return r.Worst > ocrUnsureDistance
+}
+
+// Read reads the board in a screenshot cropped to its grid, matching every tile with the template it looks the most like.
+// Tiles are matched best first, and every template is only matched once, so that the result is always a valid board.
+func (t *OCRTemplates) Read(img image.Image) *OCRResult {
+	samples := sampleGrid(img, t.Width, t.Height)
+
+	type match struct {
+		tile, template int
+		distance       float64
+	}
+	matches := make([]match, 0, len(samples)*len(t.Tiles))
+	for i, s := range samples {
+		for j, tmpl := range t.Tiles {
+			matches = append(matches, match{i, j, s.distance(tmpl)})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].distance < matches[j].distance })
+
+	b, _ := NewBoard(t.Width, t.Height)
+	res := &OCRResult{Board: b}
+	tileDone, templateDone := make([]bool, len(samples)), make([]bool, len(t.Tiles))
+	for _, m := range matches {
+		if tileDone[m.tile] || templateDone[m.template] {
+			continue
+		}
+		tileDone[m.tile], templateDone[m.template] = true, true
+
+		b[m.tile%t.Width][m.tile/t.Width] = m.template + 1
+		if m.distance > res.Worst {
+			res.Worst = m.distance
+		}
+	}
+
+	return res
+}
+
+// decodeImageFile decodes a PNG or JPEG file.
+func decodeImageFile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return img, nil
+}
+
+// IsImageFile returns true if the file is a PNG or JPEG image, by its extension.
+func IsImageFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	default:
+		return false
+	}
+}
+
+// ImportScreenshot reads a width*height board from a screenshot cropped to its grid, with the templates of its size.
+func ImportScreenshot(path string, width, height int) (*OCRResult, error) {
+	img, err := decodeImageFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := LoadOCRTemplates(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.Read(img), nil
+}
+
+// runOCR runs the ocr command.
+func runOCR(args []string) error {
+	fs := flag.NewFlagSet("ocr", flag.ContinueOnError)
+	size := fs.String("size", "5x5", "board size of the screenshot")
+	calibrate := fs.Bool("calibrate", false, "learn what the tiles look like from a screenshot of a solved board, to read other screenshots from the same source")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ocr [-size 5x5] [-calibrate] IMAGE")
+		fmt.Fprintln(fs.Output(), "Reads a board from a PNG or JPEG screenshot cropped to its grid, printing its state code. Experimental.")
+		fmt.Fprintln(fs.Output(), "Tiles are recognized by comparing them with the tiles of a calibration screenshot of the solved board, or of generate-image diagrams until a size is calibrated.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected an image file")
+	}
+	w, h, err := ParseTwoDimensions(*size)
+	if err != nil {
+		return err
+	}
+	if _, err := NewBoard(w, h); err != nil {
+		return err
+	}
+
+	if *calibrate {
+		img, err := decodeImageFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if err := CalibrateOCR(img, w, h).Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Calibrated %dx%d screenshots\n", w, h)
+		return nil
+	}
+
+	res, err := ImportScreenshot(fs.Arg(0), w, h)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(EncodeState(&res.Board))
+	if res.Unsure() {
+		fmt.Fprintln(os.Stderr, "Some tiles barely matched, check the board against the screenshot:")
+		fmt.Fprintln(os.Stderr, SprintBoard(&res.Board))
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// MaxPackedTiles is the most tiles a board can have to be packed into a PackedBoard.
+const MaxPackedTiles = 16
+
+// PackedBoard holds the tiles of a small board in a single integer, 4 bits per tile row by row from the top left, every tile stored as its value minus one.
+// Packed boards of the same size are hashed and compared as integers, and moves are made on them with a few shifts and masks,
+// which makes them cheap to keep by the million in searches of the whole state space.
+type PackedBoard uint64
+
+// PackedLayout packs and unpacks boards of one size, and makes moves on them.
+type PackedLayout struct {
+	Width, Height int
+
+	// rowMask covers the tiles of the top row, and columnMask the ones of the leftmost column.
+	rowMask, columnMask PackedBoard
+	solved              PackedBoard
+}
+
+// NewPackedLayout returns the layout of packed width*height boards, which can have up to MaxPackedTiles tiles.
+func NewPackedLayout(width, height int) (*PackedLayout, error) {
+	if width <= 1 || height <= 1 {
+		return nil, fmt.Errorf("board dimensions must be greater than 1")
+	}
+	if width*height > MaxPackedTiles {
+		return nil, fmt.Errorf("%dx%d boards have more than %d tiles, too many to pack", width, height, MaxPackedTiles)
+	}
+
+	l := &PackedLayout{Width: width, Height: height}
+	l.rowMask = 1<<(4*uint(width)) - 1
+	for y := 0; y < height; y++ {
+		l.columnMask |= 0xf << (4 * uint(y*width))
+	}
+	for i := 0; i < width*height; i++ {
+		l.solved = l.solved.withTile(i, i+1)
+	}
+
+	return l, nil
+}
+
+// Solved returns the packed solved board.
+func (l *PackedLayout) Solved() PackedBoard {
+	return l.solved
+}
+
+// Pack packs a board of the layout's size.
+func (l *PackedLayout) Pack(b *Board) PackedBoard {
+	var p PackedBoard
+	for x := range *b {
+		for y, t := range (*b)[x] {
+			p = p.withTile(y*l.Width+x, t)
+		}
+	}
+
+	return p
+}
+
+// Unpack returns the board packed into `p`.
+func (l *PackedLayout) Unpack(p PackedBoard) Board {
+	b, _ := NewBoard(l.Width, l.Height)
+	for x := range b {
+		for y := range b[x] {
+			b[x][y] = p.Tile(y*l.Width + x)
+		}
+	}
+
+	return b
+}
+
+// Tile returns the value of the ith tile, counting row by row from the top left.
+func (p PackedBoard) Tile(i int) int {
+	return int(p>>(4*uint(i))&0xf) + 1
+}
+
+// withTile returns `p` with the ith tile set to `t`.
+func (p PackedBoard) withTile(i, t int) PackedBoard {
+	shift := 4 * uint(i)
+	return p&^(0xf<<shift) | PackedBoard(t-1)<<shift
+}
+
+// Move returns `p` with a move made, like MakeMove does on a Board.
+func (l *PackedLayout) Move(p PackedBoard, m *Move) PackedBoard {
+	// the slice is rotated in place: shifting it forward pushes its last tiles out of it, and shifting it back by the rest of its length brings them around to its start.
+	if m.Axis == HorizontalAxis {
+		k := rotation(m.Amount, l.Width)
+		mask := l.rowMask << (4 * uint(m.Index*l.Width))
+		row := p & mask
+
+		return p&^mask | (row<<(4*k)|row>>(4*(uint(l.Width)-k)))&mask
+	}
+
+	k := rotation(m.Amount, l.Height)
+	mask := l.columnMask << (4 * uint(m.Index))
+	column := p & mask
+	w := uint(l.Width)
+
+	return p&^mask | (column<<(4*w*k)|column>>(4*w*(uint(l.Height)-k)))&mask
+}
+
+// rotation returns how many tiles forward shifting a slice of length `n` by `amount` moves its tiles, between 0 and n-1.
+func rotation(amount, n int) uint {
+	amount %= n
+	if amount < 0 {
+		amount += n
+	}
+
+	return uint(amount)
+}
+
+// IsSolved returns true if `p` is the packed solved board.
+func (l *PackedLayout) IsSolved(p PackedBoard) bool {
+	return p == l.solved
+}
+
+// Rank returns the position of the packed board's arrangement in the lexicographic order of all arrangements, like rankTiles.
+func (l *PackedLayout) Rank(p PackedBoard) int {
+	n := l.Width * l.Height
+
+	var rank int
+	for i := 0; i < n; i++ {
+		t, smaller := p.Tile(i), 0
+		for j := i + 1; j < n; j++ {
+			if p.Tile(j) < t {
+				smaller++
+			}
+		}
+
+		rank = rank*(n-i) + smaller
+	}
+
+	return rank
+}
+
+// Unrank returns the packed board whose arrangement has the given rank, the inverse of Rank.
+func (l *PackedLayout) Unrank(rank int) PackedBoard {
+	n := l.Width * l.Height
+
+	var digits [MaxPackedTiles]int
+	for i := n - 1; i >= 0; i-- {
+		digits[i] = rank % (n - i)
+		rank /= n - i
+	}
+
+	// every digit picks the tile among the ones that are left, which are the unset bits of `used`.
+	var p PackedBoard
+	var used uint
+	for i := 0; i < n; i++ {
+		t := 0
+		for d := digits[i]; ; t++ {
+			if used&(1<<uint(t)) != 0 {
+				continue
+			}
+			if d == 0 {
+				break
+			}
+			d--
+		}
+
+		used |= 1 << uint(t)
+		p = p.withTile(i, t+1)
+	}
+
+	return p
+}
+
+// Bitset is a set of the integers from 0 to its length in bits, taking one bit for each.
+// Indexed by rank, it keeps track of every arrangement of a small board in far less memory than a map.
+type Bitset []uint64
+
+// NewBitset returns an empty set of the integers from 0 to n-1.
+func NewBitset(n int) Bitset {
+	return make(Bitset, (n+63)/64)
+}
+
+// Has returns true if `i` is in the set.
+func (s Bitset) Has(i int) bool {
+	return s[i/64]&(1<<uint(i%64)) != 0
+}
+
+// Add adds `i` to the set, returning false if it was already in it.
+func (s Bitset) Add(i int) bool {
+	bit := uint64(1) << uint(i%64)
+	if s[i/64]&bit != 0 {
+		return false
+	}
+
+	s[i/64] |= bit
+	return true
+}
+
+// AtomicAdd adds `i` to the set like Add, but can be called from several goroutines at once.
+func (s Bitset) AtomicAdd(i int) {
+	bit := uint64(1) << uint(i%64)
+	for {
+		old := atomic.LoadUint64(&s[i/64])
+		if old&bit != 0 || atomic.CompareAndSwapUint64(&s[i/64], old, old|bit) {
+			return
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import "fmt"
+
+// MaxHumanTPS is the most single shifts per second a person keeps up over a whole solve. Faster solves are flagged.
+const MaxHumanTPS = 20
+
+// evenPace is how little the pace of a solve can vary between its phases, relative to its mean, before the solve is flagged as made at a machine's even pace.
+const evenPace = 0.02
+
+// Flags returns why the solve looks like it wasn't played by a person, to review it before trusting it on a leaderboard, or nothing if it looks plausible.
+// Solves imported from other machines can't be trusted the way the ones played here can, but these are only heuristics: a flag is a reason to look, not proof.
+func (r *SolveRecord) Flags() []string {
+	var flags []string
+	if r.Seconds <= 0 && r.Moves > 0 {
+		flags = append(flags, "impossible timing: no time for the moves")
+	} else if tps := r.TPS(); tps > MaxHumanTPS {
+		flags = append(flags, fmt.Sprintf("inhuman TPS of %.1f", tps))
+	}
+
+	var total float64
+	for _, s := range r.Splits {
+		total += s.Seconds
+		if s.Seconds < 0 {
+			flags = append(flags, "impossible timing: a negative split")
+			break
+		}
+	}
+	if total > r.Seconds+0.001 {
+		flags = append(flags, fmt.Sprintf("impossible timing: the splits add up to %.3fs, more than the time", total))
+	}
+
+	b, err := DecodeState(r.Scramble)
+	if err != nil {
+		return append(flags, "invalid scramble")
+	}
+	seq, err := ParseMoves(r.Solution, &b)
+	if err != nil {
+		return append(flags, "unreadable solution")
+	}
+
+	end := b.Clone()
+	if _, err := end.ApplyMoves(seq); err != nil {
+		return append(flags, "invalid solution")
+	}
+	if _, ok := r.Result(); !ok {
+		return flags
+	}
+	if !end.IsSolved() {
+		return append(flags, "the solution doesn't solve the scramble")
+	}
+
+	// short solutions are often the engine's by chance.
+	var f MoveFormat
+	if engine, err := SolveHuman(&b); err == nil && MovesLength(seq) >= 10 && f.FormatMoves(SimplifyMoves(engine.Moves, &b), &b) == f.FormatMoves(SimplifyMoves(seq, &b), &b) {
+		flags = append(flags, "the solution is the engine's")
+	}
+
+	// people slow down and speed up between phases, as some take more thinking than others.
+	phases := SplitPhases(&Replay{Scramble: b, Moves: seq})
+	if len(phases) >= 3 && len(phases) == len(r.Splits) {
+		paces := make([]float64, len(phases))
+		for i, p := range phases {
+			if r.Splits[i].Seconds <= 0 {
+				return flags
+			}
+			paces[i] = float64(MovesLength(seq[p.Start:p.End])) / r.Splits[i].Seconds
+		}
+
+		d := Summarize(paces)
+		if d.Mean > 0 && d.Std/d.Mean < evenPace {
+			flags = append(flags, fmt.Sprintf("an even pace of %.2f TPS in every phase", d.Mean))
+		}
+	}
+
+	return flags
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+/* Plugin protocol
+Plugins are executables in the plugins directory of the data directory. Every one is started along with the game,
+and exchanges JSON-RPC 2.0 messages with it over its standard input and output, one JSON object per line.
+Whatever a plugin writes to its standard error goes to the game's.
+
+describe            {}                                -> {"name": string, "commands": [feature], "solvers": [feature], "renderers": [feature]}
+command             {"name", "arg", "state"}          -> {"output": string, "state": string, "moves": string}
+solve               {"name", "state"}                 -> {"moves": string}
+render              {"name", "state"}                 -> {"output": string}
+
+feature = {"name": string, "summary": string}
+
+describe is called first, to learn what the plugin adds. command runs one of its REPL commands with the rest of the line as "arg",
+and can print "output", replace the board with the one of "state" and make the moves of "moves", all optional.
+States are state codes and moves are in Programmer's Notation, separated by spaces.
+*/
+
+// pluginTimeout is how long a plugin can take to answer a call before it's given up on.
+const pluginTimeout = 30 * time.Second
+
+// PluginFeature is a command, solver or renderer a plugin adds.
+type PluginFeature struct {
+	Name    string `json:"name"`
+	Summary string `json:"summary"`
+}
+
+// Plugin is a running plugin executable.
+type Plugin struct {
+	Path      string          `json:"-"`
+	Name      string          `json:"name"`
+	Commands  []PluginFeature `json:"commands"`
+	Solvers   []PluginFeature `json:"solvers"`
+	Renderers []PluginFeature `json:"renderers"`
+
+	cmd    *exec.Cmd
+	in     io.WriteCloser
+	lines  chan []byte
+	nextID int
+}
+
+// pluginRequest and pluginResponse are JSON-RPC 2.0 messages.
+type pluginRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type pluginResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// PluginsDir returns the directory of the data directory where plugins are installed.
+func PluginsDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "plugins"), nil
+}
+
+// StartPlugin starts a plugin executable and asks it what it adds.
+func StartPlugin(path string) (*Plugin, error) {
+	p := &Plugin{Path: path, cmd: exec.Command(path), lines: make(chan []byte)}
+	p.cmd.Stderr = os.Stderr
+
+	in, err := p.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	p.in = in
+
+	if err := p.cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// answers are read on their own goroutine so that calls can time out.
+	go func() {
+		defer close(p.lines)
+
+		s := bufio.NewScanner(out)
+		s.Buffer(nil, 1<<20)
+		for s.Scan() {
+			p.lines <- append([]byte(nil), s.Bytes()...)
+		}
+	}()
+
+	if err := p.Call("describe", struct{}{}, p); err != nil {
+		p.Close()
+		return nil, err
+	}
+	if p.Name == "" {
+		p.Name = filepath.Base(path)
+	}
+
+	return p, nil
+}
+
+// Call calls a method of the plugin, decoding its result into `result`.
+func (p *Plugin) Call(method string, params, result interface{}) error {
+	p.nextID++
+	data, err := json.Marshal(pluginRequest{JSONRPC: "2.0", ID: p.nextID, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.in.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("plugin %s: %s", filepath.Base(p.Path), err)
+	}
+
+	timeout := time.After(pluginTimeout)
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return fmt.Errorf("plugin %s exited", filepath.Base(p.Path))
+			}
+
+			var resp pluginResponse
+			if err := json.Unmarshal(line, &resp); err != nil {
+				return fmt.Errorf("plugin %s: invalid answer: %s", filepath.Base(p.Path), err)
+			}
+			if resp.ID != p.nextID {
+				// an answer to a call that timed out.
+				continue
+			}
+
+			if resp.Error != nil {
+				return fmt.Errorf("plugin %s: %s", filepath.Base(p.Path), resp.Error.Message)
+			}
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("plugin %s: invalid result: %s", filepath.Base(p.Path), err)
+			}
+
+			return nil
+		case <-timeout:
+			return fmt.Errorf("plugin %s didn't answer in %s", filepath.Base(p.Path), pluginTimeout)
+		}
+	}
+}
+
+// Close closes the plugin's standard input, which tells it to exit, and waits a second for it to before killing it.
+func (p *Plugin) Close() error {
+	p.in.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- p.cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		p.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+// Plugins is the set of running plugins.
+type Plugins []*Plugin
+
+// StartPlugins starts every executable in `dir`. Plugins that fail to start are left out, along with an error saying why.
+func StartPlugins(dir string) (Plugins, []error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var ps Plugins
+	var errs []error
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+			continue
+		}
+
+		p, err := StartPlugin(filepath.Join(dir, e.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ps = append(ps, p)
+	}
+
+	return ps, errs
+}
+
+// find returns the plugin adding the feature with the given name, looking through the features `of` returns for every plugin.
+func (ps Plugins) find(name string, of func(p *Plugin) []PluginFeature) (*Plugin, bool) {
+	for _, p := range ps {
+		for _, f := range of(p) {
+			if strings.EqualFold(f.Name, name) {
+				return p, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// Command returns the plugin adding the REPL command with the given name.
+func (ps Plugins) Command(name string) (*Plugin, bool) {
+	return ps.find(name, func(p *Plugin) []PluginFeature { return p.Commands })
+}
+
+// Solver returns the plugin adding the solver with the given name.
+func (ps Plugins) Solver(name string) (*Plugin, bool) {
+	return ps.find(name, func(p *Plugin) []PluginFeature { return p.Solvers })
+}
+
+// Renderer returns the plugin adding the renderer with the given name.
+func (ps Plugins) Renderer(name string) (*Plugin, bool) {
+	return ps.find(name, func(p *Plugin) []PluginFeature { return p.Renderers })
+}
+
+// Close closes every plugin.
+func (ps Plugins) Close() {
+	for _, p := range ps {
+		p.Close()
+	}
+}
+
+// String lists the plugins and what each adds, one per line.
+func (ps Plugins) String() string {
+	var sb strings.Builder
+	for i, p := range ps {
+		if i != 0 {
+			sb.WriteByte('\n')
+		}
+		fmt.Fprintf(&sb, "%s (%s)", p.Name, p.Path)
+
+		for _, k := range []struct {
+			kind     string
+			features []PluginFeature
+		}{{"command", p.Commands}, {"solver", p.Solvers}, {"renderer", p.Renderers}} {
+			features := append([]PluginFeature(nil), k.features...)
+			sort.Slice(features, func(i, j int) bool { return features[i].Name < features[j].Name })
+
+			for _, f := range features {
+				fmt.Fprintf(&sb, "\n  %s %s: %s", k.kind, f.Name, f.Summary)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// PluginCommandResult is what a plugin's REPL command did.
+type PluginCommandResult struct {
+	Output string `json:"output"`
+	State  string `json:"state"`
+	Moves  string `json:"moves"`
+}
+
+// RunCommand runs one of the plugin's REPL commands on the board.
+func (p *Plugin) RunCommand(name, arg string, b *Board) (*PluginCommandResult, error) {
+	var res PluginCommandResult
+	params := map[string]string{"name": name, "arg": arg, "state": EncodeState(b)}
+	if err := p.Call("command", params, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// Solve solves the board with one of the plugin's solvers, checking that its solution does solve it.
+func (p *Plugin) Solve(name string, b *Board) ([]Move, error) {
+	var res struct {
+		Moves string `json:"moves"`
+	}
+	if err := p.Call("solve", map[string]string{"name": name, "state": EncodeState(b)}, &res); err != nil {
+		return nil, err
+	}
+
+	seq, err := ParseMoves(res.Moves, b)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid solution: %s", p.Name, err)
+	}
+
+	c := b.Clone()
+	if _, err := c.ApplyMoves(seq); err != nil || !c.IsSolved() {
+		return nil, fmt.Errorf("plugin %s: solution doesn't solve the board", p.Name)
+	}
+
+	return seq, nil
+}
+
+// Render draws the board with one of the plugin's renderers.
+func (p *Plugin) Render(name string, b *Board) (string, error) {
+	var res struct {
+		Output string `json:"output"`
+	}
+	if err := p.Call("render", map[string]string{"name": name, "state": EncodeState(b)}, &res); err != nil {
+		return "", err
+	}
+
+	return res.Output, nil
+}
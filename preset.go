@@ -0,0 +1,384 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "presets",
+		Summary: "list, export and import presets of game settings",
+		Run:     runPresets,
+	})
+}
+
+// ScrambleKind is how the shuffle command scrambles the board.
+type ScrambleKind int
+
+const (
+	// AskScramble asks whether to fast shuffle or shuffle with random moves every time.
+	AskScramble ScrambleKind = iota
+	// RandomStateScramble picks an arrangement uniformly at random among the solvable ones, like competitions do.
+	RandomStateScramble
+	// FastScramble swaps every tile with a random one, which is quick on big boards.
+	FastScramble
+	// MovesScramble makes random moves, as many as Shuffle makes by default.
+	MovesScramble
+	// FewMovesScramble makes only a few random moves, for boards that are quick to solve.
+	FewMovesScramble
+	// RowsScramble only moves rows, leaving every tile in its column.
+	RowsScramble
+	// CornerScramble picks a random arrangement of the tiles of the bottom right corner, leaving the rest of the board solved, to practice the end of solves.
+	CornerScramble
+)
+
+func (k ScrambleKind) String() string {
+	switch k {
+	case AskScramble:
+		return "ask"
+	case RandomStateScramble:
+		return "random-state"
+	case FastScramble:
+		return "fast"
+	case MovesScramble:
+		return "moves"
+	case FewMovesScramble:
+		return "few-moves"
+	case RowsScramble:
+		return "rows"
+	case CornerScramble:
+		return "corner"
+	default:
+		return fmt.Sprintf("ScrambleKind(%d)", int(k))
+	}
+}
+
+// ParseScrambleKind parses the name of a kind of scramble, as returned by ScrambleKind.String.
+func ParseScrambleKind(s string) (ScrambleKind, error) {
+	for k := AskScramble; k <= CornerScramble; k++ {
+		if strings.EqualFold(s, k.String()) {
+			return k, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown scramble %q, expected ask, random-state, fast, moves, few-moves, rows or corner", s)
+}
+
+// Scramble scrambles the board, returning a message saying how. Boards scrambled with random moves or fast shuffles are scrambled further if they end up too close to solved.
+func (k ScrambleKind) Scramble(b *Board) string {
+	min := MinScrambleDistance(b.Width(), b.Height())
+
+	switch k {
+	case RandomStateScramble:
+		b.uniformShuffle()
+		return "Scrambled board to a random state"
+	case FastScramble:
+		b.FastShuffle()
+		if b.Distance() <= min {
+			return fmt.Sprintf("Fast shuffled board, and scrambled it further with %d iterations", b.ScrambleUntil(min))
+		}
+		return "Fast shuffled board"
+	case FewMovesScramble:
+		k := (b.Width() + b.Height()) / 2
+		b.ScrambleDepth(k)
+		for b.IsSolved() {
+			b.ScrambleDepth(k)
+		}
+		return fmt.Sprintf("Scrambled board with %d random moves", k)
+	case RowsScramble:
+		rows := &Restrictions{Axes: map[Axis]bool{HorizontalAxis: true}}
+		b.Reset()
+		iterations := b.ShuffleRestricted(rows, 0)
+		for b.IsSolved() {
+			iterations += b.ShuffleRestricted(rows, 0)
+		}
+		return fmt.Sprintf("Shuffled rows with %d iterations", iterations)
+	case CornerScramble:
+		w, h := b.Width()/2+1, b.Height()/2+1
+		b.Region(b.Width()-w, b.Height()-h, w, h).Shuffle()
+		return fmt.Sprintf("Scrambled the bottom right %dx%d corner to a random state", w, h)
+	default:
+		return fmt.Sprintf("Shuffled board with %d iterations", b.ScrambleUntil(min))
+	}
+}
+
+// Metric is how the moves of a solve are counted.
+type Metric int
+
+const (
+	// ShiftMetric counts every single shift, the way moves are counted while playing.
+	ShiftMetric Metric = iota
+	// SliceMetric counts consecutive moves of the same slice as one.
+	SliceMetric
+)
+
+func (m Metric) String() string {
+	switch m {
+	case ShiftMetric:
+		return "shifts"
+	case SliceMetric:
+		return "slices"
+	default:
+		return fmt.Sprintf("Metric(%d)", int(m))
+	}
+}
+
+// ParseMetric parses the name of a metric, as returned by Metric.String.
+func ParseMetric(s string) (Metric, error) {
+	for m := ShiftMetric; m <= SliceMetric; m++ {
+		if strings.EqualFold(s, m.String()) {
+			return m, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown metric %q, expected shifts or slices", s)
+}
+
+// MovesIn returns how many moves the game's solve made so far, counted in the metric.
+func (g *Game) MovesIn(m Metric) int {
+	if m == SliceMetric {
+		return SliceMoves(g.History.Path(), &g.Board)
+	}
+
+	return g.Moves
+}
+
+// Preset is a named bundle of game settings, so that competitions can standardize them.
+// Presets are defined in the config, or in files of their own to share them, written as JSON.
+type Preset struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Size is the board size, like "5x5".
+	Size string `json:"size"`
+	// Scramble, Metric and TimeLimit are the names of a ScrambleKind and a Metric, and a duration like "2m", all optional.
+	Scramble  string `json:"scramble,omitempty"`
+	Metric    string `json:"metric,omitempty"`
+	TimeLimit string `json:"time_limit,omitempty"`
+	// Inspection is how long scrambles can be inspected before the solve starts, like "15s", if the preset plays under competition rules.
+	Inspection string `json:"inspection,omitempty"`
+}
+
+// BuiltinPresets are the presets that are always available.
+var BuiltinPresets = []*Preset{
+	{Name: "wc-5x5", Description: "5x5 from a random state, counting single shifts, with 15 seconds of inspection", Size: "5x5", Scramble: "random-state", Metric: "shifts", Inspection: "15s"},
+	{Name: "blitz-4x4", Description: "4x4 from a random state in under a minute, counting slice moves", Size: "4x4", Scramble: "random-state", Metric: "slices", TimeLimit: "1m"},
+	{Name: "big-20", Description: "20x20 fast shuffled, counting single shifts", Size: "20x20", Scramble: "fast", Metric: "shifts"},
+}
+
+// Validate checks that every setting of the preset is valid.
+func (p *Preset) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("preset has no name")
+	}
+	if _, _, err := ParseTwoDimensions(p.Size); err != nil {
+		return fmt.Errorf("preset %s: %s", p.Name, err)
+	}
+	if p.Scramble != "" {
+		if _, err := ParseScrambleKind(p.Scramble); err != nil {
+			return fmt.Errorf("preset %s: %s", p.Name, err)
+		}
+	}
+	if p.Metric != "" {
+		if _, err := ParseMetric(p.Metric); err != nil {
+			return fmt.Errorf("preset %s: %s", p.Name, err)
+		}
+	}
+	for _, d := range []string{p.TimeLimit, p.Inspection} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d); err != nil {
+			return fmt.Errorf("preset %s: %s", p.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Apply sets the settings of the preset in `o`, except for the ones of the flags in `set`, which were given on the command line.
+func (p *Preset) Apply(o *PlayOptions, set map[string]bool) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	if !set["size"] {
+		o.Size = p.Size
+	}
+	if p.Scramble != "" && !set["scramble"] {
+		o.Scramble, _ = ParseScrambleKind(p.Scramble)
+	}
+	if p.Metric != "" {
+		o.Metric, _ = ParseMetric(p.Metric)
+	}
+	if p.TimeLimit != "" && !set["time-limit"] {
+		o.TimeLimit, _ = time.ParseDuration(p.TimeLimit)
+	}
+	if p.Inspection != "" && !set["inspection"] {
+		o.Inspection, _ = time.ParseDuration(p.Inspection)
+	}
+
+	return nil
+}
+
+func (p *Preset) String() string {
+	s := fmt.Sprintf("%s: %s", p.Name, p.Size)
+	if p.Scramble != "" {
+		s += ", " + p.Scramble + " scramble"
+	}
+	if p.Metric != "" {
+		s += ", counting " + p.Metric
+	}
+	if p.TimeLimit != "" {
+		s += ", " + p.TimeLimit + " time limit"
+	}
+	if p.Inspection != "" {
+		s += ", " + p.Inspection + " inspection"
+	}
+	if p.Description != "" {
+		s += " (" + p.Description + ")"
+	}
+
+	return s
+}
+
+// LoadPresetFile reads a preset shared as a file.
+func LoadPresetFile(path string) (*Preset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Preset
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return &p, nil
+}
+
+// AllPresets returns every preset by name: the built-in ones and the ones defined in the config, which replace built-in ones of the same name.
+func (c *Config) AllPresets() map[string]*Preset {
+	presets := map[string]*Preset{}
+	for _, p := range BuiltinPresets {
+		presets[p.Name] = p
+	}
+	for name, p := range c.Presets {
+		p.Name = name
+		presets[name] = p
+	}
+
+	return presets
+}
+
+// FindPreset returns the preset with the given name, or the one in the file at `name` if there is no preset with that name.
+func (c *Config) FindPreset(name string) (*Preset, error) {
+	if p, ok := c.AllPresets()[name]; ok {
+		return p, nil
+	}
+	if _, err := os.Stat(name); err == nil {
+		return LoadPresetFile(name)
+	}
+
+	return nil, fmt.Errorf("unknown preset %q, list them with the presets command", name)
+}
+
+// runPresets runs the presets command.
+func runPresets(args []string) error {
+	fs := flag.NewFlagSet("presets", flag.ContinueOnError)
+	out := fs.String("o", "", "file to export the preset to instead of the standard output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: presets | presets export NAME [-o FILE] | presets import FILE")
+		fmt.Fprintln(fs.Output(), "Lists the presets, writes one as a file to share it, or adds one shared as a file to the config. Presets are played with -preset NAME, or -preset FILE.")
+		fs.PrintDefaults()
+	}
+
+	action := "list"
+	if len(args) != 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	// flags can come after the name or file too, like "presets export wc-5x5 -o wc.json".
+	var names []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		names = append(names, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	c, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "list":
+		presets := c.AllPresets()
+		var names []string
+		for name := range presets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fmt.Println(presets[name])
+		}
+		return nil
+	case "export":
+		if len(names) != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected the name of a preset")
+		}
+		p, err := c.FindPreset(names[0])
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+
+		if *out == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		return os.WriteFile(*out, data, 0o644)
+	case "import":
+		if len(names) != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected a preset file")
+		}
+		p, err := LoadPresetFile(names[0])
+		if err != nil {
+			return err
+		}
+
+		if c.Presets == nil {
+			c.Presets = map[string]*Preset{}
+		}
+		c.Presets[p.Name] = p
+		if err := c.Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Added preset %s\n", p)
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// Profiling holds the profiles a command was asked to write, for investigating the performance of solvers and searches without changing the code.
+type Profiling struct {
+	// CPU, Mem and Trace are the files to write a CPU profile, a heap profile and an execution trace to, if not empty.
+	CPU, Mem, Trace string
+
+	cpu, trace *os.File
+}
+
+// AddProfilingFlags adds the -cpuprofile, -memprofile and -trace flags to a command's flags.
+func AddProfilingFlags(fs *flag.FlagSet) *Profiling {
+	p := &Profiling{}
+	fs.StringVar(&p.CPU, "cpuprofile", "", "write a CPU profile to this file")
+	fs.StringVar(&p.Mem, "memprofile", "", "write a heap profile to this file when the command ends")
+	fs.StringVar(&p.Trace, "trace", "", "write an execution trace to this file")
+
+	return p
+}
+
+// Start starts the CPU profile and the execution trace. Stop has to be called once the command ends, even if Start fails.
+func (p *Profiling) Start() error {
+	if p.CPU != "" {
+		f, err := os.Create(p.CPU)
+		if err != nil {
+			return err
+		}
+		p.cpu = f
+
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return err
+		}
+	}
+
+	if p.Trace != "" {
+		f, err := os.Create(p.Trace)
+		if err != nil {
+			return err
+		}
+		p.trace = f
+
+		if err := trace.Start(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stop stops the CPU profile and the execution trace, and writes the heap profile.
+// Errors are printed instead of returned, so that they don't hide the command's own result.
+func (p *Profiling) Stop() {
+	if p.cpu != nil {
+		pprof.StopCPUProfile()
+		closeProfile(p.cpu)
+	}
+
+	if p.trace != nil {
+		trace.Stop()
+		closeProfile(p.trace)
+	}
+
+	if p.Mem != "" {
+		f, err := os.Create(p.Mem)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not write heap profile (%s)\n", err)
+			return
+		}
+
+		// collect garbage first so the profile shows what's still in use.
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not write heap profile (%s)\n", err)
+		}
+		closeProfile(f)
+	}
+}
+
+// closeProfile closes a profile file, printing why if it fails.
+func closeProfile(f *os.File) {
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not write %s (%s)\n", f.Name(), err)
+	}
+}
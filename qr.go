@@ -0,0 +1,557 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "qr",
+		Summary: "print a state code as a QR code, or write it as a PNG",
+		Run:     runQR,
+	})
+}
+
+// QRCode is a QR code, with every module dark or light.
+// Codes are encoded in byte mode with the lowest error correction level, which fits the most data.
+type QRCode struct {
+	Version int
+	// Modules holds whether every module is dark, row by row from the top.
+	Modules [][]bool
+
+	function [][]bool
+}
+
+// qrECCPerBlock and qrBlocks hold how many error correction codewords every block has and how many blocks there are, for every version at the lowest error correction level.
+var (
+	qrECCPerBlock = [41]int{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30}
+	qrBlocks      = [41]int{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25}
+)
+
+// qrRawModules returns how many modules of a QR code of the given version can hold data, including error correction.
+func qrRawModules(ver int) int {
+	n := (16*ver+128)*ver + 64
+	if ver >= 2 {
+		align := ver/7 + 2
+		n -= (25*align-10)*align - 55
+		if ver >= 7 {
+			n -= 36
+		}
+	}
+
+	return n
+}
+
+// qrDataCodewords returns how many bytes of data, excluding error correction, a QR code of the given version holds.
+func qrDataCodewords(ver int) int {
+	return qrRawModules(ver)/8 - qrECCPerBlock[ver]*qrBlocks[ver]
+}
+
+// EncodeQR encodes data as the smallest QR code that can hold it.
+func EncodeQR(data []byte) (*QRCode, error) {
+	ver := 1
+	for ; ver <= 40; ver++ {
+		countBits := 8
+		if ver >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= 8*qrDataCodewords(ver) {
+			break
+		}
+	}
+	if ver > 40 {
+		return nil, fmt.Errorf("%d bytes don't fit in a QR code", len(data))
+	}
+
+	// byte mode indicator, character count and data, then a terminator and padding up to the capacity.
+	var bits []bool
+	put := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, v>>i&1 == 1)
+		}
+	}
+	put(0x4, 4)
+	if ver < 10 {
+		put(len(data), 8)
+	} else {
+		put(len(data), 16)
+	}
+	for _, b := range data {
+		put(int(b), 8)
+	}
+
+	capacity := 8 * qrDataCodewords(ver)
+	for i := 0; i < 4 && len(bits) < capacity; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	for pad := 0xec; len(bits) < capacity; pad ^= 0xec ^ 0x11 {
+		put(pad, 8)
+	}
+
+	codewords := make([]byte, len(bits)/8)
+	for i, b := range bits {
+		if b {
+			codewords[i/8] |= 1 << (7 - i%8)
+		}
+	}
+
+	q := newQRCode(ver)
+	q.drawCodewords(qrAddECC(ver, codewords))
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormat(mask)
+		if p := q.penalty(); bestPenalty == -1 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		// masks are undone by applying them again.
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormat(best)
+
+	return q, nil
+}
+
+// newQRCode creates a QR code of the given version with its function patterns drawn.
+func newQRCode(ver int) *QRCode {
+	size := 4*ver + 17
+	q := &QRCode{Version: ver, Modules: make([][]bool, size), function: make([][]bool, size)}
+	for y := range q.Modules {
+		q.Modules[y] = make([]bool, size)
+		q.function[y] = make([]bool, size)
+	}
+
+	for i := 0; i < size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+
+	for _, c := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x >= 0 && x < size && y >= 0 && y < size {
+					d := maxInt(Abs(dx), Abs(dy))
+					q.setFunction(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+
+	align := qrAlignmentPositions(ver)
+	last := len(align) - 1
+	for i, ax := range align {
+		for j, ay := range align {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.setFunction(ax+dx, ay+dy, maxInt(Abs(dx), Abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// reserve the format modules, drawn once the mask is picked.
+	q.drawFormat(0)
+
+	if ver >= 7 {
+		rem := ver
+		for i := 0; i < 12; i++ {
+			rem = rem<<1 ^ (rem>>11)*0x1f25
+		}
+		bits := ver<<12 | rem
+
+		for i := 0; i < 18; i++ {
+			a, b := size-11+i%3, i/3
+			q.setFunction(a, b, bits>>i&1 == 1)
+			q.setFunction(b, a, bits>>i&1 == 1)
+		}
+	}
+
+	return q
+}
+
+// maxInt returns the largest of `a` and `b`.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// qrAlignmentPositions returns the coordinates, on both axes, of the centers of the alignment patterns of a QR code of the given version.
+func qrAlignmentPositions(ver int) []int {
+	if ver == 1 {
+		return nil
+	}
+
+	n := ver/7 + 2
+	step := (ver*4 + n*2 + 1) / (n*2 - 2) * 2
+	if ver == 32 {
+		step = 26
+	}
+
+	pos := make([]int, n)
+	pos[0] = 6
+	for i, p := n-1, 4*ver+10; i >= 1; i, p = i-1, p-step {
+		pos[i] = p
+	}
+
+	return pos
+}
+
+// setFunction sets a module that is part of a function pattern, which holds no data and is never masked.
+func (q *QRCode) setFunction(x, y int, dark bool) {
+	q.Modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+// drawFormat draws both copies of the format information, for the lowest error correction level and the given mask.
+func (q *QRCode) drawFormat(mask int) {
+	data := 1<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	size := len(q.Modules)
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.setFunction(size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, size-15+i, bit(i))
+	}
+	q.setFunction(8, size-8, true)
+}
+
+// qrAddECC splits the data codewords of a QR code of the given version into blocks, adds their error correction codewords and interleaves them.
+func qrAddECC(ver int, data []byte) []byte {
+	blocks, eccLen := qrBlocks[ver], qrECCPerBlock[ver]
+	raw := qrRawModules(ver) / 8
+	short := blocks - raw%blocks
+	shortLen := raw / blocks
+
+	divisor := qrDivisor(eccLen)
+	var all [][]byte
+	for i, k := 0, 0; i < blocks; i++ {
+		n := shortLen - eccLen
+		if i >= short {
+			n++
+		}
+
+		block := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := qrRemainder(block, divisor)
+		if i < short {
+			// short blocks are padded so every block has the same length, and the padding is skipped when interleaving.
+			block = append(block, 0)
+		}
+		all = append(all, append(block, ecc...))
+	}
+
+	var out []byte
+	for i := range all[0] {
+		for j, block := range all {
+			if i != shortLen-eccLen || j >= short {
+				out = append(out, block[i])
+			}
+		}
+	}
+
+	return out
+}
+
+// qrMultiply multiplies two elements of the Galois field QR codes use for error correction.
+func qrMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11d
+		z ^= int(y>>i&1) * int(x)
+	}
+
+	return byte(z)
+}
+
+// qrDivisor returns the Reed-Solomon generator polynomial of the given degree, without its leading term.
+func qrDivisor(degree int) []byte {
+	d := make([]byte, degree)
+	d[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range d {
+			d[j] = qrMultiply(d[j], root)
+			if j+1 < degree {
+				d[j] ^= d[j+1]
+			}
+		}
+		root = qrMultiply(root, 2)
+	}
+
+	return d
+}
+
+// qrRemainder returns the Reed-Solomon error correction codewords of data.
+func qrRemainder(data, divisor []byte) []byte {
+	r := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ r[0]
+		copy(r, r[1:])
+		r[len(r)-1] = 0
+
+		for i := range r {
+			r[i] ^= qrMultiply(divisor[i], factor)
+		}
+	}
+
+	return r
+}
+
+// drawCodewords draws the codewords in the zigzag order QR codes are read in, going up and down columns two modules wide from the right.
+func (q *QRCode) drawCodewords(data []byte) {
+	size := len(q.Modules)
+
+	i := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// the vertical timing pattern is skipped.
+			right = 5
+		}
+
+		for vert := 0; vert < size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = size - 1 - vert
+				}
+
+				if !q.function[y][x] && i < len(data)*8 {
+					q.Modules[y][x] = data[i/8]>>(7-i%8)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules picked by one of the 8 mask patterns.
+func (q *QRCode) applyMask(mask int) {
+	for y := range q.Modules {
+		for x := range q.Modules[y] {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+
+			if flip && !q.function[y][x] {
+				q.Modules[y][x] = !q.Modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the code is to read, to pick the mask with the lowest score: long runs and blocks of the same color, patterns that look like finders and an unbalanced number of dark modules all add to it.
+func (q *QRCode) penalty() int {
+	size := len(q.Modules)
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return q.Modules[x][y]
+		}
+		return q.Modules[y][x]
+	}
+
+	var p int
+	for _, transpose := range []bool{false, true} {
+		for y := 0; y < size; y++ {
+			var line strings.Builder
+			run := 0
+			for x := 0; x < size; x++ {
+				if x > 0 && at(x, y, transpose) == at(x-1, y, transpose) {
+					run++
+				} else {
+					run = 1
+				}
+				if run == 5 {
+					p += 3
+				} else if run > 5 {
+					p++
+				}
+
+				if at(x, y, transpose) {
+					line.WriteByte('1')
+				} else {
+					line.WriteByte('0')
+				}
+			}
+
+			// the light margin around the code counts as light modules.
+			s := "0000" + line.String() + "0000"
+			p += 40 * (strings.Count(s, "00001011101") + strings.Count(s, "10111010000"))
+		}
+	}
+
+	var dark int
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if q.Modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				c := q.Modules[y][x]
+				if c == q.Modules[y-1][x] && c == q.Modules[y][x-1] && c == q.Modules[y-1][x-1] {
+					p += 3
+				}
+			}
+		}
+	}
+
+	total := size * size
+	k := (Abs(dark*20-total*10)+total-1)/total - 1
+	return p + 10*k
+}
+
+// qrQuietZone is how many light modules wide the margin around a QR code must be.
+const qrQuietZone = 4
+
+// dark returns whether the module at x, y is dark, where coordinates in the quiet zone around the code are light.
+func (q *QRCode) dark(x, y int) bool {
+	size := len(q.Modules)
+	return x >= 0 && x < size && y >= 0 && y < size && q.Modules[y][x]
+}
+
+// Terminal formats the code with block characters, two rows of modules per line, along with its quiet zone.
+// Dark modules are drawn as spaces and light ones as blocks, for terminals with a dark background, unless `invert` is true.
+func (q *QRCode) Terminal(invert bool) string {
+	var sb strings.Builder
+	blocks := [4]string{" ", "▄", "▀", "█"}
+
+	size := len(q.Modules)
+	for y := -qrQuietZone; y < size+qrQuietZone; y += 2 {
+		for x := -qrQuietZone; x < size+qrQuietZone; x++ {
+			top, bottom := q.dark(x, y) == invert, q.dark(x, y+1) == invert
+			if y+1 >= size+qrQuietZone {
+				// past the quiet zone, which only happens on the last line, is left as the terminal's background.
+				bottom = false
+			}
+
+			var i int
+			if top {
+				i |= 2
+			}
+			if bottom {
+				i |= 1
+			}
+			sb.WriteString(blocks[i])
+		}
+		sb.WriteByte('\n')
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// PNG writes the code as a PNG image with every module `scale` pixels wide, along with its quiet zone.
+func (q *QRCode) PNG(w io.Writer, scale int) error {
+	n := (len(q.Modules) + 2*qrQuietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, n, n))
+
+	for py := 0; py < n; py++ {
+		for px := 0; px < n; px++ {
+			c := color.Gray{0xff}
+			if q.dark(px/scale-qrQuietZone, py/scale-qrQuietZone) {
+				c = color.Gray{0}
+			}
+			img.SetGray(px, py, c)
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// runQR runs the qr command.
+func runQR(args []string) error {
+	fs := flag.NewFlagSet("qr", flag.ContinueOnError)
+	out := fs.String("o", "", "PNG file to write the code to instead of printing it")
+	scale := fs.Int("scale", 8, "width of every module of the PNG, in pixels")
+	invert := fs.Bool("invert", false, "draw dark modules as blocks, for terminals with a light background")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: qr [-invert] [-o FILE] [-scale 8] STATE")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a state code")
+	}
+
+	b, err := DecodeState(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	q, err := EncodeQR([]byte(EncodeState(&b)))
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		fmt.Println(q.Terminal(*invert))
+		return nil
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+
+	err = q.PNG(f, *scale)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
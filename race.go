@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EngineOpponent is a computer opponent for races that plays at a limited speed and quality, so that people can beat it.
+type EngineOpponent struct {
+	Level int
+	// TPS is how many moves per second it makes.
+	TPS float64
+	// Waste is how many extra moves it makes per move of its solution, as a fraction.
+	Waste float64
+}
+
+// engineLevels holds the TPS and waste of every engine level, from the weakest to the strongest.
+var engineLevels = []struct{ TPS, Waste float64 }{
+	{0.5, 1},
+	{1, 0.6},
+	{2, 0.3},
+	{3, 0.15},
+	{5, 0},
+}
+
+// ParseOpponent parses an engine opponent in the form "engine:levelN", with N from 1 to the number of levels.
+func ParseOpponent(s string) (EngineOpponent, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if !strings.HasPrefix(s, "engine:level") {
+		return EngineOpponent{}, fmt.Errorf("%q is not an opponent, use engine:level1 to engine:level%d", s, len(engineLevels))
+	}
+
+	level, err := strconv.Atoi(strings.TrimPrefix(s, "engine:level"))
+	if err != nil || level < 1 || level > len(engineLevels) {
+		return EngineOpponent{}, fmt.Errorf("engine level must be between 1 and %d", len(engineLevels))
+	}
+
+	l := engineLevels[level-1]
+	return EngineOpponent{Level: level, TPS: l.TPS, Waste: l.Waste}, nil
+}
+
+// Name returns the name the opponent is rated under.
+func (e EngineOpponent) Name() string {
+	return fmt.Sprintf("engine:level%d", e.Level)
+}
+
+// Plan returns how many moves the opponent takes to solve a scramble and how long it takes to make them.
+// The number of wasted moves varies randomly by up to a half in either direction.
+func (e EngineOpponent) Plan(scramble *Board) (int, time.Duration, error) {
+	res, err := SolveHuman(scramble)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	n := MovesLength(res.Moves)
+	n += int(math.Round(float64(n) * e.Waste * (0.5 + rng.Float64())))
+
+	return n, time.Duration(float64(n) / e.TPS * float64(time.Second)), nil
+}
+
+// Race is a race against an engine opponent on the current scramble.
+type Race struct {
+	Opponent EngineOpponent
+	// Moves and Time are how many moves the opponent takes and how long it takes to make them, starting from the player's first move.
+	Moves int
+	Time  time.Duration
+}
+
+// StartRace scrambles the board and starts a race against an engine opponent.
+func (g *Game) StartRace(e EngineOpponent) error {
+	if g.Restrictions != nil {
+		return fmt.Errorf("engine opponents can't play with restricted moves")
+	}
+
+	g.Board.ScrambleUntil(MinScrambleDistance(g.Board.Width(), g.Board.Height()))
+	g.Restart()
+
+	n, d, err := e.Plan(&g.Board)
+	if err != nil {
+		return err
+	}
+
+	g.Race = &Race{Opponent: e, Moves: n, Time: d}
+	return nil
+}
+
+// OpponentMoves returns how many moves the opponent has made so far.
+func (r *Race) OpponentMoves(elapsed time.Duration) int {
+	if elapsed >= r.Time {
+		return r.Moves
+	}
+
+	return int(elapsed.Seconds() * r.Opponent.TPS)
+}
+
+// formatRaceTime formats the time of the player in a race, like "12.34s", "14.34s (+2)" or "DNF".
+func formatRaceTime(d time.Duration, p Penalty) string {
+	switch {
+	case p.DNF:
+		return p.String()
+	case p.PlusTwos > 0:
+		return fmt.Sprintf("%.2fs (%s)", d.Seconds(), p)
+	default:
+		return fmt.Sprintf("%.2fs", d.Seconds())
+	}
+}
+
+// FinishRace ends the race after the board was solved or the solve was given up, recording its result in the profile store under `player`.
+// The player's time counts its penalties, and a DNF loses the race. Returns a description of the result.
+func (g *Game) FinishRace(player string) (string, error) {
+	r := g.Race
+	g.Race = nil
+
+	res := RaceResult{Player: player, Opponent: r.Opponent.Name(), PlayerTime: g.Penalty.Add(g.Elapsed()), OpponentTime: r.Time}
+	if g.Penalty.DNF {
+		res.PlayerTime = 0
+	}
+
+	yours := formatRaceTime(res.PlayerTime, g.Penalty)
+
+	var s string
+	switch res.Score() {
+	case 1:
+		s = fmt.Sprintf("You won! %s against %s's %.2fs", yours, res.Opponent, res.OpponentTime.Seconds())
+	case 0:
+		s = fmt.Sprintf("%s won with %.2fs against your %s", res.Opponent, res.OpponentTime.Seconds(), yours)
+	default:
+		s = fmt.Sprintf("Draw against %s at %s", res.Opponent, yours)
+	}
+
+	p, err := LoadProfiles()
+	if err != nil {
+		return s, err
+	}
+
+	delta := p.RecordRace(res, RatingK)
+	if err := p.Save(); err != nil {
+		return s, err
+	}
+
+	return fmt.Sprintf("%s, rating %.0f (%+.1f)", s, p.Get(player).Rating, delta), nil
+}
+
+// Ghost is a recorded solve of the scramble being played, played back alongside the player's solve to compare their paces.
+type Ghost struct {
+	// Name is whose solve it is.
+	Name     string
+	Scramble Board
+	Moves    []Move
+	// Times holds when every move was made, since the first move.
+	Times []time.Duration
+}
+
+// NewGhost returns the ghost of a timed replay that solves its scramble, named after who played it.
+func NewGhost(name string, r *Replay) (*Ghost, error) {
+	if r.Times == nil || len(r.Moves) == 0 {
+		return nil, fmt.Errorf("the replay isn't timed")
+	}
+
+	b := r.Scramble.Clone()
+	if _, err := b.ApplyMoves(r.Moves); err != nil {
+		return nil, err
+	}
+	if !b.IsSolved() {
+		return nil, fmt.Errorf("the replay doesn't solve its scramble")
+	}
+
+	return &Ghost{Name: name, Scramble: r.Scramble.Clone(), Moves: r.Moves, Times: r.Times}, nil
+}
+
+// Time returns how long the ghost took to solve.
+func (gh *Ghost) Time() time.Duration {
+	return gh.Times[len(gh.Times)-1]
+}
+
+// MovesAt returns how many moves the ghost made `elapsed` after its first move.
+func (gh *Ghost) MovesAt(elapsed time.Duration) int {
+	return sort.Search(len(gh.Times), func(i int) bool { return gh.Times[i] > elapsed })
+}
+
+// RowsAt returns how many rows from the top the ghost solved `elapsed` after its first move.
+func (gh *Ghost) RowsAt(elapsed time.Duration) int {
+	b := gh.Scramble.Clone()
+	b.ApplyMoves(gh.Moves[:gh.MovesAt(elapsed)])
+
+	rows, _ := b.SolvedRegion()
+	return rows
+}
+
+// StartGhostRace replaces the board with the ghost's scramble and starts racing the ghost, whose clock starts with the player's first move.
+func (g *Game) StartGhostRace(gh *Ghost) {
+	g.Board = gh.Scramble.Clone()
+	g.Restart()
+	g.Ghost = gh
+}
+
+// FinishGhostRace ends the race against the ghost after the board was solved or the solve was given up, returning how the solve compares to the ghost's.
+// The player's time counts its penalties, and a DNF loses, like in races against the engine.
+func (g *Game) FinishGhostRace() string {
+	gh := g.Ghost
+	g.Ghost = nil
+
+	you, them := g.Penalty.Add(g.Elapsed()), gh.Time()
+	res := RaceResult{PlayerTime: you, OpponentTime: them}
+	if g.Penalty.DNF {
+		res.PlayerTime = 0
+	}
+
+	yours := formatRaceTime(you, g.Penalty)
+
+	switch res.Score() {
+	case 1:
+		return fmt.Sprintf("You beat %s's ghost by %.2fs, %s against %.2fs", gh.Name, (them - you).Seconds(), yours, them.Seconds())
+	case 0:
+		if g.Penalty.DNF {
+			return fmt.Sprintf("%s's ghost won with %.2fs against your %s", gh.Name, them.Seconds(), yours)
+		}
+		return fmt.Sprintf("%s's ghost was faster by %.2fs, %.2fs against your %s", gh.Name, (you - them).Seconds(), them.Seconds(), yours)
+	default:
+		return fmt.Sprintf("Tied with %s's ghost at %s", gh.Name, yours)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"time"
+)
+
+// rng is the source of randomness of every shuffle and scramble.
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// UseCryptoRand makes every shuffle and scramble draw its randomness from crypto/rand, so that they can't be predicted from a seed.
+// This is slower than the default source and meant for competitions.
+func UseCryptoRand() {
+	rng = rand.New(cryptoSource{})
+}
+
+// cryptoSource is a rand.Source64 that reads from crypto/rand.
+type cryptoSource struct{}
+
+func (s cryptoSource) Int63() int64 {
+	return int64(s.Uint64() &^ (1 << 63))
+}
+
+func (cryptoSource) Uint64() uint64 {
+	var buf [8]byte
+	if _, err := crand.Read(buf[:]); err != nil {
+		panic("crypto/rand: " + err.Error())
+	}
+
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+// Seed does nothing, as crypto/rand can't be seeded.
+func (cryptoSource) Seed(int64) {}
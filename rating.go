@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "rating",
+		Summary: "list player and engine ratings, or record a race result",
+		Run:     runRating,
+	})
+}
+
+// DefaultRating is the rating of players and engines that haven't raced yet.
+const DefaultRating = 1200
+
+// RatingK is the K-factor of rating updates: the most a rating can change after a single race.
+var RatingK = 32.0
+
+// Profile holds what is known about a player or an engine opponent.
+type Profile struct {
+	Name   string  `json:"name"`
+	Rating float64 `json:"rating"`
+	// Races is how many rated races were played.
+	Races int `json:"races"`
+	// BestScore is the best score of the games played in scoring mode, of which there were ScoredGames.
+	BestScore   int `json:"best_score,omitempty"`
+	ScoredGames int `json:"scored_games,omitempty"`
+}
+
+// Profiles is the store of every profile, saved as JSON in the data directory.
+type Profiles struct {
+	path    string
+	Players map[string]*Profile `json:"players"`
+}
+
+// DataDir returns the directory where profiles and other persistent data are stored: $LOOPOVER_HOME if set, or a loopover directory in the user's config directory.
+func DataDir() (string, error) {
+	if dir := os.Getenv("LOOPOVER_HOME"); dir != "" {
+		return dir, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "loopover"), nil
+}
+
+// LoadProfiles reads the profile store from the data directory. A missing store is empty.
+func LoadProfiles() (*Profiles, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Profiles{path: filepath.Join(dir, "profiles.json"), Players: map[string]*Profile{}}
+
+	data, err := os.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%s: %s", p.path, err)
+	}
+	if p.Players == nil {
+		p.Players = map[string]*Profile{}
+	}
+
+	return p, nil
+}
+
+// Save writes the profile store to the data directory.
+func (p *Profiles) Save() error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(p.path, data, 0o644)
+}
+
+// Get returns the profile with the given name, creating it with DefaultRating if it doesn't exist.
+func (p *Profiles) Get(name string) *Profile {
+	pr, ok := p.Players[name]
+	if !ok {
+		pr = &Profile{Name: name, Rating: DefaultRating}
+		p.Players[name] = pr
+	}
+
+	return pr
+}
+
+// Sorted returns every profile from the highest to the lowest rating.
+func (p *Profiles) Sorted() []*Profile {
+	list := make([]*Profile, 0, len(p.Players))
+	for _, pr := range p.Players {
+		list = append(list, pr)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Rating != list[j].Rating {
+			return list[i].Rating > list[j].Rating
+		}
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// RaceResult is the outcome of a timed race between two players on the same scramble.
+type RaceResult struct {
+	Player, Opponent string
+	// PlayerTime and OpponentTime are how long each took to solve, or 0 if they didn't finish.
+	PlayerTime, OpponentTime time.Duration
+}
+
+// Score returns 1 if the player won, 0 if the opponent won and 0.5 on a draw.
+// A finished solve beats an unfinished one.
+func (r RaceResult) Score() float64 {
+	switch {
+	case r.PlayerTime == r.OpponentTime:
+		return 0.5
+	case r.OpponentTime == 0 || r.PlayerTime != 0 && r.PlayerTime < r.OpponentTime:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ExpectedScore returns the score a player with rating `a` is expected to get against one with rating `b`.
+func ExpectedScore(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// RecordRace updates the ratings of both players of a race with the K-factor `k`, returning how much the player's rating changed.
+func (p *Profiles) RecordRace(r RaceResult, k float64) float64 {
+	a, b := p.Get(r.Player), p.Get(r.Opponent)
+
+	delta := k * (r.Score() - ExpectedScore(a.Rating, b.Rating))
+	a.Rating += delta
+	b.Rating -= delta
+	a.Races++
+	b.Races++
+
+	return delta
+}
+
+// runRating runs the rating command.
+func runRating(args []string) error {
+	fs := flag.NewFlagSet("rating", flag.ContinueOnError)
+	fs.Float64Var(&RatingK, "k", RatingK, "K-factor: the most a rating can change after a single race")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: rating [-k K] [PLAYER TIME OPPONENT TIME]")
+		fmt.Fprintln(fs.Output(), "Without arguments, lists every rating. Otherwise records a race, where a time of 0 means the solve wasn't finished.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	p, err := LoadProfiles()
+	if err != nil {
+		return err
+	}
+
+	switch fs.NArg() {
+	case 0:
+		for i, pr := range p.Sorted() {
+			fmt.Printf("%3d. %-20s %6.0f (%d races)\n", i+1, pr.Name, pr.Rating, pr.Races)
+		}
+		return nil
+	case 4:
+	default:
+		fs.Usage()
+		return fmt.Errorf("expected a player, a time, an opponent and a time")
+	}
+
+	r := RaceResult{Player: fs.Arg(0), Opponent: fs.Arg(2)}
+	if r.PlayerTime, err = time.ParseDuration(fs.Arg(1)); err != nil {
+		return err
+	}
+	if r.OpponentTime, err = time.ParseDuration(fs.Arg(3)); err != nil {
+		return err
+	}
+
+	delta := p.RecordRace(r, RatingK)
+	if err := p.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: %.0f (%+.1f), %s: %.0f (%+.1f)\n", r.Player, p.Get(r.Player).Rating, delta, r.Opponent, p.Get(r.Opponent).Rating, -delta)
+	return nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "reconstruct",
+		Summary: "write a Markdown or HTML reconstruction of a replay file",
+		Run:     runReconstruct,
+	})
+}
+
+// Phase is a part of a line of moves, ending when more rows from the top become solved or at the end of the line.
+type Phase struct {
+	Name string
+	// Start and End are the indices of the phase's first move and of the move after its last one.
+	Start, End int
+	// Board is the board at the end of the phase.
+	Board Board
+}
+
+// SplitPhases splits the moves of a replay into phases, one for each time more rows from the top become solved, or at the splits marked in the replay if there are any.
+func SplitPhases(r *Replay) []Phase {
+	if r.Splits != nil {
+		return markedPhases(r)
+	}
+
+	b := r.Scramble.Clone()
+	solved, _ := b.SolvedRegion()
+
+	var phases []Phase
+	start := 0
+	for i := range r.Moves {
+		b.MakeMove(&r.Moves[i])
+
+		rows, _ := b.SolvedRegion()
+		if rows <= solved {
+			continue
+		}
+
+		phases = append(phases, Phase{
+			Name:  rowsPhaseName(solved, rows, b.Height()),
+			Start: start,
+			End:   i + 1,
+			Board: b.Clone(),
+		})
+		solved, start = rows, i+1
+	}
+
+	if start < len(r.Moves) {
+		phases = append(phases, Phase{
+			Name:  "Unfinished",
+			Start: start,
+			End:   len(r.Moves),
+			Board: b.Clone(),
+		})
+	}
+
+	return phases
+}
+
+// markedPhases splits the moves of a replay into phases at the splits marked in it, naming every phase after the split that ends it.
+func markedPhases(r *Replay) []Phase {
+	b := r.Scramble.Clone()
+
+	var phases []Phase
+	start := 0
+	for i := range r.Moves {
+		b.MakeMove(&r.Moves[i])
+		if r.Splits[i] == "" {
+			continue
+		}
+
+		phases = append(phases, Phase{Name: r.Splits[i], Start: start, End: i + 1, Board: b.Clone()})
+		start = i + 1
+	}
+
+	if start < len(r.Moves) {
+		name := "Unfinished"
+		if b.IsSolved() {
+			name = "Finish"
+		}
+		phases = append(phases, Phase{Name: name, Start: start, End: len(r.Moves), Board: b.Clone()})
+	}
+
+	return phases
+}
+
+// rowsPhaseName names a phase that went from `from` to `to` solved rows on a board with `height` rows.
+func rowsPhaseName(from, to, height int) string {
+	switch {
+	case to == height:
+		if from == height-1 {
+			return "Last row"
+		}
+		return fmt.Sprintf("Last %d rows", height-from)
+	case to == from+1:
+		return fmt.Sprintf("Row %d", to)
+	default:
+		return fmt.Sprintf("Rows %d to %d", from+1, to)
+	}
+}
+
+// phaseMoves returns how many single shifts the moves of a phase make.
+func phaseMoves(r *Replay, p Phase) int {
+	var n int
+	for _, m := range r.Moves[p.Start:p.End] {
+		n += Abs(m.Amount)
+	}
+
+	return n
+}
+
+// WriteMarkdown writes a reconstruction of a replay as Markdown with its moves in the given format, with the moves of every phase, their comments and ASCII diagrams of the board after each phase.
+func WriteMarkdown(w io.Writer, r *Replay, f MoveFormat) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "# Loopover %dx%d reconstruction\n\n", r.Scramble.Width(), r.Scramble.Height())
+	fmt.Fprintf(bw, "Scramble: `%s`\n\n", EncodeState(&r.Scramble))
+	fmt.Fprintf(bw, "```\n%s\n```\n", SprintBoard(&r.Scramble))
+	if c := r.Comments[0]; c != "" {
+		fmt.Fprintf(bw, "\n%s\n", c)
+	}
+
+	var total int
+	for _, p := range SplitPhases(r) {
+		n := phaseMoves(r, p)
+		total += n
+
+		fmt.Fprintf(bw, "\n## %s (%d moves)\n\n", p.Name, n)
+		fmt.Fprintf(bw, "`%s`\n", f.FormatMoves(r.Moves[p.Start:p.End], &r.Scramble))
+
+		var comments bool
+		for i := p.Start; i < p.End; i++ {
+			if c := r.Comments[i+1]; c != "" {
+				if !comments {
+					bw.WriteString("\n")
+					comments = true
+				}
+				fmt.Fprintf(bw, "- after `%s`: %s\n", f.Format(r.Moves[i], &r.Scramble), strings.Replace(c, "\n", " ", -1))
+			}
+		}
+
+		fmt.Fprintf(bw, "\n```\n%s\n```\n", SprintBoard(&p.Board))
+	}
+
+	fmt.Fprintf(bw, "\n**Total: %d moves**\n", total)
+
+	return bw.Flush()
+}
+
+// WriteHTML writes a reconstruction of a replay as an HTML page with its moves in the given format, with the moves of every phase, their comments and SVG diagrams of the board after each phase.
+func WriteHTML(w io.Writer, r *Replay, f MoveFormat) error {
+	bw := bufio.NewWriter(w)
+	esc := html.EscapeString
+
+	title := fmt.Sprintf("Loopover %dx%d reconstruction", r.Scramble.Width(), r.Scramble.Height())
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n<body>\n", title)
+	fmt.Fprintf(bw, "<h1>%s</h1>\n", title)
+	fmt.Fprintf(bw, "<p>Scramble: <code>%s</code></p>\n", EncodeState(&r.Scramble))
+	fmt.Fprintf(bw, "%s\n", SprintSVG(&r.Scramble))
+	if c := r.Comments[0]; c != "" {
+		fmt.Fprintf(bw, "<p>%s</p>\n", esc(c))
+	}
+
+	var total int
+	for _, p := range SplitPhases(r) {
+		n := phaseMoves(r, p)
+		total += n
+
+		fmt.Fprintf(bw, "<h2>%s (%d moves)</h2>\n", esc(p.Name), n)
+		fmt.Fprintf(bw, "<p><code>%s</code></p>\n", f.FormatMoves(r.Moves[p.Start:p.End], &r.Scramble))
+
+		var comments bool
+		for i := p.Start; i < p.End; i++ {
+			if c := r.Comments[i+1]; c != "" {
+				if !comments {
+					bw.WriteString("<ul>\n")
+					comments = true
+				}
+				fmt.Fprintf(bw, "<li>after <code>%s</code>: %s</li>\n", f.Format(r.Moves[i], &r.Scramble), esc(c))
+			}
+		}
+		if comments {
+			bw.WriteString("</ul>\n")
+		}
+
+		fmt.Fprintf(bw, "%s\n", SprintSVG(&p.Board))
+	}
+
+	fmt.Fprintf(bw, "<p><strong>Total: %d moves</strong></p>\n</body>\n</html>\n", total)
+
+	return bw.Flush()
+}
+
+// SprintSVG formats the board as an SVG image in the DefaultDiagramStyle, with the tiles that are in place highlighted.
+func SprintSVG(b *Board) string {
+	return DefaultDiagramStyle.SVG(b, nil)
+}
+
+// WriteReconstruction writes a reconstruction of a replay to a file with its moves in the format `mf`, as HTML if `path` ends in .html or .htm and as Markdown otherwise.
+func WriteReconstruction(path string, r *Replay, mf MoveFormat) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		err = WriteHTML(f, r, mf)
+	default:
+		err = WriteMarkdown(f, r, mf)
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// runReconstruct runs the reconstruct command.
+func runReconstruct(args []string) error {
+	fs := flag.NewFlagSet("reconstruct", flag.ContinueOnError)
+	asHTML := fs.Bool("html", false, "write HTML instead of Markdown")
+	out := fs.String("o", "", "file to write to instead of the standard output, as HTML if it ends in .html")
+	var mf MoveFormat
+	fs.Func("notation", "notation to write moves in: programmer, standard or english", func(s string) (err error) {
+		mf.Notation, err = ParseNotation(s)
+		return err
+	})
+	fs.BoolVar(&mf.ReverseIndex, "reverse-index", false, "count indices in Programmer's Notation from the bottom or right when that makes them smaller, like 1R0'")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: reconstruct [-html] [-notation NOTATION] [-reverse-index] [-o FILE] REPLAY")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a replay file")
+	}
+
+	r, err := LoadReplayFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		return WriteReconstruction(*out, r, mf)
+	}
+	if *asHTML {
+		return WriteHTML(os.Stdout, r, mf)
+	}
+	return WriteMarkdown(os.Stdout, r, mf)
+}
@@ -0,0 +1,117 @@
+package main
+
+import "fmt"
+
+// Region is a view of a rectangle of a board, to check and measure part of the board without looping over all of it.
+// Regions share the tiles of their board, and don't wrap around its edges.
+type Region struct {
+	Board               *Board
+	X, Y, Width, Height int
+}
+
+// Region returns a view of the w*h rectangle of the board whose top left tile is at (x, y).
+// Panics if the rectangle is empty or doesn't fit in the board.
+func (b *Board) Region(x, y, w, h int) Region {
+	if w <= 0 || h <= 0 || x < 0 || y < 0 || x+w > b.Width() || y+h > b.Height() {
+		panic(fmt.Sprintf("region %dx%d at (%d, %d) doesn't fit in a %dx%d board", w, h, x, y, b.Width(), b.Height()))
+	}
+
+	return Region{Board: b, X: x, Y: y, Width: w, Height: h}
+}
+
+// Contains returns true if (x, y) of the board is in the region.
+func (r Region) Contains(x, y int) bool {
+	return x >= r.X && x < r.X+r.Width && y >= r.Y && y < r.Y+r.Height
+}
+
+// IsSolvedRegion returns true if every tile of the region is in place, whatever the rest of the board looks like.
+func (r Region) IsSolvedRegion() bool {
+	b := r.Board
+	for x := r.X; x < r.X+r.Width; x++ {
+		for y := r.Y; y < r.Y+r.Height; y++ {
+			if (*b)[x][y] != b.defaultTileValue(x, y) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Placed returns how many tiles of the region are where they belong.
+func (r Region) Placed() int {
+	b := r.Board
+
+	var n int
+	for x := r.X; x < r.X+r.Width; x++ {
+		for y := r.Y; y < r.Y+r.Height; y++ {
+			if (*b)[x][y] == b.defaultTileValue(x, y) {
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+// Distance returns an estimate of how far the region is from being solved, like Board.Distance does for the whole board:
+// the sum of how many single shifts away every tile that belongs in the region is from its place, wherever it is on the board.
+func (r Region) Distance() int {
+	b := r.Board
+	w := b.Width()
+
+	var d int
+	for x := range *b {
+		for y := range (*b)[x] {
+			v := (*b)[x][y] - 1
+			if r.Contains(v%w, v/w) {
+				d += b.tileDistance(x, y)
+			}
+		}
+	}
+
+	return d
+}
+
+// Missing returns how many tiles that belong in the region are outside of it.
+func (r Region) Missing() int {
+	b := r.Board
+	w := b.Width()
+
+	var n int
+	for x := r.X; x < r.X+r.Width; x++ {
+		for y := r.Y; y < r.Y+r.Height; y++ {
+			v := (*b)[x][y] - 1
+			if !r.Contains(v%w, v/w) {
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+// Shuffle resets the board and arranges the tiles of the region at random, leaving the rest of the board solved.
+// The region must be at least 2 tiles wide, and is never left solved.
+func (r Region) Shuffle() {
+	b := r.Board
+	b.Reset()
+
+	tiles := make([]int, 0, r.Width*r.Height)
+	for i := 0; i < r.Width*r.Height; i++ {
+		tiles = append(tiles, (*b)[r.X+i%r.Width][r.Y+i/r.Width])
+	}
+	for {
+		for i, j := range rng.Perm(len(tiles)) {
+			(*b)[r.X+i%r.Width][r.Y+i/r.Width] = tiles[j]
+		}
+
+		// swapping two tiles flips the permutation parity, making it solvable.
+		if !b.IsSolvable() {
+			(*b)[r.X][r.Y], (*b)[r.X+1][r.Y] = (*b)[r.X+1][r.Y], (*b)[r.X][r.Y]
+		}
+		if !r.IsSolvedRegion() {
+			return
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the tests with the current output")
+
+// checkGolden compares `got` with the golden file testdata/`name`, or rewrites the file with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s (run go test -update to write it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// goldenBoard returns a scrambled 4x3 board, with some tiles where they belong.
+func goldenBoard(t *testing.T) Board {
+	t.Helper()
+
+	b, err := NewBoardFromTiles(4, 3, []int{1, 2, 7, 4, 12, 6, 3, 8, 9, 10, 11, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSprintBoardGolden(t *testing.T) {
+	b := goldenBoard(t)
+	checkGolden(t, "board.txt", SprintBoard(&b))
+
+	wide, err := NewBoard(4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "board_up_to.txt", sprintBoardUpTo(&wide, 1000))
+}
+
+func TestColorSchemeGolden(t *testing.T) {
+	b := goldenBoard(t)
+	flash := func(x, y int) bool { return y == 1 }
+
+	for _, c := range []ColorScheme{NoColors, PlacedColors, RowColors} {
+		t.Run(c.String(), func(t *testing.T) {
+			checkGolden(t, "board_"+c.String()+".txt", c.SprintBoard(&b))
+			checkGolden(t, "board_"+c.String()+"_flash.txt", c.SprintBoardFlash(&b, flash))
+		})
+	}
+
+	goal, err := NewGoal("spiral", b.Width(), b.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "board_rows_spiral.txt", RowColors.SprintGoalBoard(&b, goal, nil))
+}
+
+func TestSVGGolden(t *testing.T) {
+	b := goldenBoard(t)
+	checkGolden(t, "board.svg", DefaultDiagramStyle.SVG(&b, nil))
+	checkGolden(t, "board_arrow.svg", DefaultDiagramStyle.SVG(&b, &Move{Axis: VerticalAxis, Index: 2, Amount: -1}))
+}
+
+// TestPNGGolden compares the hash of the pixels of PNG diagrams, decoded again, rather than the encoded files, which may change with the version of Go.
+func TestPNGGolden(t *testing.T) {
+	b := goldenBoard(t)
+	style := DefaultDiagramStyle
+	style.Labels = false
+
+	for name, d := range map[string]struct {
+		style DiagramStyle
+		arrow *Move
+	}{
+		"board_png.sha256":           {DefaultDiagramStyle, nil},
+		"board_png_arrow.sha256":     {DefaultDiagramStyle, &Move{Axis: HorizontalAxis, Index: 1, Amount: 2}},
+		"board_png_no_labels.sha256": {style, nil},
+	} {
+		var buf bytes.Buffer
+		if err := d.style.PNG(&buf, &b, d.arrow); err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rgba := image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+		sum := sha256.Sum256(rgba.Pix)
+		checkGolden(t, name, hex.EncodeToString(sum[:])+"\n")
+	}
+}
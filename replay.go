@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* Replay file
+replay  = state-line { comment-line } { move-line [ split-line ] { comment-line } }
+
+state-line   = "state" state-code
+move-line    = move [ "@" seconds ]
+split-line   = "split" name
+comment-line = "#" text
+
+Comments belong to the position reached after the moves above them.
+Timed replays follow every move with the seconds since the first move when it was made, like "1R0 @2.350".
+Split lines mark the end of a phase after the move above them, like "split First row", replacing the phases found from the rows solved.
+*/
+
+// Replay is a scramble along with a line of moves played on it, where every position can have a comment.
+type Replay struct {
+	Scramble Board
+	Moves    []Move
+	// Comments holds the comment on every position: Comments[0] is the comment on the scramble and Comments[i] the one after the ith move.
+	Comments []string
+	// Times holds the time since the first move when every move was made, or is nil if the replay isn't timed.
+	Times []time.Duration
+	// Splits holds the name of the phase split marked after every move, empty for moves without one, or is nil if no splits were marked.
+	Splits []string
+}
+
+// Replay returns the game's scramble along with the line of moves leading to the current move and continuing through the last played branches.
+func (g *Game) Replay() *Replay {
+	r := &Replay{
+		Scramble: g.Scramble.Clone(),
+		Comments: []string{g.History.Root.Comment},
+	}
+
+	timed, split := false, false
+	for _, n := range g.History.MainLine() {
+		r.Moves = append(r.Moves, n.Move)
+		r.Comments = append(r.Comments, n.Comment)
+		r.Times = append(r.Times, n.Elapsed)
+		r.Splits = append(r.Splits, n.Split)
+		timed = timed || n.Elapsed != 0
+		split = split || n.Split != ""
+	}
+	if !timed {
+		r.Times = nil
+	}
+	if !split {
+		r.Splits = nil
+	}
+
+	return r
+}
+
+// LoadReplay replaces the board with the replay's scramble, and the history with the replay's moves and comments, ready to be redone.
+func (g *Game) LoadReplay(r *Replay) {
+	g.Board = r.Scramble.Clone()
+	g.Restart()
+
+	h := g.History
+	h.Root.Comment = r.Comments[0]
+	for i, m := range r.Moves {
+		h.Push(m)
+		h.Current.Comment = r.Comments[i+1]
+		if r.Times != nil {
+			h.Current.Elapsed = r.Times[i]
+		}
+		if r.Splits != nil {
+			h.Current.Split = r.Splits[i]
+		}
+	}
+	h.Current = h.Root
+}
+
+// WriteReplay writes a replay in the replay file format.
+func WriteReplay(w io.Writer, r *Replay) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "state %s\n", EncodeState(&r.Scramble))
+	writeComment(bw, r.Comments[0])
+	for i, m := range r.Moves {
+		if r.Times != nil {
+			fmt.Fprintf(bw, "%s @%.3f\n", m, r.Times[i].Seconds())
+		} else {
+			fmt.Fprintln(bw, m)
+		}
+		if r.Splits != nil && r.Splits[i] != "" {
+			fmt.Fprintf(bw, "split %s\n", r.Splits[i])
+		}
+		writeComment(bw, r.Comments[i+1])
+	}
+
+	return bw.Flush()
+}
+
+// writeComment writes every line of a comment as a comment line.
+func writeComment(w io.Writer, comment string) {
+	if comment == "" {
+		return
+	}
+
+	for _, l := range strings.Split(comment, "\n") {
+		fmt.Fprintf(w, "# %s\n", l)
+	}
+}
+
+// ReadReplay reads a replay in the replay file format.
+func ReadReplay(rd io.Reader) (*Replay, error) {
+	scanner := bufio.NewScanner(rd)
+
+	var r *Replay
+	timed, split := false, false
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case s == "":
+		case r == nil:
+			cmd, arg := SplitCommand(s)
+			if cmd != "state" {
+				return nil, fmt.Errorf("line %d: expected the scramble's state", line)
+			}
+
+			b, err := DecodeState(arg)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+
+			r = &Replay{Scramble: b, Comments: []string{""}}
+		case strings.HasPrefix(s, "#"):
+			c := &r.Comments[len(r.Comments)-1]
+			if *c != "" {
+				*c += "\n"
+			}
+			*c += strings.TrimSpace(s[1:])
+		case strings.HasPrefix(s, "split"):
+			_, name := SplitCommand(s)
+			if len(r.Moves) == 0 || name == "" {
+				return nil, fmt.Errorf("line %d: splits need a name and come after a move", line)
+			}
+
+			r.Splits[len(r.Splits)-1] = name
+			split = true
+		default:
+			var t time.Duration
+			if i := strings.IndexByte(s, '@'); i != -1 {
+				seconds, err := strconv.ParseFloat(strings.TrimSpace(s[i+1:]), 64)
+				if err != nil || seconds < 0 {
+					return nil, fmt.Errorf("line %d: invalid time %q", line, s[i+1:])
+				}
+				s, t = strings.TrimSpace(s[:i]), time.Duration(seconds*float64(time.Second))
+				timed = true
+			}
+
+			m, err := ParseMove(s, &r.Scramble)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+
+			r.Moves = append(r.Moves, *m)
+			r.Comments = append(r.Comments, "")
+			r.Times = append(r.Times, t)
+			r.Splits = append(r.Splits, "")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if r == nil {
+		return nil, fmt.Errorf("empty replay")
+	}
+	if !timed {
+		r.Times = nil
+	}
+	if !split {
+		r.Splits = nil
+	}
+
+	return r, nil
+}
+
+// SaveReplay writes a replay to a file.
+func SaveReplay(path string, r *Replay) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = WriteReplay(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// LoadReplayFile reads a replay from a file.
+func LoadReplayFile(path string) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := ReadReplay(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Restrictions limits which slices of the board can be moved, for constrained puzzle variants. A nil *Restrictions allows every move.
+type Restrictions struct {
+	// Axes holds the axes whose every slice can be moved.
+	Axes map[Axis]bool
+	// Slices holds the indices of the slices that can be moved, by axis.
+	Slices map[Axis]map[int]bool
+}
+
+// ParseRestrictions parses a comma separated list of what can be moved: "rows" or "columns" for every slice of an axis, and slices like "r0" or "c2".
+// Everything that is not listed can't be moved. An empty list allows every move, returning nil.
+func ParseRestrictions(input string) (*Restrictions, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, nil
+	}
+
+	r := &Restrictions{Axes: map[Axis]bool{}, Slices: map[Axis]map[int]bool{}}
+	for _, item := range strings.Split(strings.ToLower(input), ",") {
+		item = strings.TrimSpace(item)
+
+		switch item {
+		case "rows":
+			r.Axes[HorizontalAxis] = true
+			continue
+		case "columns":
+			r.Axes[VerticalAxis] = true
+			continue
+		case "":
+			return nil, fmt.Errorf("empty slice in %q", input)
+		}
+
+		a, err := ParseAxis(item[:1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid slice %q, use rows, columns, or a slice like r0 or c2", item)
+		}
+
+		i, err := strconv.Atoi(item[1:])
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("invalid index in slice %q", item)
+		}
+
+		if r.Slices[a] == nil {
+			r.Slices[a] = map[int]bool{}
+		}
+		r.Slices[a][i] = true
+	}
+
+	return r, nil
+}
+
+// Allows returns true if the move's slice can be moved.
+func (r *Restrictions) Allows(m *Move) bool {
+	return r == nil || r.Axes[m.Axis] || r.Slices[m.Axis][m.Index]
+}
+
+// Filter returns the moves that are allowed, reusing the slice.
+func (r *Restrictions) Filter(moves []Move) []Move {
+	if r == nil {
+		return moves
+	}
+
+	allowed := moves[:0]
+	for i := range moves {
+		if r.Allows(&moves[i]) {
+			allowed = append(allowed, moves[i])
+		}
+	}
+
+	return allowed
+}
+
+// String formats the restrictions the way ParseRestrictions parses them.
+func (r *Restrictions) String() string {
+	if r == nil {
+		return "rows,columns"
+	}
+
+	var items []string
+	for _, a := range []Axis{HorizontalAxis, VerticalAxis} {
+		name, prefix := "columns", "c"
+		if a == HorizontalAxis {
+			name, prefix = "rows", "r"
+		}
+
+		if r.Axes[a] {
+			items = append(items, name)
+			continue
+		}
+
+		indices := make([]int, 0, len(r.Slices[a]))
+		for i := range r.Slices[a] {
+			indices = append(indices, i)
+		}
+		sort.Ints(indices)
+
+		for _, i := range indices {
+			items = append(items, prefix+strconv.Itoa(i))
+		}
+	}
+
+	return strings.Join(items, ",")
+}
+
+// ShuffleRestricted shuffles the board like Shuffle, but only with moves the restrictions allow, so the board stays solvable under them.
+// Returns the number of moves made, which is 0 if no move is allowed.
+func (b *Board) ShuffleRestricted(r *Restrictions, iterations int) int {
+	if iterations <= 0 {
+		iterations = DefaultShuffleIterations(b.Width(), b.Height())
+	}
+
+	moves := r.Filter(AllMoves(b))
+	if len(moves) == 0 {
+		return 0
+	}
+
+	for i := 0; i < iterations; i++ {
+		b.MakeMove(&moves[rng.Intn(len(moves))])
+	}
+
+	return iterations
+}
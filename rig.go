@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rig streams the moves made in a game to a physical Loopover build, like a motorized one, so that it mirrors the board on screen. A nil *Rig sends nothing.
+// Commands are lines of text, which the rig answers with a line starting with "ok" once it is done, or with "err" followed by a reason:
+//
+//	size W H     sent once connected, with the size of the board
+//	state CODE   the board was replaced, like by a shuffle, with its state code. Rigs that can't arrange it themselves should wait for it to be arranged by hand
+//	move A I N   shift slice I of axis A, r for rows or c for columns, by N: positive amounts shift rows right and columns down
+//
+// Commands are queued so that the game doesn't wait for the rig, and sent one at a time: the next one is only sent once the last one is answered, and at least Pace after it was sent.
+type Rig struct {
+	conn io.ReadWriteCloser
+	// Pace is the least time between two commands, to give the rig time to settle.
+	Pace time.Duration
+	// Timeout is how long the rig has to answer a command. A rig that doesn't answer in time is given up on.
+	Timeout time.Duration
+
+	queue   chan string
+	answers chan string
+	done    chan struct{}
+	mu      sync.Mutex
+	err     error
+}
+
+// rigQueue is how many commands can wait to be sent before the rig is considered to have fallen behind.
+const rigQueue = 4096
+
+// DialRig connects to a rig at `addr`, either a TCP address like "rig.local:9000", or a serial port like "/dev/ttyUSB0" or "/dev/ttyUSB0@115200" to set its baud rate,
+// and tells it the size of the board.
+func DialRig(addr string, width, height int) (*Rig, error) {
+	var conn io.ReadWriteCloser
+	var err error
+	if strings.HasPrefix(addr, "/") {
+		conn, err = openSerial(addr)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, 10*time.Second)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Rig{
+		conn:    conn,
+		Timeout: 10 * time.Second,
+		queue:   make(chan string, rigQueue),
+		answers: make(chan string),
+		done:    make(chan struct{}),
+	}
+	go r.read()
+	go r.send()
+
+	r.enqueue(fmt.Sprintf("size %d %d", width, height))
+	return r, nil
+}
+
+// openSerial opens a serial port like "/dev/ttyUSB0@115200", setting it to raw mode and the baud rate if there is one, using stty.
+func openSerial(addr string) (*os.File, error) {
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("serial ports are not available on %s, use a TCP bridge instead", runtime.GOOS)
+	}
+
+	path, baud := addr, ""
+	if i := strings.LastIndexByte(addr, '@'); i != -1 {
+		path, baud = addr[:i], addr[i+1:]
+		if _, err := strconv.Atoi(baud); err != nil {
+			return nil, fmt.Errorf("invalid baud rate %q", baud)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"raw", "-echo"}
+	if baud != "" {
+		args = append(args, baud)
+	}
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	if out, err := cmd.CombinedOutput(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not set up %s (%s %s)", path, err, strings.TrimSpace(string(out)))
+	}
+
+	return f, nil
+}
+
+// read reads the answers of the rig, one per line, until the connection is closed.
+func (r *Rig) read() {
+	sc := bufio.NewScanner(r.conn)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+
+		select {
+		case r.answers <- line:
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// send sends the queued commands one at a time, waiting for every one to be answered.
+func (r *Rig) send() {
+	var last time.Time
+	for cmd := range r.queue {
+		if r.Err() != nil {
+			continue
+		}
+		if wait := r.Pace - time.Since(last); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		last = time.Now()
+		if _, err := io.WriteString(r.conn, cmd+"\n"); err != nil {
+			r.fail(fmt.Errorf("could not send %q to the rig (%s)", cmd, err))
+			continue
+		}
+
+		select {
+		case answer := <-r.answers:
+			word, reason := SplitCommand(answer)
+			switch strings.ToLower(word) {
+			case "ok":
+				logger.Debug("rig", "command", cmd, "took", time.Since(last))
+			case "err":
+				logger.Warn("the rig refused a command", "command", cmd, "reason", reason)
+			default:
+				r.fail(fmt.Errorf("unexpected answer %q from the rig to %q", answer, cmd))
+			}
+		case <-time.After(r.Timeout):
+			r.fail(fmt.Errorf("the rig didn't answer %q within %s", cmd, r.Timeout))
+		}
+	}
+
+	close(r.done)
+}
+
+// fail gives up on the rig, which no longer mirrors the board.
+func (r *Rig) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = err
+		logger.Warn("stopped mirroring the board on the rig", "err", err)
+	}
+}
+
+// Err returns why the rig was given up on, or nil if it still mirrors the board.
+func (r *Rig) Err() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// enqueue queues a command to be sent, giving up on the rig if it fell too far behind.
+func (r *Rig) enqueue(cmd string) {
+	if r == nil || r.Err() != nil {
+		return
+	}
+
+	select {
+	case r.queue <- cmd:
+	default:
+		r.fail(fmt.Errorf("the rig fell more than %d commands behind", rigQueue))
+	}
+}
+
+// Move queues a move made on the board.
+func (r *Rig) Move(m *Move) {
+	axis := "r"
+	if m.Axis == VerticalAxis {
+		axis = "c"
+	}
+
+	r.enqueue(fmt.Sprintf("move %s %d %d", axis, m.Index, m.Amount))
+}
+
+// State queues the state of a board that replaced the one the rig mirrors.
+func (r *Rig) State(b *Board) {
+	r.enqueue("state " + EncodeState(b))
+}
+
+// Close waits for the queued commands to be sent, for up to `wait`, and closes the connection.
+func (r *Rig) Close(wait time.Duration) error {
+	if r == nil {
+		return nil
+	}
+
+	close(r.queue)
+	select {
+	case <-r.done:
+	case <-time.After(wait):
+	}
+
+	return r.conn.Close()
+}
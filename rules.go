@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlusTwo is the time a +2 penalty adds to a solve.
+const PlusTwo = 2 * time.Second
+
+// InspectionGrace is how long past the inspection time a solve can start with a +2. Starting later is a DNF.
+const InspectionGrace = 2 * time.Second
+
+// Penalty is the penalty a solve got under competition rules.
+type Penalty struct {
+	// PlusTwos is how many +2 penalties were given.
+	PlusTwos int
+	// DNF is true if the solve did not finish, because it was given up or started too late. DNFs have no time.
+	DNF bool
+}
+
+// String formats the penalty like "+2", "+4" or "DNF", and no penalty as an empty string.
+func (p Penalty) String() string {
+	switch {
+	case p.DNF:
+		return "DNF"
+	case p.PlusTwos > 0:
+		return fmt.Sprintf("+%d", 2*p.PlusTwos)
+	default:
+		return ""
+	}
+}
+
+// ParsePenalty parses a penalty as returned by Penalty.String.
+func ParsePenalty(s string) (Penalty, error) {
+	switch {
+	case s == "":
+		return Penalty{}, nil
+	case strings.EqualFold(s, "DNF"):
+		return Penalty{DNF: true}, nil
+	case strings.HasPrefix(s, "+"):
+		n, err := strconv.Atoi(s[1:])
+		if err == nil && n > 0 && n%2 == 0 {
+			return Penalty{PlusTwos: n / 2}, nil
+		}
+	}
+
+	return Penalty{}, fmt.Errorf("invalid penalty %q, expected +2, +4 and so on, or DNF", s)
+}
+
+// Add returns the time of a solve that took `d` with the penalty added.
+func (p Penalty) Add(d time.Duration) time.Duration {
+	return d + time.Duration(p.PlusTwos)*PlusTwo
+}
+
+// InspectionPenalty returns the penalty for starting a solve after inspecting for `took` when `limit` is allowed: a +2 if inspection went over time,
+// and a DNF if it went over by more than InspectionGrace.
+func InspectionPenalty(took, limit time.Duration) Penalty {
+	switch over := took - limit; {
+	case over > InspectionGrace:
+		return Penalty{DNF: true}
+	case over > 0:
+		return Penalty{PlusTwos: 1}
+	default:
+		return Penalty{}
+	}
+}
+
+// startInspection starts inspecting the scramble, if the game is played with inspection and the board isn't already solved.
+func (g *Game) startInspection() {
+	g.inspectionStart = time.Time{}
+	if g.Inspection > 0 && !g.Board.IsSolved() {
+		g.inspectionStart = time.Now()
+	}
+}
+
+// Inspecting returns true if the scramble is being inspected, and the solve hasn't started yet.
+func (g *Game) Inspecting() bool {
+	return !g.inspectionStart.IsZero()
+}
+
+// InspectionElapsed returns the time since inspection started.
+func (g *Game) InspectionElapsed() time.Duration {
+	if !g.Inspecting() {
+		return 0
+	}
+
+	return time.Since(g.inspectionStart)
+}
+
+// StartSolve ends inspection and starts the timer, with the penalty of InspectionPenalty.
+// Returns how long inspection took.
+func (g *Game) StartSolve() time.Duration {
+	took := g.InspectionElapsed()
+	p := InspectionPenalty(took, g.Inspection)
+	g.Penalty.PlusTwos += p.PlusTwos
+	g.Penalty.DNF = g.Penalty.DNF || p.DNF
+	logger.Debug("solve started", "inspection", took, "penalty", g.Penalty)
+
+	g.inspectionStart = time.Time{}
+	g.Start = time.Now()
+	return took
+}
+
+// GiveUp ends the solve in progress as a DNF. Returns false if there is no solve in progress.
+func (g *Game) GiveUp() bool {
+	if g.Board.IsSolved() || g.Start.IsZero() && !g.Inspecting() {
+		return false
+	}
+
+	g.inspectionStart = time.Time{}
+	g.Penalty.DNF = true
+	return true
+}
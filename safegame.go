@@ -0,0 +1,63 @@
+package main
+
+import (
+	"sync"
+)
+
+// SafeGame guards a Game with a mutex, so that the input loop, tickers and observers can share it between goroutines.
+// It implements sync.Locker, for code that needs to make several changes to the game at once.
+type SafeGame struct {
+	mu sync.Mutex
+	g  *Game
+}
+
+// NewSafeGame wraps a game. The game must not be touched without going through the wrapper from then on.
+func NewSafeGame(g *Game) *SafeGame {
+	return &SafeGame{g: g}
+}
+
+// Lock locks the game for exclusive access.
+func (s *SafeGame) Lock() {
+	s.mu.Lock()
+}
+
+// Unlock unlocks the game.
+func (s *SafeGame) Unlock() {
+	s.mu.Unlock()
+}
+
+// Do runs `f` with exclusive access to the game. The game must not be kept after `f` returns.
+func (s *SafeGame) Do(f func(g *Game)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f(s.g)
+}
+
+// MakeMove makes a move if the game's restrictions and variant allow it, returning how many single shifts it took.
+func (s *SafeGame) MakeMove(m *Move) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.g.CheckMove(m); err != nil {
+		return 0, err
+	}
+
+	return s.g.MakeMove(m), nil
+}
+
+// Board returns a copy of the board.
+func (s *SafeGame) Board() Board {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.g.Board.Clone()
+}
+
+// Progress returns how far along the game is.
+func (s *SafeGame) Progress() Progress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.g.Progress()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSafeGameConcurrent drives a survival game from several goroutines at once, like the input loop, tickers and observers do.
+// Run it with go test -race, which fails it if the game is touched without holding the lock.
+func TestSafeGameConcurrent(t *testing.T) {
+	g, err := NewGame(5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// observers are told about moves of both the players and survival.
+	var solved int
+	g.Observers = append(g.Observers, ObserverFunc(func(g *Game, a Axis, index int) {
+		solved++
+	}))
+
+	sg := NewSafeGame(g)
+	var injected int
+	sg.Lock()
+	err = g.StartSurvival(time.Millisecond, sg, func(m Move, lost bool) {
+		injected++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// chaos must not win before the test is over.
+	g.Survival.Limit = 1 << 30
+	sg.Unlock()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; time.Since(start) < 50*time.Millisecond; j++ {
+				m := Move{Axis: Axis(j % 2), Index: (i + j) % 5, Amount: 1 - 2*(j%3%2)}
+				if _, err := sg.MakeMove(&m); err != nil {
+					t.Error(err)
+					return
+				}
+
+				b := sg.Board()
+				if b.Width() != 5 || b.Height() != 5 {
+					t.Errorf("board is %dx%d", b.Width(), b.Height())
+				}
+				sg.Progress()
+				sg.Do(func(g *Game) {
+					g.Undo()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	sg.Do(func(g *Game) {
+		g.StopSurvival()
+		if injected == 0 {
+			t.Error("survival made no move")
+		}
+		if !g.Board.IsSolvable() {
+			t.Error("the board can't be solved anymore")
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// TilePoints is how many points placing a tile is worth, before the combo multiplier.
+var TilePoints = 100
+
+// UndoPenalty is how many points every undo costs.
+var UndoPenalty = 50
+
+// MaxCombo is the largest combo multiplier.
+var MaxCombo = 5
+
+// TimeBonus is how many points every second under par is worth when the board is solved. Par is 2 seconds per tile.
+var TimeBonus = 10
+
+// Score keeps the score of a game in scoring mode.
+type Score struct {
+	Points int
+	// Combo is how many moves in a row placed new tiles, which multiplies the points of the next placement.
+	Combo int
+	// Best is the most tiles that were placed at once so far. Only placing tiles beyond it scores, so moving tiles out and back in doesn't.
+	Best     int
+	Undos    int
+	Finished bool
+}
+
+// NewScore creates the score of a game that starts with `placed` tiles in place.
+func NewScore(placed int) *Score {
+	return &Score{Best: placed}
+}
+
+// Move scores a move that left `placed` tiles in place, returning the points it earned.
+func (s *Score) Move(placed int) int {
+	if s.Finished {
+		return 0
+	}
+
+	if placed <= s.Best {
+		s.Combo = 0
+		return 0
+	}
+
+	if s.Combo < MaxCombo {
+		s.Combo++
+	}
+
+	points := (placed - s.Best) * TilePoints * s.Combo
+	s.Points += points
+	s.Best = placed
+
+	return points
+}
+
+// Undo takes the undo penalty and breaks the combo.
+func (s *Score) Undo() {
+	if s.Finished {
+		return
+	}
+
+	s.Points -= UndoPenalty
+	s.Undos++
+	s.Combo = 0
+}
+
+// Finish adds the time bonus of a width*height board solved in `elapsed`, returning it.
+func (s *Score) Finish(width, height int, elapsed time.Duration) int {
+	if s.Finished {
+		return 0
+	}
+	s.Finished = true
+
+	par := time.Duration(2*width*height) * time.Second
+	if elapsed >= par {
+		return 0
+	}
+
+	bonus := int((par - elapsed).Seconds()) * TimeBonus
+	s.Points += bonus
+
+	return bonus
+}
+
+// String formats the score as a status line.
+func (s *Score) String() string {
+	if s.Combo > 1 {
+		return fmt.Sprintf("Score: %d (combo x%d)", s.Points, s.Combo)
+	}
+
+	return fmt.Sprintf("Score: %d", s.Points)
+}
+
+// FinishScore ends the game's scoring after the board was solved, recording the score in the profile store under `player`.
+// Returns a description of the final score.
+func (g *Game) FinishScore(player string) (string, error) {
+	bonus := g.Score.Finish(g.Board.Width(), g.Board.Height(), g.Elapsed())
+	s := fmt.Sprintf("Final score: %d (time bonus %d, %d undos)", g.Score.Points, bonus, g.Score.Undos)
+
+	p, err := LoadProfiles()
+	if err != nil {
+		return s, err
+	}
+
+	pr := p.Get(player)
+	pr.ScoredGames++
+	if g.Score.Points > pr.BestScore {
+		pr.BestScore = g.Score.Points
+		s += ", a new best"
+	}
+
+	return s, p.Save()
+}
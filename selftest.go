@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "selftest",
+		Summary: "solve many shuffled boards with the engine and check every solution",
+		Run:     runSelftest,
+	})
+}
+
+// SelftestResult holds how the engine did on the boards of one size during a selftest.
+type SelftestResult struct {
+	Width, Height int
+	Boards        int
+	// Moves is the total and MaxMoves the longest of the engine's solutions, in single shifts.
+	Moves, MaxMoves int
+	// Failures holds the state code of every scramble the engine failed to solve, along with why.
+	Failures []string
+}
+
+// Selftest arranges `boards` boards with the given dimensions uniformly at random, solves each with SolveHuman and checks that making the solution's moves solves the board.
+func Selftest(width, height, boards int) (*SelftestResult, error) {
+	b, err := NewBoard(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SelftestResult{Width: width, Height: height, Boards: boards}
+	for i := 0; i < boards; i++ {
+		b.uniformShuffle()
+		state := EncodeState(&b)
+
+		sol, err := SolveHuman(&b)
+		if err == nil {
+			err = CheckSolution(&b, sol.Moves)
+		}
+		if err != nil {
+			res.Failures = append(res.Failures, fmt.Sprintf("%s: %s", state, err))
+			continue
+		}
+
+		n := MovesLength(sol.Moves)
+		res.Moves += n
+		if n > res.MaxMoves {
+			res.MaxMoves = n
+		}
+	}
+
+	return res, nil
+}
+
+// CheckSolution returns an error if making the moves doesn't solve the board.
+func CheckSolution(b *Board, moves []Move) error {
+	c := b.Clone()
+	if _, err := c.ApplyMoves(moves); err != nil {
+		return err
+	}
+	if !c.IsSolved() {
+		return fmt.Errorf("solution leaves the board at %s", EncodeState(&c))
+	}
+
+	return nil
+}
+
+// runSelftest runs the selftest command.
+func runSelftest(args []string) error {
+	fs := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	sizes := fs.String("sizes", "2x2,2x3,3x2,3x3,3x4,4x4,4x5,5x5,6x6,10x10", "comma separated list of board sizes")
+	boards := fs.Int("boards", 1000, "number of boards of every size")
+	seed := fs.Int64("seed", 0, "seed of the shuffles, to repeat a selftest, or 0 to pick one")
+	prof := AddProfilingFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	defer prof.Stop()
+	if err := prof.Start(); err != nil {
+		return err
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rng = rand.New(rand.NewSource(*seed))
+	fmt.Printf("Seed %d\n", *seed)
+
+	var failures int
+	for _, size := range strings.Split(*sizes, ",") {
+		w, h, err := ParseTwoDimensions(strings.TrimSpace(size))
+		if err != nil {
+			return err
+		}
+
+		res, err := Selftest(w, h, *boards)
+		if err != nil {
+			return err
+		}
+
+		solved := res.Boards - len(res.Failures)
+		mean := 0.0
+		if solved != 0 {
+			mean = float64(res.Moves) / float64(solved)
+		}
+		fmt.Printf("%5s: %d/%d solved, %.1f moves on average, %d at most\n", size, solved, res.Boards, mean, res.MaxMoves)
+
+		for _, f := range res.Failures {
+			fmt.Printf("  %s\n", f)
+		}
+		failures += len(res.Failures)
+	}
+
+	if failures != 0 {
+		return fmt.Errorf("the engine failed to solve %d boards", failures)
+	}
+	return nil
+}
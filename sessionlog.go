@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// LogEvent is an event of a session, written as a line of JSON to a session log.
+type LogEvent struct {
+	Time time.Time `json:"time"`
+	// Type is one of "command", "move", "scramble" or "solve".
+	Type string `json:"type"`
+
+	// Command and Arg are the command and its argument, for command events.
+	Command string `json:"command,omitempty"`
+	Arg     string `json:"arg,omitempty"`
+	// Move is the move made, in Programmer's Notation, for move events.
+	Move string `json:"move,omitempty"`
+	// State is the state code of the board after the event.
+	State string `json:"state,omitempty"`
+	// Moves and Elapsed are how many moves were made and how many seconds passed since the first move after the last scramble, for every event but scrambles.
+	Moves   int     `json:"moves,omitempty"`
+	Elapsed float64 `json:"elapsed,omitempty"`
+}
+
+// SessionLog appends the events of a session to a JSONL file, for analytics and coaching tools to mine. A nil *SessionLog logs nothing.
+type SessionLog struct {
+	f   *os.File
+	enc *json.Encoder
+}
+
+// OpenSessionLog opens a session log, appending to the file if it already exists.
+func OpenSessionLog(path string) (*SessionLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionLog{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Log writes an event, setting its time to now.
+func (l *SessionLog) Log(e LogEvent) error {
+	if l == nil {
+		return nil
+	}
+
+	e.Time = time.Now()
+	return l.enc.Encode(e)
+}
+
+// Close closes the log file.
+func (l *SessionLog) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	return l.f.Close()
+}
+
+// logEvent logs an event about the game's board to the game's session log, filling in the board's state, the moves and the elapsed time.
+func (g *Game) logEvent(e LogEvent) {
+	if g.Log == nil {
+		return
+	}
+
+	e.State = EncodeState(&g.Board)
+	if e.Type != "scramble" {
+		e.Moves = g.Moves
+		e.Elapsed = g.Elapsed().Seconds()
+	}
+
+	if err := g.Log.Log(e); err != nil {
+		// a broken log shouldn't get in the way of playing, so stop logging instead.
+		g.Log = nil
+	}
+}
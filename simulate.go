@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "simulate",
+		Summary: "solve many random scrambles with a solver and report the lengths and times of its solutions",
+		Run:     runSimulate,
+	})
+}
+
+// Simulation holds how a solver did on many random scrambles of one size.
+type Simulation struct {
+	Width, Height int
+	Games         int
+	// Moves and Times are the length in single shifts and the solving time of every solution, in the order the scrambles were solved.
+	Moves []int
+	Times []time.Duration
+	// Failures holds the state code of every scramble the solver failed to solve, along with why.
+	Failures []string
+}
+
+// Simulate arranges `games` boards with the given dimensions uniformly at random, solves each with the solver and checks that the solution solves the board.
+func Simulate(width, height, games int, s Solver) (*Simulation, error) {
+	b, err := NewBoard(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := &Simulation{Width: width, Height: height, Games: games}
+	for i := 0; i < games; i++ {
+		b.uniformShuffle()
+
+		moves, took, err := runSolver(s, &b)
+		if err != nil {
+			sim.Failures = append(sim.Failures, fmt.Sprintf("%s: %s", EncodeState(&b), err))
+			continue
+		}
+
+		sim.Moves = append(sim.Moves, moves)
+		sim.Times = append(sim.Times, took)
+	}
+
+	return sim, nil
+}
+
+// runSolver solves the board with the solver and checks the solution, returning its length in single shifts and how long the solver took.
+func runSolver(s Solver, b *Board) (int, time.Duration, error) {
+	start := time.Now()
+	res, err := s(b)
+	took := time.Since(start)
+	if err == nil {
+		err = CheckSolution(b, res.Moves)
+	}
+	if err != nil {
+		return 0, took, err
+	}
+
+	return MovesLength(res.Moves), took, nil
+}
+
+// percentile returns the value below which `p` percent of the sorted values are, by the nearest rank.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// Distribution summarizes values by their mean and percentiles.
+type Distribution struct {
+	N                          int
+	Mean, Std                  float64
+	Min, Median, P90, P99, Max float64
+}
+
+// Summarize returns the distribution of the values.
+func Summarize(values []float64) Distribution {
+	d := Distribution{N: len(values)}
+	if d.N == 0 {
+		return d
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	for _, v := range sorted {
+		d.Mean += v
+	}
+	d.Mean /= float64(d.N)
+	for _, v := range sorted {
+		d.Std += (v - d.Mean) * (v - d.Mean)
+	}
+	d.Std = math.Sqrt(d.Std / float64(d.N))
+
+	d.Min, d.Max = sorted[0], sorted[d.N-1]
+	d.Median, d.P90, d.P99 = percentile(sorted, 50), percentile(sorted, 90), percentile(sorted, 99)
+	return d
+}
+
+// MoveDistribution returns the distribution of the lengths of the solutions.
+func (s *Simulation) MoveDistribution() Distribution {
+	values := make([]float64, len(s.Moves))
+	for i, m := range s.Moves {
+		values[i] = float64(m)
+	}
+
+	return Summarize(values)
+}
+
+// TimeDistribution returns the distribution of the solving times, in milliseconds.
+func (s *Simulation) TimeDistribution() Distribution {
+	values := make([]float64, len(s.Times))
+	for i, t := range s.Times {
+		values[i] = float64(t) / float64(time.Millisecond)
+	}
+
+	return Summarize(values)
+}
+
+// Histogram counts how many solutions fall in each of `buckets` equal ranges of lengths, from the shortest to the longest.
+func (s *Simulation) Histogram(buckets int) []float64 {
+	d := s.MoveDistribution()
+	counts := make([]float64, buckets)
+	if d.N == 0 {
+		return counts
+	}
+
+	span := d.Max - d.Min + 1
+	for _, m := range s.Moves {
+		counts[int((float64(m)-d.Min)/span*float64(buckets))]++
+	}
+
+	return counts
+}
+
+// runSimulate runs the simulate command.
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	games := fs.Int("games", 1000, "number of scrambles to solve")
+	size := fs.String("size", "4x4", "board size")
+	solverName := fs.String("solver", "human", "solver to evaluate: human solves row by row like the engine, and short also shortens its solutions like insertions, in parts of up to 7 single shifts or of up to N with short:N")
+	seed := fs.Int64("seed", 0, "seed of the scrambles, to repeat a simulation, or 0 to pick one")
+	prof := AddProfilingFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: simulate [-games 1000] [-size 4x4] [-solver human] [-seed N]")
+		fmt.Fprintln(fs.Output(), "Solves many scrambles picked uniformly at random and reports the distribution of the lengths of the solutions, the percentiles of the solving times and the failures,")
+		fmt.Fprintln(fs.Output(), "to evaluate changes to a solver.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	defer prof.Stop()
+	if err := prof.Start(); err != nil {
+		return err
+	}
+	if *games <= 0 {
+		return fmt.Errorf("the number of games must be at least 1")
+	}
+
+	w, h, err := ParseTwoDimensions(*size)
+	if err != nil {
+		return err
+	}
+	s, err := ParseSolver(*solverName)
+	if err != nil {
+		return err
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rng = rand.New(rand.NewSource(*seed))
+	fmt.Printf("Seed %d\n", *seed)
+
+	sim, err := Simulate(w, h, *games, s)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d %s scrambles solved with %s: %d solved, %d failed\n", sim.Games, *size, strings.ToLower(*solverName), len(sim.Moves), len(sim.Failures))
+	if len(sim.Moves) != 0 {
+		m, t := sim.MoveDistribution(), sim.TimeDistribution()
+		fmt.Printf("Moves: %.1f on average (std %.1f), min %.0f, median %.0f, p90 %.0f, p99 %.0f, max %.0f\n", m.Mean, m.Std, m.Min, m.Median, m.P90, m.P99, m.Max)
+		fmt.Printf("Distribution from %.0f to %.0f moves: %s\n", m.Min, m.Max, Sparkline(sim.Histogram(20)))
+		fmt.Printf("Time: %.3fms on average, median %.3fms, p90 %.3fms, p99 %.3fms, max %.3fms\n", t.Mean, t.Median, t.P90, t.P99, t.Max)
+	}
+
+	for i, f := range sim.Failures {
+		if i == 10 {
+			fmt.Printf("  and %d more\n", len(sim.Failures)-i)
+			break
+		}
+		fmt.Printf("  %s\n", f)
+	}
+
+	if len(sim.Failures) != 0 {
+		return fmt.Errorf("the solver failed to solve %d scrambles", len(sim.Failures))
+	}
+	return nil
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SolveResult is a solution found by a solver, along with statistics on how it was found.
+type SolveResult struct {
+	Moves []Move
+	// Nodes is how many positions the solver looked at.
+	Nodes int
+	// Depth is how many single shifts deep the solver searched, for solvers that search.
+	Depth int
+	// TableProbes is how many times the solver looked a position up in a table, and TableHits how many of those found it.
+	TableProbes, TableHits int
+	// Phases holds the statistics of every phase of the solve, for solvers that solve in phases.
+	Phases []SolvePhase
+	// Time is how long the whole solve took.
+	Time time.Duration
+}
+
+// SolvePhase holds the statistics of a phase of a solve.
+type SolvePhase struct {
+	Name string
+	// Moves is how many single shifts the phase added to the solution.
+	Moves int
+	Time  time.Duration
+}
+
+// HitRate returns the fraction of table probes that found the position, or 0 if there were none.
+func (r *SolveResult) HitRate() float64 {
+	if r.TableProbes == 0 {
+		return 0
+	}
+
+	return float64(r.TableHits) / float64(r.TableProbes)
+}
+
+// String formats the statistics of the solve, one per line, leaving out the ones that don't apply to the solver.
+func (r *SolveResult) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d moves in %s, %d nodes", MovesLength(r.Moves), r.Time, r.Nodes)
+	if r.Depth != 0 {
+		fmt.Fprintf(&sb, ", depth %d", r.Depth)
+	}
+	if r.TableProbes != 0 {
+		fmt.Fprintf(&sb, ", %d/%d table hits (%.1f%%)", r.TableHits, r.TableProbes, 100*r.HitRate())
+	}
+
+	for _, p := range r.Phases {
+		fmt.Fprintf(&sb, "\n  %-12s %4d moves in %s", p.Name, p.Moves, p.Time)
+	}
+
+	return sb.String()
+}
+
+// SolveHuman returns a sequence of moves that solves the board, found the way a person would solve it: row by row from the top, placing one tile at a time,
+// and then the last row with 3-cycles made out of commutators. Solutions are far from optimal but are found quickly on boards of any size.
+// Returns an error if the board can't be solved.
+func SolveHuman(b *Board) (*SolveResult, error) {
+	if !b.IsSolvable() {
+		return nil, fmt.Errorf("board can't be solved")
+	}
+
+	start := time.Now()
+	s := &humanSolver{board: b.Clone()}
+	h := s.board.Height()
+
+	s.phase("Parity", s.fixParity)
+	for y := 0; y < h-1; y++ {
+		s.phase(rowsPhaseName(y, y+1, h), func() {
+			for x := 0; x < s.board.Width(); x++ {
+				s.placeTile(x, y)
+			}
+		})
+	}
+	s.phase(rowsPhaseName(h-1, h, h), s.solveLastRow)
+
+	if !s.board.IsSolved() {
+		return nil, fmt.Errorf("solver failed to solve %s", EncodeState(b))
+	}
+
+	return &SolveResult{Moves: s.moves, Nodes: s.nodes, Phases: s.phases, Time: time.Since(start)}, nil
+}
+
+// humanSolver holds the state of SolveHuman.
+type humanSolver struct {
+	board  Board
+	moves  []Move
+	nodes  int
+	phases []SolvePhase
+
+	// scratch and cycle are reused by lastRowCycle to try out sequences without allocating for every one.
+	scratch Board
+	cycle   []Move
+}
+
+// phase runs a phase of the solve, recording its statistics.
+func (s *humanSolver) phase(name string, solve func()) {
+	start, moves := time.Now(), MovesLength(s.moves)
+	solve()
+
+	p := SolvePhase{Name: name, Moves: MovesLength(s.moves) - moves, Time: time.Since(start)}
+	s.phases = append(s.phases, p)
+	logger.Debug("solver phase", "phase", p.Name, "moves", p.Moves, "time", p.Time)
+}
+
+// move makes a move on the solver's board and records it, merging it with the previous move if both shift the same slice.
+func (s *humanSolver) move(a Axis, index, amount int) {
+	m := s.board.NormalizeMove(Move{Axis: a, Index: index, Amount: amount})
+	if m.Amount == 0 {
+		return
+	}
+	s.board.MakeMove(&m)
+	s.nodes++
+
+	if n := len(s.moves); n != 0 && s.moves[n-1].Axis == a && s.moves[n-1].Index == index {
+		last := s.board.NormalizeMove(Move{Axis: a, Index: index, Amount: s.moves[n-1].Amount + m.Amount})
+		if last.Amount == 0 {
+			s.moves = s.moves[:n-1]
+		} else {
+			s.moves[n-1] = last
+		}
+		return
+	}
+
+	s.moves = append(s.moves, m)
+}
+
+// find returns the coordinate of the tile with value `v`.
+func (s *humanSolver) find(v int) (int, int) {
+	for x := range s.board {
+		for y := range s.board[x] {
+			if s.board[x][y] == v {
+				return x, y
+			}
+		}
+	}
+
+	panic(fmt.Sprintf("tile %d not on the board", v))
+}
+
+// fixParity makes a column move if the board's permutation is odd and its width is odd but its height is even.
+// Every move used for the rows above the last one is then an even permutation, which leaves the last row with an even permutation that 3-cycles can solve.
+// When the width is even, the last row is fixed with a single shift instead, and when both dimensions are odd the permutation is already even.
+func (s *humanSolver) fixParity() {
+	if s.board.Width()%2 == 1 && s.board.Height()%2 == 0 && PermutationParity(s.board.Tiles()) == 1 {
+		s.move(VerticalAxis, 0, 1)
+	}
+}
+
+// placeTile brings the tile that belongs at (tx, ty) there, without disturbing the rows above or the tiles to the left in the same row.
+// The row below is used as scratch space, so this only works for rows above the last one.
+func (s *humanSolver) placeTile(tx, ty int) {
+	w := s.board.Width()
+	x, y := s.find(s.board.defaultTileValue(tx, ty))
+
+	if x == tx && y == ty {
+		return
+	}
+
+	// bring the tile down into the row below, shifting it out of its column so the column can go back.
+	if y != ty+1 {
+		k := ty + 1 - y
+		if y == ty {
+			k = 1
+		}
+
+		s.move(VerticalAxis, x, k)
+		s.move(HorizontalAxis, ty+1, 1)
+		s.move(VerticalAxis, x, -k)
+		x = (x + 1) % w
+	}
+
+	if x == tx {
+		s.move(HorizontalAxis, ty+1, 1)
+		x = (x + 1) % w
+	}
+
+	// insert it by moving the target column down, lining the tile up under the target and moving the column back up.
+	s.move(VerticalAxis, tx, 1)
+	s.move(HorizontalAxis, ty+1, tx-x)
+	s.move(VerticalAxis, tx, -1)
+}
+
+// solveLastRow solves the last row once every other row is solved, using 3-cycles of the last row's tiles.
+func (s *humanSolver) solveLastRow() {
+	w, l := s.board.Width(), s.board.Height()-1
+
+	if PermutationParity(s.board.Tiles()) == 1 {
+		s.move(HorizontalAxis, l, 1)
+	}
+
+	for tx := 0; tx < w-2; tx++ {
+		x, _ := s.find(s.board.defaultTileValue(tx, l))
+		if x == tx {
+			continue
+		}
+
+		q := tx + 1
+		if q == x {
+			q++
+		}
+
+		for _, m := range s.lastRowCycle(tx, x, q) {
+			s.move(m.Axis, m.Index, m.Amount)
+		}
+	}
+}
+
+// lastRowCycle returns a sequence of moves that 3-cycles the tiles at columns `a`, `b` and `c` of the last row, moving the tile at `b` to `a`, without disturbing any other tile.
+// Tile `c` is moved into the row above, under `a`, so that the three form an L and can be cycled with a commutator of a row and a column move, and then moved back.
+// The sequence is reused by the next call.
+func (s *humanSolver) lastRowCycle(a, b, c int) []Move {
+	w, h := s.board.Width(), s.board.Height()
+	l := h - 1
+
+	setup := [2]Move{
+		{Axis: VerticalAxis, Index: c, Amount: -1},
+		{Axis: HorizontalAxis, Index: l - 1, Amount: a - c},
+	}
+	undo := [2]Move{
+		{Axis: HorizontalAxis, Index: l - 1, Amount: c - a},
+		{Axis: VerticalAxis, Index: c, Amount: 1},
+	}
+	if s.scratch == nil {
+		s.scratch, _ = NewBoard(w, h)
+	}
+
+	for _, col := range []int{1, -1} {
+		for _, row := range []int{b - a, a - b} {
+			x := Move{Axis: VerticalAxis, Index: a, Amount: col}
+			y := Move{Axis: HorizontalAxis, Index: l, Amount: row}
+			xi := Move{Axis: VerticalAxis, Index: a, Amount: -col}
+			yi := Move{Axis: HorizontalAxis, Index: l, Amount: -row}
+
+			// both orders of the commutator cycle the same tiles, but in opposite directions.
+			for _, comm := range [2][4]Move{{x, y, xi, yi}, {y, x, yi, xi}} {
+				s.cycle = append(append(append(s.cycle[:0], setup[:]...), comm[:]...), undo[:]...)
+				s.nodes++
+				if isLastRowCycle(&s.scratch, s.cycle, a, b, c) {
+					return s.cycle
+				}
+			}
+		}
+	}
+
+	panic(fmt.Sprintf("no commutator cycles columns %d, %d and %d", a, b, c))
+}
+
+// isLastRowCycle returns true if `seq` moves the tile at column `b` of the last row to `a`, and only touches the tiles at columns `a`, `b` and `c` of it.
+// It is tried out on `t`, which is reset first.
+func isLastRowCycle(t *Board, seq []Move, a, b, c int) bool {
+	t.Reset()
+	t.ApplyMovesUnchecked(seq)
+	w, h := t.Width(), t.Height()
+
+	l := h - 1
+	if (*t)[a][l] != t.defaultTileValue(b, l) {
+		return false
+	}
+
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if y == l && (x == a || x == b || x == c) {
+				continue
+			}
+			if (*t)[x][y] != t.defaultTileValue(x, y) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// InverseMoves returns the sequence of moves that undoes `seq`.
+func InverseMoves(seq []Move) []Move {
+	inv := make([]Move, len(seq))
+	for i, m := range seq {
+		m.Amount = -m.Amount
+		inv[len(seq)-1-i] = m
+	}
+
+	return inv
+}
+
+// MovesLength returns how many single shifts a sequence of moves makes, the way moves are counted while playing.
+func MovesLength(seq []Move) int {
+	var n int
+	for _, m := range seq {
+		n += Abs(m.Amount)
+	}
+
+	return n
+}
+
+// LowerBound returns a number of moves that solving the board takes at least.
+// A single shift moves every tile of a slice by one, so it can't lower the board's Distance by more than the length of the longest slice.
+func LowerBound(b *Board) int {
+	l := b.Width()
+	if b.Height() > l {
+		l = b.Height()
+	}
+
+	return (b.Distance() + l - 1) / l
+}
+
+// SolveWithin looks for a solution of at most `maxMoves` single shifts allowed by `r`, returning it along with the search statistics.
+// Solutions are searched for with iterative deepening, so the one returned is the shortest. Returns false if there is none within `maxMoves`.
+// The search grows exponentially with `maxMoves`, so it is only feasible for short solutions.
+func SolveWithin(b *Board, maxMoves int, r *Restrictions) (*SolveResult, bool) {
+	return SolveWithinSeeded(b, maxMoves, r, 0, nil)
+}
+
+// SolveWithinSeeded is SolveWithin trying the moves from every position in an order shuffled by `seed`, which picks among equally short solutions,
+// or in the order of UnitMoves if `seed` is 0. Every position searched is recorded in the trace, if not nil, so that the search can be replayed.
+func SolveWithinSeeded(b *Board, maxMoves int, r *Restrictions, seed int64, t *SearchTrace) (*SolveResult, bool) {
+	p := &boardPuzzle{board: b.Clone(), moves: r.Filter(UnitMoves(b))}
+	if seed != 0 {
+		rand.New(rand.NewSource(seed)).Shuffle(len(p.moves), func(i, j int) {
+			p.moves[i], p.moves[j] = p.moves[j], p.moves[i]
+		})
+	}
+	t.start(b, maxMoves, seed, p.moves)
+
+	res, path, found := searchWithin(p, maxMoves, t)
+	for _, i := range path {
+		res.Moves = append(res.Moves, p.moves[i])
+	}
+
+	return res, found
+}
+
+// puzzle is a position that depthSearch can search through, with a fixed list of single shifts that can be made on it, referred to by their index.
+type puzzle interface {
+	IsSolved() bool
+	// LowerBound returns a number of moves that solving the position takes at least.
+	LowerBound() int
+	// Moves returns how many moves there are.
+	Moves() int
+	Make(i int)
+	Unmake(i int)
+	// Redundant returns true if making move `i` right after making move `last` `run` times in a row never leads to a shortest solution.
+	Redundant(last, run, i int) bool
+}
+
+// searchObserver is told about every position a depthSearch searches, as it searches it: the limit of the iteration, its depth, the index of the move that led to it,
+// or -1 for the position searched from, its lower bound and its outcome, SolvedNode, PrunedNode or ExpandedNode.
+type searchObserver interface {
+	visit(limit, depth, move, bound int, outcome string)
+}
+
+// searchWithin looks for the shortest solution of at most `maxMoves` moves to a puzzle with iterative deepening, returning the search statistics and the indices of the solution's moves.
+// The observer, if not nil, is told about every position searched.
+func searchWithin(p puzzle, maxMoves int, o searchObserver) (*SolveResult, []int, bool) {
+	start := time.Now()
+	if o == nil {
+		o = noObserver{}
+	}
+	s := &depthSearch{puzzle: p, res: &SolveResult{}, observer: o}
+
+	found := false
+	for limit := p.LowerBound(); limit <= maxMoves && !found; limit++ {
+		s.res.Depth = limit
+		found = s.search(limit, -1, 0)
+		logger.Debug("search depth", "depth", limit, "nodes", s.res.Nodes, "found", found)
+	}
+
+	// the path was pushed from the last move to the first.
+	n := len(s.path)
+	path := make([]int, n)
+	for i, m := range s.path {
+		path[n-1-i] = m
+	}
+
+	s.res.Time = time.Since(start)
+	return s.res, path, found
+}
+
+// depthSearch holds the state of searchWithin.
+type depthSearch struct {
+	puzzle   puzzle
+	path     []int
+	res      *SolveResult
+	observer searchObserver
+}
+
+// noObserver is the observer of searches that aren't observed.
+type noObserver struct{}
+
+func (noObserver) visit(limit, depth, move, bound int, outcome string) {}
+
+// search looks for a solution of at most `left` moves from the current position, where `last` is the move that led to it, or -1 at the start,
+// made `run` times in a row. If one is found, its moves are pushed to the path from the last one to the first.
+func (s *depthSearch) search(left, last, run int) bool {
+	s.res.Nodes++
+
+	p := s.puzzle
+	if p.IsSolved() {
+		s.observer.visit(s.res.Depth, s.res.Depth-left, last, 0, SolvedNode)
+		return true
+	}
+	bound := p.LowerBound()
+	if bound > left {
+		s.observer.visit(s.res.Depth, s.res.Depth-left, last, bound, PrunedNode)
+		return false
+	}
+	s.observer.visit(s.res.Depth, s.res.Depth-left, last, bound, ExpandedNode)
+
+	for i := 0; i < p.Moves(); i++ {
+		if last != -1 && p.Redundant(last, run, i) {
+			continue
+		}
+
+		next := 1
+		if i == last {
+			next = run + 1
+		}
+		p.Make(i)
+		found := s.search(left-1, i, next)
+		p.Unmake(i)
+
+		if found {
+			s.path = append(s.path, i)
+			return true
+		}
+	}
+
+	return false
+}
+
+// boardPuzzle is a puzzle on a Board, with the moves it can make.
+type boardPuzzle struct {
+	board Board
+	moves []Move
+}
+
+func (p *boardPuzzle) IsSolved() bool  { return p.board.IsSolved() }
+func (p *boardPuzzle) LowerBound() int { return LowerBound(&p.board) }
+func (p *boardPuzzle) Moves() int      { return len(p.moves) }
+func (p *boardPuzzle) Make(i int)      { p.board.MakeMove(&p.moves[i]) }
+
+func (p *boardPuzzle) Unmake(i int) {
+	m := p.moves[i]
+	m.Amount = -m.Amount
+	p.board.MakeMove(&m)
+}
+
+// Redundant returns true if both moves shift the same slice in opposite directions, or if the run of shifts goes past half of the slice.
+func (p *boardPuzzle) Redundant(last, run, i int) bool {
+	l, m := p.moves[last], p.moves[i]
+	return m.Axis == l.Axis && m.Index == l.Index && redundantShift(l.Amount, run, m.Amount, p.board.SliceLength(m.Axis))
+}
+
+// redundantShift returns true if a single shift of `amount` right after `run` single shifts of `last` of the same slice of `length` tiles never leads to a shortest solution:
+// if it undoes the last one, or if the run goes past half of the slice, which is shorter the other way around.
+// Runs of exactly half of the slice are as short both ways, so only the forward one is kept.
+func redundantShift(last, run, amount, length int) bool {
+	if amount != last {
+		return true
+	}
+
+	n := run + 1
+	return 2*n > length || 2*n == length && amount < 0
+}
+
+// Solver solves a board, returning its solution along with statistics.
+type Solver func(b *Board) (*SolveResult, error)
+
+// Solvers are the solvers that can be picked by name, to evaluate and compare them.
+var Solvers = map[string]Solver{
+	"human": SolveHuman,
+	"short": ShortSolver(shortWindow),
+}
+
+// ParseSolver returns the solver named `name` among Solvers. The window of the short solver can be given after a colon, like "short:5", to compare windows.
+func ParseSolver(name string) (Solver, error) {
+	name = strings.ToLower(name)
+	if s, ok := Solvers[name]; ok {
+		return s, nil
+	}
+	if strings.HasPrefix(name, "short:") {
+		window, err := strconv.Atoi(name[len("short:"):])
+		if err != nil || window < 2 {
+			return nil, fmt.Errorf("invalid window in solver %q, expected at least 2 single shifts", name)
+		}
+		return ShortSolver(window), nil
+	}
+
+	names := make([]string, 0, len(Solvers))
+	for n := range Solvers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown solver %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// shortWindow is the longest part of SolveHuman's solutions that the short solver looks for shortcuts in by default, in single shifts.
+const shortWindow = 7
+
+// ShortSolver returns a solver that solves the board with SolveHuman, and then shortens the solution by replacing its parts of up to `window` single shifts with shorter ones,
+// like insertions does. With the default window, solutions are a few moves shorter, but take tens of milliseconds to find instead of microseconds.
+func ShortSolver(window int) Solver {
+	return func(b *Board) (*SolveResult, error) {
+		start := time.Now()
+		res, err := SolveHuman(b)
+		if err != nil {
+			return nil, err
+		}
+
+		_, improved := FindShortcuts(b, res.Moves, window, ShiftCost)
+		res.Moves = SimplifyMoves(improved, b)
+		res.Time = time.Since(start)
+		return res, nil
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import "testing"
+
+// unitMoves returns a single shift of every slice of the board, both ways.
+func unitMoves(t testing.TB, b *Board) []Move {
+	t.Helper()
+
+	seq := UnitMoves(b)
+	if len(seq) == 0 {
+		t.Fatal("no moves")
+	}
+	return seq
+}
+
+func TestMakeMoveAllocs(t *testing.T) {
+	b, err := NewBoard(5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := unitMoves(t, &b)
+
+	i := 0
+	if n := testing.AllocsPerRun(1000, func() {
+		b.MakeMove(&seq[i%len(seq)])
+		i++
+	}); n != 0 {
+		t.Errorf("MakeMove made %v allocations per move, want 0", n)
+	}
+
+	if n := testing.AllocsPerRun(100, func() {
+		b.ApplyMovesUnchecked(seq)
+	}); n != 0 {
+		t.Errorf("ApplyMovesUnchecked made %v allocations per sequence, want 0", n)
+	}
+}
+
+func TestLastRowCycleAllocs(t *testing.T) {
+	b, err := NewBoard(6, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &humanSolver{board: b}
+
+	// the scratch board and the sequence buffer are allocated by the first call.
+	s.lastRowCycle(0, 3, 5)
+	if n := testing.AllocsPerRun(100, func() {
+		s.lastRowCycle(0, 3, 5)
+		s.lastRowCycle(4, 1, 2)
+	}); n != 0 {
+		t.Errorf("lastRowCycle made %v allocations per call, want 0", n)
+	}
+}
+
+func BenchmarkMakeMove(bm *testing.B) {
+	b, err := NewBoard(5, 5)
+	if err != nil {
+		bm.Fatal(err)
+	}
+	seq := unitMoves(bm, &b)
+
+	bm.ReportAllocs()
+	for i := 0; i < bm.N; i++ {
+		b.MakeMove(&seq[i%len(seq)])
+	}
+}
+
+func BenchmarkSolveHuman(bm *testing.B) {
+	b, err := NewBoard(6, 6)
+	if err != nil {
+		bm.Fatal(err)
+	}
+	b.uniformShuffle()
+
+	bm.ReportAllocs()
+	for i := 0; i < bm.N; i++ {
+		if _, err := SolveHuman(&b); err != nil {
+			bm.Fatal(err)
+		}
+	}
+}
+
+// inversePuzzle is a boardPuzzle that only prunes moves undoing the last one, as a baseline for how much the other pruning saves.
+type inversePuzzle struct {
+	*boardPuzzle
+}
+
+func (p inversePuzzle) Redundant(last, run, i int) bool {
+	l, m := p.moves[last], p.moves[i]
+	return m.Axis == l.Axis && m.Index == l.Index && m.Amount != l.Amount
+}
+
+func TestSolveWithinPrunesRuns(t *testing.T) {
+	b, err := NewBoard(3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, err := ParseMoves("1R0 1C1 -1R2 2C2 1R3 -1C0", &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.ApplyMovesUnchecked(seq)
+
+	res, found := SolveWithin(&b, 10, nil)
+	if !found {
+		t.Fatal("no solution found")
+	}
+	solved := b.Clone()
+	if solved.ApplyMovesUnchecked(res.Moves); !solved.IsSolved() {
+		t.Errorf("%v doesn't solve the board", res.Moves)
+	}
+
+	base, _, found := searchWithin(inversePuzzle{&boardPuzzle{board: b.Clone(), moves: UnitMoves(&b)}}, 10, nil)
+	if !found {
+		t.Fatal("no solution found without pruning runs")
+	}
+
+	if res.Depth != base.Depth {
+		t.Errorf("solution of %d moves, want %d", res.Depth, base.Depth)
+	}
+	if res.Nodes >= base.Nodes {
+		t.Errorf("searched %d nodes, want fewer than the %d searched without pruning runs", res.Nodes, base.Nodes)
+	}
+	t.Logf("searched %d nodes, %d without pruning runs", res.Nodes, base.Nodes)
+}
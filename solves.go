@@ -0,0 +1,558 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "solves",
+		Summary: "export the solves played to CSV, or import solves exported on another machine",
+		Run:     runSolves,
+	})
+}
+
+// SolveRecord is a solve played in the game, kept for analyzing progress over time.
+type SolveRecord struct {
+	Date   time.Time `json:"date"`
+	Player string    `json:"player"`
+	// Size is the board size, like "5x5".
+	Size string `json:"size"`
+	// Scramble is the state code of the scramble.
+	Scramble string  `json:"scramble"`
+	Seconds  float64 `json:"seconds"`
+	// Penalty is the penalty of the solve under competition rules, like "+2" or "DNF", as returned by Penalty.String.
+	Penalty string `json:"penalty,omitempty"`
+	// Moves and SliceMoves are the length of the solution in single shifts and in slice moves, where consecutive moves of the same slice count as one.
+	Moves      int `json:"moves"`
+	SliceMoves int `json:"slice_moves"`
+	// Solution is the moves of the solution in Programmer's Notation.
+	Solution string `json:"solution"`
+	// Splits holds how long every phase of the solve took, with phases ending whenever more rows from the top became solved or at the splits marked by hand.
+	Splits []SolveSplit `json:"splits,omitempty"`
+	// MoveTimes holds the seconds since the first move when every move of the solution was made, to the millisecond, or is empty for solves recorded without them.
+	MoveTimes []float64 `json:"move_times,omitempty"`
+}
+
+// SolveSplit is how long a phase of a recorded solve took.
+type SolveSplit struct {
+	Phase   string  `json:"phase"`
+	Seconds float64 `json:"seconds"`
+}
+
+// formatSplits formats splits for CSV files, like "Row 1=3.200; Last 4 rows=8.100".
+func formatSplits(splits []SolveSplit) string {
+	parts := make([]string, len(splits))
+	for i, s := range splits {
+		parts[i] = fmt.Sprintf("%s=%.3f", s.Phase, s.Seconds)
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// parseSplits parses splits formatted by formatSplits.
+func parseSplits(s string) ([]SolveSplit, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var splits []SolveSplit
+	for _, part := range strings.Split(s, ";") {
+		i := strings.LastIndexByte(part, '=')
+		if i == -1 {
+			return nil, fmt.Errorf("invalid split %q, expected PHASE=SECONDS", part)
+		}
+		seconds, err := strconv.ParseFloat(part[i+1:], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid split %q, expected PHASE=SECONDS", part)
+		}
+
+		splits = append(splits, SolveSplit{Phase: strings.TrimSpace(part[:i]), Seconds: seconds})
+	}
+
+	return splits, nil
+}
+
+// TPS returns the moves per second of the solve.
+func (r *SolveRecord) TPS() float64 {
+	if r.Seconds <= 0 {
+		return 0
+	}
+
+	return float64(r.Moves) / r.Seconds
+}
+
+// Result returns the time of the solve with its penalty, in seconds. Returns false for DNFs, which have no result.
+func (r *SolveRecord) Result() (float64, bool) {
+	p, _ := ParsePenalty(r.Penalty)
+	if p.DNF {
+		return 0, false
+	}
+
+	return p.Add(time.Duration(r.Seconds * float64(time.Second))).Seconds(), true
+}
+
+// FormatResult formats the result of the solve like "12.345", "14.345 (+2)" or "DNF (12.345)".
+func (r *SolveRecord) FormatResult() string {
+	res, ok := r.Result()
+	switch {
+	case !ok:
+		return fmt.Sprintf("DNF (%.3f)", r.Seconds)
+	case r.Penalty != "":
+		return fmt.Sprintf("%.3f (%s)", res, r.Penalty)
+	default:
+		return fmt.Sprintf("%.3f", res)
+	}
+}
+
+// Replay returns the solve as a timed replay, with the time every move was made at. Solves recorded without the times of their moves can't be replayed.
+func (r *SolveRecord) Replay() (*Replay, error) {
+	b, err := DecodeState(r.Scramble)
+	if err != nil {
+		return nil, err
+	}
+	seq, err := ParseMoves(r.Solution, &b)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.MoveTimes) != len(seq) {
+		return nil, fmt.Errorf("the solve was recorded without the times of its moves")
+	}
+
+	rep := &Replay{Scramble: b, Moves: seq, Comments: make([]string, len(seq)+1), Times: make([]time.Duration, len(seq))}
+	rep.Comments[0] = fmt.Sprintf("%s's %s solve of %s", r.Player, r.FormatResult(), r.Date.Local().Format("2006-01-02"))
+	for i, t := range r.MoveTimes {
+		rep.Times[i] = time.Duration(t * float64(time.Second))
+	}
+
+	return rep, nil
+}
+
+// formatMoveTimes formats the times of moves for CSV files, as seconds separated by spaces.
+func formatMoveTimes(times []float64) string {
+	parts := make([]string, len(times))
+	for i, t := range times {
+		parts[i] = strconv.FormatFloat(t, 'f', 3, 64)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// parseMoveTimes parses the times of moves formatted by formatMoveTimes.
+func parseMoveTimes(s string) ([]float64, error) {
+	var times []float64
+	for _, f := range strings.Fields(s) {
+		t, err := strconv.ParseFloat(f, 64)
+		if err != nil || t < 0 {
+			return nil, fmt.Errorf("invalid move time %q", f)
+		}
+		times = append(times, t)
+	}
+
+	return times, nil
+}
+
+// key identifies a solve, to tell which solves of an import are already recorded. Dates are only exported to the second, so they are compared to the second.
+func (r *SolveRecord) key() string {
+	return r.Date.UTC().Format(time.RFC3339) + " " + r.Player + " " + r.Scramble
+}
+
+// SolveHistory is the store of every solve played, saved as JSON in the data directory.
+type SolveHistory struct {
+	path   string
+	Solves []SolveRecord `json:"solves"`
+}
+
+// LoadSolveHistory reads the solve history from the data directory. A missing history is empty.
+func LoadSolveHistory() (*SolveHistory, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	h := &SolveHistory{path: filepath.Join(dir, "solves.json")}
+
+	data, err := os.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("%s: %s", h.path, err)
+	}
+
+	return h, nil
+}
+
+// Save writes the solve history to the data directory.
+func (h *SolveHistory) Save() error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(h.path, data, 0o644)
+}
+
+// Merge adds the solves that aren't in the history yet, keeping it sorted by date, and returns how many were added.
+func (h *SolveHistory) Merge(solves []SolveRecord) int {
+	known := map[string]bool{}
+	for i := range h.Solves {
+		known[h.Solves[i].key()] = true
+	}
+
+	var added int
+	for _, s := range solves {
+		if known[s.key()] {
+			continue
+		}
+		known[s.key()] = true
+		h.Solves = append(h.Solves, s)
+		added++
+	}
+
+	sort.SliceStable(h.Solves, func(i, j int) bool { return h.Solves[i].Date.Before(h.Solves[j].Date) })
+	return added
+}
+
+// RecordSolve adds the game's solve to the solve history, under the name of the player, along with its penalty. Solves that were given up are recorded as DNFs.
+func (g *Game) RecordSolve(player string) error {
+	h, err := LoadSolveHistory()
+	if err != nil {
+		return err
+	}
+
+	r := g.Replay()
+	var splits []SolveSplit
+	var times []float64
+	if r.Times != nil {
+		for _, s := range PhaseSplits(r) {
+			splits = append(splits, SolveSplit{Phase: s.Name, Seconds: s.Time.Seconds()})
+		}
+		for _, t := range r.Times {
+			times = append(times, t.Round(time.Millisecond).Seconds())
+		}
+	}
+
+	h.Merge([]SolveRecord{{
+		Date:       time.Now(),
+		Player:     player,
+		Size:       fmt.Sprintf("%dx%d", g.Board.Width(), g.Board.Height()),
+		Scramble:   EncodeState(&g.Scramble),
+		Seconds:    g.Elapsed().Seconds(),
+		Penalty:    g.Penalty.String(),
+		Moves:      g.Moves,
+		SliceMoves: SliceMoves(r.Moves, &g.Board),
+		Solution:   MoveFormat{}.FormatMoves(r.Moves, &g.Board),
+		Splits:     splits,
+		MoveTimes:  times,
+	}})
+
+	return h.Save()
+}
+
+// Leaderboard returns the best solve of every player on boards of a size, ranked by their result. DNFs don't count.
+func Leaderboard(solves []SolveRecord, size string) []SolveRecord {
+	best := map[string]SolveRecord{}
+	for _, s := range solves {
+		res, ok := s.Result()
+		if !ok || s.Size != size {
+			continue
+		}
+		if b, seen := best[s.Player]; seen {
+			if bres, _ := b.Result(); bres <= res {
+				continue
+			}
+		}
+		best[s.Player] = s
+	}
+
+	var board []SolveRecord
+	for _, s := range best {
+		board = append(board, s)
+	}
+	sort.Slice(board, func(i, j int) bool {
+		ri, _ := board[i].Result()
+		rj, _ := board[j].Result()
+		return ri < rj
+	})
+
+	return board
+}
+
+// solvesCSVHeader is the header of solve CSV files, naming the columns.
+var solvesCSVHeader = []string{"date", "player", "size", "scramble", "seconds", "penalty", "moves", "slice_moves", "tps", "solution", "splits", "move_times"}
+
+// WriteSolvesCSV writes solves as CSV, with a header naming the columns.
+func WriteSolvesCSV(w io.Writer, solves []SolveRecord) error {
+	cw := csv.NewWriter(w)
+	cw.Write(solvesCSVHeader)
+
+	for _, s := range solves {
+		cw.Write([]string{
+			s.Date.Format(time.RFC3339),
+			s.Player,
+			s.Size,
+			s.Scramble,
+			strconv.FormatFloat(s.Seconds, 'f', 3, 64),
+			s.Penalty,
+			strconv.Itoa(s.Moves),
+			strconv.Itoa(s.SliceMoves),
+			strconv.FormatFloat(s.TPS(), 'f', 2, 64),
+			s.Solution,
+			formatSplits(s.Splits),
+			formatMoveTimes(s.MoveTimes),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadSolvesCSV reads solves written by WriteSolvesCSV. Columns are found by the names in the header, so they can be in any order,
+// and extra columns added in a spreadsheet are ignored.
+func ReadSolvesCSV(r io.Reader) ([]SolveRecord, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty input")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	for _, name := range []string{"date", "player", "size", "scramble", "seconds", "moves"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var solves []SolveRecord
+	for line, rec := range records[1:] {
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(rec) {
+				return rec[i]
+			}
+			return ""
+		}
+
+		s := SolveRecord{Player: field("player"), Size: field("size"), Scramble: field("scramble"), Penalty: field("penalty"), Solution: field("solution")}
+		if s.Date, err = time.Parse(time.RFC3339, field("date")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", line+2, field("date"))
+		}
+		if _, err := DecodeState(s.Scramble); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line+2, err)
+		}
+		if s.Seconds, err = strconv.ParseFloat(field("seconds"), 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid seconds %q", line+2, field("seconds"))
+		}
+		if s.Splits, err = parseSplits(field("splits")); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line+2, err)
+		}
+		if s.MoveTimes, err = parseMoveTimes(field("move_times")); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line+2, err)
+		}
+		if _, err := ParsePenalty(s.Penalty); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line+2, err)
+		}
+		if s.Moves, err = strconv.Atoi(field("moves")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid moves %q", line+2, field("moves"))
+		}
+		if v := field("slice_moves"); v != "" {
+			if s.SliceMoves, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("line %d: invalid slice moves %q", line+2, v)
+			}
+		}
+
+		solves = append(solves, s)
+	}
+
+	return solves, nil
+}
+
+// runSolves runs the solves command.
+func runSolves(args []string) error {
+	fs := flag.NewFlagSet("solves", flag.ContinueOnError)
+	player := fs.String("player", "", "only export or review the solves of this player")
+	since := fs.String("since", "", "only export the solves since a date like 2006-01-02, or for a duration like 3h, to export a session")
+	out := fs.String("o", "", "file to export to instead of the standard output, or to save the ghost to")
+	size := fs.String("size", "5x5", "board size to rank the solves of")
+	n := fs.Int("n", 10, "how many players to rank")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: solves export [-player NAME] [-since DATE] [-o FILE] | solves import FILE... | solves top [-size 5x5] [-n 10] | solves review [-player NAME]")
+		fmt.Fprintln(fs.Output(), "       solves ghost [-size 5x5] [-o FILE] RANK")
+		fmt.Fprintln(fs.Output(), "export writes every solve played as CSV: its date, player, size, scramble, time, penalty, moves, slice moves, TPS, solution, phase splits and the time of every move.")
+		fmt.Fprintln(fs.Output(), "import adds the solves of CSV files exported on another machine, skipping the ones already recorded.")
+		fmt.Fprintln(fs.Output(), "top ranks the players by their best result on a board size, with penalties added and DNFs left out, marking the solves that look implausible for a person.")
+		fmt.Fprintln(fs.Output(), "ghost saves the solve ranked RANK by top as a timed replay, PLAYER.replay by default, to race it as a ghost with the ghost command of the game.")
+		fmt.Fprintln(fs.Output(), "review lists those solves with why they were flagged: an inhuman TPS, impossible timing, a solution that is the engine's or doesn't solve the scramble, or an even pace in every phase.")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected export or import")
+	}
+	action := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	h, err := LoadSolveHistory()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "export":
+		var from time.Time
+		if *since != "" {
+			if d, err := time.ParseDuration(*since); err == nil {
+				from = time.Now().Add(-d)
+			} else if from, err = time.ParseInLocation("2006-01-02", *since, time.Local); err != nil {
+				return fmt.Errorf("invalid -since %q, expected a date like 2006-01-02 or a duration like 3h", *since)
+			}
+		}
+
+		var solves []SolveRecord
+		for _, s := range h.Solves {
+			if (*player == "" || s.Player == *player) && !s.Date.Before(from) {
+				solves = append(solves, s)
+			}
+		}
+
+		if *out == "" {
+			return WriteSolvesCSV(os.Stdout, solves)
+		}
+
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		err = WriteSolvesCSV(f, solves)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			fmt.Printf("Exported %d solves to %s\n", len(solves), *out)
+		}
+
+		return err
+	case "import":
+		if fs.NArg() == 0 {
+			fs.Usage()
+			return fmt.Errorf("expected files to import")
+		}
+
+		var added, total int
+		for _, path := range fs.Args() {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			solves, err := ReadSolvesCSV(f)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %s", path, err)
+			}
+
+			added += h.Merge(solves)
+			total += len(solves)
+		}
+
+		if err := h.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d new solves out of %d\n", added, total)
+		return nil
+	case "top":
+		if _, _, err := ParseTwoDimensions(*size); err != nil {
+			return err
+		}
+
+		board := Leaderboard(h.Solves, *size)
+		if len(board) == 0 {
+			fmt.Printf("No %s solves yet\n", *size)
+			return nil
+		}
+		if len(board) > *n {
+			board = board[:*n]
+		}
+
+		for i, s := range board {
+			fmt.Printf("%d. %s: %s, %d moves, on %s", i+1, s.Player, s.FormatResult(), s.Moves, s.Date.Local().Format("2006-01-02"))
+			if flags := s.Flags(); len(flags) != 0 {
+				fmt.Printf(", flagged for review: %s", strings.Join(flags, "; "))
+			}
+			fmt.Println()
+		}
+		return nil
+	case "ghost":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected the rank of the solve on the leaderboard")
+		}
+		rank, err := strconv.Atoi(fs.Arg(0))
+		board := Leaderboard(h.Solves, *size)
+		if err != nil || rank < 1 || rank > len(board) {
+			return fmt.Errorf("invalid rank %q, there are %d %s solves on the leaderboard", fs.Arg(0), len(board), *size)
+		}
+
+		s := board[rank-1]
+		r, err := s.Replay()
+		if err != nil {
+			return err
+		}
+		path := *out
+		if path == "" {
+			path = s.Player + ".replay"
+		}
+		if err := SaveReplay(path, r); err != nil {
+			return err
+		}
+
+		fmt.Printf("Saved %s's %s solve to %s, race it with \"ghost %s\" in the game\n", s.Player, s.FormatResult(), path, path)
+		return nil
+	case "review":
+		var flagged int
+		for _, s := range h.Solves {
+			if *player != "" && s.Player != *player {
+				continue
+			}
+			flags := s.Flags()
+			if len(flags) == 0 {
+				continue
+			}
+
+			flagged++
+			fmt.Printf("%s %s on %s: %s, %d moves, scramble %s\n", s.Date.Local().Format("2006-01-02 15:04"), s.Player, s.Size, s.FormatResult(), s.Moves, s.Scramble)
+			for _, f := range flags {
+				fmt.Printf("  %s\n", f)
+			}
+		}
+		fmt.Printf("%d solves flagged out of %d\n", flagged, len(h.Solves))
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "rank",
+		Summary: "print the rank of a board's arrangement, or the board with a rank",
+		Run:     runRank,
+	})
+}
+
+/* State code for a board
+state     = dimension ":" tiles
+dimension = number "x" number
+tiles     = number { "," number }
+
+Tiles are listed row by row, starting from the top left.
+*/
+
+// EncodeState returns a compact code of the board's dimensions and tiles that can be shared and later parsed back with DecodeState.
+func EncodeState(b *Board) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%dx%d:", b.Width(), b.Height())
+
+	for y := 0; y < b.Height(); y++ {
+		for x := 0; x < b.Width(); x++ {
+			if x != 0 || y != 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(strconv.Itoa((*b)[x][y]))
+		}
+	}
+
+	return sb.String()
+}
+
+// DecodeState creates a Board from a code generated by EncodeState.
+func DecodeState(input string) (Board, error) {
+	input = strings.TrimSpace(input)
+	if len(input) == 0 {
+		return nil, fmt.Errorf("empty input")
+	}
+
+	i := strings.IndexByte(input, ':')
+	if i == -1 {
+		return nil, fmt.Errorf("missing dimensions in state %q", input)
+	}
+
+	w, h, err := ParseTwoDimensions(input[:i])
+	if err != nil {
+		return nil, fmt.Errorf("invalid dimensions in state %q: %s", input, err)
+	}
+
+	fields := strings.Split(input[i+1:], ",")
+	if len(fields) != w*h {
+		return nil, fmt.Errorf("expected %d tiles but got %d in state %q", w*h, len(fields), input)
+	}
+
+	tiles := make([]int, len(fields))
+	for j, f := range fields {
+		tiles[j], err = strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, fmt.Errorf("invalid number for tile %d in state %q", j+1, input)
+		}
+	}
+
+	return NewBoardFromTiles(w, h, tiles)
+}
+
+// NewBoardFromTiles creates a Board with the given dimensions whose tiles are taken row by row from `tiles`.
+// The tiles must be a permutation of 1 through width*height.
+func NewBoardFromTiles(width, height int, tiles []int) (Board, error) {
+	b, err := NewBoard(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateTiles(tiles, width*height); err != nil {
+		return nil, err
+	}
+
+	for i, t := range tiles {
+		b[i%width][i/width] = t
+	}
+
+	return b, nil
+}
+
+// ValidateTiles checks that `tiles` holds every number from 1 to `n` exactly once, reporting all duplicated, missing and out of range tiles.
+func ValidateTiles(tiles []int, n int) error {
+	if len(tiles) != n {
+		return fmt.Errorf("expected %d tiles but got %d", n, len(tiles))
+	}
+
+	seen := make([]int, n+1)
+	var invalid []string
+	for _, t := range tiles {
+		if t < 1 || t > n {
+			invalid = append(invalid, strconv.Itoa(t))
+			continue
+		}
+		seen[t]++
+	}
+
+	var duplicated, missing []string
+	for t := 1; t <= n; t++ {
+		switch {
+		case seen[t] == 0:
+			missing = append(missing, strconv.Itoa(t))
+		case seen[t] > 1:
+			duplicated = append(duplicated, strconv.Itoa(t))
+		}
+	}
+
+	var problems []string
+	if len(invalid) != 0 {
+		problems = append(problems, "out of range "+strings.Join(invalid, ", "))
+	}
+	if len(duplicated) != 0 {
+		problems = append(problems, "duplicated "+strings.Join(duplicated, ", "))
+	}
+	if len(missing) != 0 {
+		problems = append(problems, "missing "+strings.Join(missing, ", "))
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("invalid tiles: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// MaxRankTiles is the most tiles a board can have to be ranked, as 20! is the largest factorial that fits in a uint64.
+const MaxRankTiles = 20
+
+// Rank returns the position of the board's arrangement in the lexicographic order of all arrangements of its tiles, read row by row.
+// The solved board has rank 0 and ranks go up to (width*height)!-1, so every arrangement of a board size is indexed by a number in that range,
+// which can be stored in far less space than its state code.
+func (b *Board) Rank() (uint64, error) {
+	tiles := b.Tiles()
+	if len(tiles) > MaxRankTiles {
+		return 0, fmt.Errorf("%dx%d boards have more than %d tiles, too many to rank", b.Width(), b.Height(), MaxRankTiles)
+	}
+
+	// the rank is a number in the factorial number system, where the ith digit is how many of the following tiles are smaller than the ith one.
+	var rank uint64
+	for i, t := range tiles {
+		var smaller uint64
+		for _, u := range tiles[i+1:] {
+			if u < t {
+				smaller++
+			}
+		}
+
+		rank = rank*uint64(len(tiles)-i) + smaller
+	}
+
+	return rank, nil
+}
+
+// UnrankBoard returns the width*height board whose arrangement has the given rank, the inverse of Board.Rank.
+func UnrankBoard(rank uint64, width, height int) (Board, error) {
+	if _, err := NewBoard(width, height); err != nil {
+		return nil, err
+	}
+
+	n := width * height
+	if n > MaxRankTiles {
+		return nil, fmt.Errorf("%dx%d boards have more than %d tiles, too many to rank", width, height, MaxRankTiles)
+	}
+
+	digits := make([]int, n)
+	for i := n - 1; i >= 0; i-- {
+		digits[i] = int(rank % uint64(n-i))
+		rank /= uint64(n - i)
+	}
+	if rank != 0 {
+		return nil, fmt.Errorf("rank is too large for a %dx%d board", width, height)
+	}
+
+	left := make([]int, n)
+	for i := range left {
+		left[i] = i + 1
+	}
+
+	tiles := make([]int, n)
+	for i, d := range digits {
+		tiles[i] = left[d]
+		left = append(left[:d], left[d+1:]...)
+	}
+
+	return NewBoardFromTiles(width, height, tiles)
+}
+
+// runRank runs the rank command.
+func runRank(args []string) error {
+	fs := flag.NewFlagSet("rank", flag.ContinueOnError)
+	size := fs.String("size", "", "board size, to print the board with the rank given instead of a state")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: rank STATE | rank -size SIZE RANK")
+		fmt.Fprintf(fs.Output(), "Prints the rank of the arrangement of a board with up to %d tiles given its state code, or the state code of the board with a rank.\n", MaxRankTiles)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a state or a rank")
+	}
+
+	if *size == "" {
+		b, err := DecodeState(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		rank, err := b.Rank()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(rank)
+		return nil
+	}
+
+	w, h, err := ParseTwoDimensions(*size)
+	if err != nil {
+		return err
+	}
+
+	rank, err := strconv.ParseUint(fs.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid rank %q", fs.Arg(0))
+	}
+
+	b, err := UnrankBoard(rank, w, h)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(EncodeState(&b))
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Survival is a survival game, where a random move is made on the board every Interval until the player solves it or chaos wins.
+type Survival struct {
+	Interval time.Duration
+	// Limit is the Distance at which chaos wins, twice the board's Distance when survival started.
+	Limit int
+	// Injected is how many random moves were made so far.
+	Injected int
+	Start    time.Time
+
+	stop chan struct{}
+}
+
+// StartSurvival scrambles the board a few moves away from solved and starts making a random move on it every `interval`, stopping when the game restarts.
+// Moves are made while holding `mu`, usually the SafeGame wrapping the game, which has to be held by whoever else touches the game, and `report` is called with every move along with whether chaos won with it.
+func (g *Game) StartSurvival(interval time.Duration, mu sync.Locker, report func(m Move, lost bool)) error {
+	moves := g.Restrictions.Filter(UnitMoves(&g.Board))
+	if len(moves) == 0 {
+		return fmt.Errorf("no slice can be moved")
+	}
+
+	w, h := g.Board.Width(), g.Board.Height()
+	depth := w
+	if h > depth {
+		depth = h
+	}
+
+	g.Board.ScrambleDepth(depth)
+	g.Restart()
+
+	s := &Survival{Interval: interval, Limit: 2 * g.Board.Distance(), Start: time.Now(), stop: make(chan struct{})}
+	g.Survival = s
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-t.C:
+			}
+
+			mu.Lock()
+			if g.Survival != s {
+				mu.Unlock()
+				return
+			}
+
+			m := moves[rng.Intn(len(moves))]
+			g.InjectMove(&m)
+			s.Injected++
+
+			lost := g.Board.Distance() >= s.Limit
+			if lost {
+				g.StopSurvival()
+			}
+			report(m, lost)
+			mu.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+// StopSurvival stops the survival game, if any.
+func (g *Game) StopSurvival() {
+	if g.Survival != nil {
+		close(g.Survival.stop)
+		g.Survival = nil
+	}
+}
+
+// scanUnlocked scans the next line of input with `mu` unlocked, so that background goroutines can touch the game while waiting for the player.
+func scanUnlocked(con *Console, mu sync.Locker) bool {
+	mu.Unlock()
+	defer mu.Lock()
+
+	return con.Scan()
+}
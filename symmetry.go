@@ -0,0 +1,111 @@
+package main
+
+// Symmetry is a rotation or reflection of the board.
+// Applying it to both the tiles' positions and their values gives a board that is exactly as far from solved as the original one.
+type Symmetry struct {
+	Name string
+
+	// point maps the coordinate (x, y) of a width*height board to its image.
+	point func(x, y, width, height int) (int, int)
+}
+
+var (
+	rectangleSymmetries = []Symmetry{
+		{"identity", func(x, y, w, h int) (int, int) { return x, y }},
+		{"mirror", func(x, y, w, h int) (int, int) { return w - 1 - x, y }},
+		{"flip", func(x, y, w, h int) (int, int) { return x, h - 1 - y }},
+		{"rotate 180", func(x, y, w, h int) (int, int) { return w - 1 - x, h - 1 - y }},
+	}
+	squareSymmetries = []Symmetry{
+		{"transpose", func(x, y, w, h int) (int, int) { return y, x }},
+		{"rotate 90", func(x, y, w, h int) (int, int) { return w - 1 - y, x }},
+		{"rotate 270", func(x, y, w, h int) (int, int) { return y, w - 1 - x }},
+		{"anti-transpose", func(x, y, w, h int) (int, int) { return w - 1 - y, w - 1 - x }},
+	}
+)
+
+// Symmetries returns the symmetries the board's dimensions allow, starting with the identity.
+// Every board can be mirrored, flipped and rotated by 180 degrees; square boards can also be rotated by 90 degrees and transposed.
+func Symmetries(b *Board) []Symmetry {
+	syms := append([]Symmetry(nil), rectangleSymmetries...)
+	if b.Width() == b.Height() {
+		syms = append(syms, squareSymmetries...)
+	}
+
+	return syms
+}
+
+// Transform returns a copy of the board with the symmetry applied to the position and the value of every tile.
+func (b *Board) Transform(s Symmetry) Board {
+	w, h := b.Width(), b.Height()
+
+	t := b.Clone()
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			v := (*b)[x][y] - 1
+			vx, vy := s.point(v%w, v/w, w, h)
+			tx, ty := s.point(x, y, w, h)
+
+			t[tx][ty] = t.defaultTileValue(vx, vy)
+		}
+	}
+
+	return t
+}
+
+// TransformMove returns the move that does on a transformed board what `m` does on the original board.
+func (b *Board) TransformMove(s Symmetry, m Move) Move {
+	w, h := b.Width(), b.Height()
+
+	// look at where the slice's first two tiles end up to find out the new slice and direction.
+	x0, y0, x1, y1 := 0, m.Index, 1, m.Index
+	if m.Axis == VerticalAxis {
+		x0, y0, x1, y1 = m.Index, 0, m.Index, 1
+	}
+	x0, y0 = s.point(x0, y0, w, h)
+	x1, y1 = s.point(x1, y1, w, h)
+
+	if y0 == y1 {
+		m.Axis, m.Index = HorizontalAxis, y0
+		if x1 != (x0+1)%w {
+			m.Amount = -m.Amount
+		}
+	} else {
+		m.Axis, m.Index = VerticalAxis, x0
+		if y1 != (y0+1)%h {
+			m.Amount = -m.Amount
+		}
+	}
+
+	return m
+}
+
+// Canonical returns the representative of every board that is equivalent to this one under Symmetries, along with the symmetry that transforms this board into it.
+// The representative is the transformed board whose Tiles come first in lexicographic order.
+func (b *Board) Canonical() (Board, Symmetry) {
+	var best Board
+	var bestSym Symmetry
+	var bestTiles []int
+
+	for _, s := range Symmetries(b) {
+		t := b.Transform(s)
+		tiles := t.Tiles()
+
+		if bestTiles == nil || lessTiles(tiles, bestTiles) {
+			best, bestSym, bestTiles = t, s, tiles
+		}
+	}
+
+	return best, bestSym
+}
+
+// lessTiles returns true if `a` comes before `b` in lexicographic order.
+func lessTiles(a, b []int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+
+	return false
+}
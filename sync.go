@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "sync",
+		Summary: "push and pull profiles, solves and replays to and from a remote store",
+		Run:     runSync,
+	})
+}
+
+/* Sync protocol
+A remote store keeps files by name, along with when each was last modified. Every request carries the header
+"Authorization: Bearer TOKEN", and times are in RFC 3339.
+
+GET {url}/index            -> {"files": {name: modified}}
+GET {url}/files/{name}     -> the file
+PUT {url}/files/{name}        with the file as body and its modification time in the header "X-Loopover-Modified"
+
+Names are paths relative to the data directory, like "solves.json" or "replays/pb.replay", escaped as a single path segment.
+*/
+
+// syncTimeout is how long a request to the remote store can take before it's given up on.
+const syncTimeout = 30 * time.Second
+
+// syncModifiedHeader is the header a file's modification time is sent in when pushing it.
+const syncModifiedHeader = "X-Loopover-Modified"
+
+// SyncSettings holds the remote store to sync with, saved as JSON in the data directory.
+type SyncSettings struct {
+	path  string
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// LoadSyncSettings reads the sync settings from the data directory. Missing settings are empty.
+func LoadSyncSettings() (*SyncSettings, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SyncSettings{path: filepath.Join(dir, "sync.json")}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("%s: %s", s.path, err)
+	}
+
+	return s, nil
+}
+
+// Save writes the sync settings to the data directory, readable only by the user as they hold the token.
+func (s *SyncSettings) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path, data, 0o600)
+}
+
+// SyncClient talks to a remote store.
+type SyncClient struct {
+	URL, Token string
+	client     *http.Client
+}
+
+// NewSyncClient returns a client of the remote store at `url`, authenticating with `token`.
+func NewSyncClient(url, token string) *SyncClient {
+	return &SyncClient{URL: strings.TrimSuffix(url, "/"), Token: token, client: &http.Client{Timeout: syncTimeout}}
+}
+
+// do makes a request to the remote store, returning the body of its answer.
+func (c *SyncClient) do(req *http.Request) ([]byte, error) {
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s %s: %s", req.Method, req.URL, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Index returns the modification time of every file in the remote store, by name.
+func (c *SyncClient) Index() (map[string]time.Time, error) {
+	req, err := http.NewRequest(http.MethodGet, c.URL+"/index", nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var index struct {
+		Files map[string]time.Time `json:"files"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("%s/index: %s", c.URL, err)
+	}
+	if index.Files == nil {
+		index.Files = map[string]time.Time{}
+	}
+	// names become paths in the data directory, so a store listing other files could write anywhere.
+	for name := range index.Files {
+		if !IsSyncedName(name) {
+			return nil, fmt.Errorf("%s/index: %q is not a synced file", c.URL, name)
+		}
+	}
+
+	return index.Files, nil
+}
+
+// Pull downloads a file from the remote store.
+func (c *SyncClient) Pull(name string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, c.URL+"/files/"+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(req)
+}
+
+// Push uploads a file to the remote store, along with when it was modified.
+func (c *SyncClient) Push(name string, data []byte, modified time.Time) error {
+	req, err := http.NewRequest(http.MethodPut, c.URL+"/files/"+url.PathEscape(name), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(syncModifiedHeader, modified.UTC().Format(time.RFC3339))
+
+	_, err = c.do(req)
+	return err
+}
+
+// IsSyncedName returns true if the name is one of a file that is synced: profiles.json, solves.json or replays/NAME.replay, where NAME is a single path segment.
+func IsSyncedName(name string) bool {
+	if name == "profiles.json" || name == "solves.json" {
+		return true
+	}
+
+	base := strings.TrimPrefix(name, "replays/")
+	return base != name && strings.HasSuffix(base, ".replay") && len(base) > len(".replay") && !strings.ContainsAny(base, `/\`) && !strings.Contains(base, "..")
+}
+
+// SyncedFiles returns the modification time of every file of the data directory that is synced, by name:
+// the profiles, the solve history and the replays in the replays directory.
+func SyncedFiles(dir string) (map[string]time.Time, error) {
+	files := map[string]time.Time{}
+
+	names := []string{"profiles.json", "solves.json"}
+	replays, err := filepath.Glob(filepath.Join(dir, "replays", "*.replay"))
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range replays {
+		names = append(names, "replays/"+filepath.Base(r))
+	}
+
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// times are exchanged to the second.
+		files[name] = info.ModTime().UTC().Truncate(time.Second)
+	}
+
+	return files, nil
+}
+
+// SyncStep is what syncing does with a file.
+type SyncStep struct {
+	Name string
+	// Push is true if the local file is newer and replaces the remote one, and false if the remote one replaces it.
+	// The solve history is merged both ways instead, as solves are only ever added.
+	Push, Merge bool
+}
+
+func (s SyncStep) String() string {
+	switch {
+	case s.Merge:
+		return "merge " + s.Name
+	case s.Push:
+		return "push " + s.Name
+	default:
+		return "pull " + s.Name
+	}
+}
+
+// PlanSync decides what to do with every file, in order of name, given their local and remote modification times.
+// The newest version of a file wins, and files that were modified at the same time are left alone.
+func PlanSync(local, remote map[string]time.Time) []SyncStep {
+	var names []string
+	for name := range local {
+		names = append(names, name)
+	}
+	for name := range remote {
+		if _, ok := local[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var steps []SyncStep
+	for _, name := range names {
+		l, inLocal := local[name]
+		r, inRemote := remote[name]
+
+		switch {
+		case inLocal && inRemote && l.Equal(r):
+		case inLocal && inRemote && name == "solves.json":
+			steps = append(steps, SyncStep{Name: name, Merge: true})
+		default:
+			steps = append(steps, SyncStep{Name: name, Push: !inRemote || inLocal && l.After(r)})
+		}
+	}
+
+	return steps
+}
+
+// Sync syncs the data directory with the remote store, writing every step taken to `w`. With `dryRun`, the steps are only written.
+func Sync(c *SyncClient, dryRun bool, w io.Writer) error {
+	dir, err := DataDir()
+	if err != nil {
+		return err
+	}
+
+	local, err := SyncedFiles(dir)
+	if err != nil {
+		return err
+	}
+	remote, err := c.Index()
+	if err != nil {
+		return err
+	}
+
+	steps := PlanSync(local, remote)
+	if len(steps) == 0 {
+		fmt.Fprintln(w, "Everything is in sync")
+		return nil
+	}
+
+	for _, s := range steps {
+		fmt.Fprintln(w, s)
+		if dryRun {
+			continue
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(s.Name))
+		switch {
+		case s.Merge:
+			err = syncMerge(c, path, s.Name)
+		case s.Push:
+			err = syncPush(c, path, s.Name, local[s.Name])
+		default:
+			err = syncPull(c, path, s.Name, remote[s.Name])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncPush uploads a local file.
+func syncPush(c *SyncClient, path, name string, modified time.Time) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return c.Push(name, data, modified)
+}
+
+// syncPull downloads a file, giving it the remote modification time so that both are in sync.
+func syncPull(c *SyncClient, path, name string, modified time.Time) error {
+	data, err := c.Pull(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Chtimes(path, modified, modified)
+}
+
+// syncMerge adds the remote solves to the local solve history and uploads the result.
+func syncMerge(c *SyncClient, path, name string) error {
+	data, err := c.Pull(name)
+	if err != nil {
+		return err
+	}
+
+	var remote SolveHistory
+	if err := json.Unmarshal(data, &remote); err != nil {
+		return fmt.Errorf("remote %s: %s", name, err)
+	}
+
+	h, err := LoadSolveHistory()
+	if err != nil {
+		return err
+	}
+	h.Merge(remote.Solves)
+	if err := h.Save(); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := os.Chtimes(path, now, now); err != nil {
+		return err
+	}
+
+	return syncPush(c, path, name, now)
+}
+
+// runSync runs the sync command.
+func runSync(args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	storeURL := fs.String("url", "", "URL of the remote store, remembered for the next syncs")
+	token := fs.String("token", "", "token to authenticate to the remote store with, remembered for the next syncs")
+	dryRun := fs.Bool("n", false, "only show what would be pushed, pulled and merged")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sync [-url URL] [-token TOKEN] [-n]")
+		fmt.Fprintln(fs.Output(), "Syncs the profiles, the solve history and the replays in the replays directory of the data directory with a remote store.")
+		fmt.Fprintln(fs.Output(), "The newest version of every file wins, except for the solve history, which is merged.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments")
+	}
+
+	s, err := LoadSyncSettings()
+	if err != nil {
+		return err
+	}
+	if *storeURL != "" || *token != "" {
+		if *storeURL != "" {
+			s.URL = *storeURL
+		}
+		if *token != "" {
+			s.Token = *token
+		}
+		if err := s.Save(); err != nil {
+			return err
+		}
+	}
+	if s.URL == "" {
+		return fmt.Errorf("no remote store to sync with, pass one with -url")
+	}
+
+	return Sync(NewSyncClient(s.URL, s.Token), *dryRun, os.Stdout)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+/* Table file
+table  = header payload
+
+header = magic version kind width height entries checksum reserved
+magic    = "LOOPTBL" 0x00
+version  = uint16
+kind     = uint16
+width    = uint16
+height   = uint16
+entries  = uint64   number of entries in the payload
+checksum = uint32   CRC-32 (IEEE) of the payload
+reserved = 4 bytes  zero
+
+payload = { byte }  two 4 bit entries per byte, the even entry in the low bits
+
+Numbers are little-endian. The header takes 32 bytes so the payload is aligned and can be mapped straight into memory.
+*/
+
+// tableMagic starts every table file.
+const tableMagic = "LOOPTBL\x00"
+
+// TableVersion is the version of the table file format written by WriteTable.
+const TableVersion = 1
+
+// tableHeaderSize is the size of a table file's header in bytes.
+const tableHeaderSize = 32
+
+// TableKind tells what a table file holds.
+type TableKind uint16
+
+const (
+	// TablebaseKind tables hold the optimal distance of every arrangement of a board, see Tablebase.
+	TablebaseKind TableKind = iota + 1
+	// PatternDatabaseKind tables hold a lower bound of the distance of every arrangement of some of a board's tiles.
+	PatternDatabaseKind
+)
+
+func (k TableKind) String() string {
+	switch k {
+	case TablebaseKind:
+		return "tablebase"
+	case PatternDatabaseKind:
+		return "pattern database"
+	default:
+		return fmt.Sprintf("unknown kind %d", uint16(k))
+	}
+}
+
+// Table is a table of 4 bit entries for a board size, packed two to a byte.
+type Table struct {
+	Kind          TableKind
+	Width, Height int
+	// Entries is how many entries the table has.
+	Entries int
+	// Data holds the packed entries.
+	Data []byte
+}
+
+// NewTable creates a table with `entries` entries, all set to `fill`.
+func NewTable(kind TableKind, width, height, entries int, fill byte) *Table {
+	data := make([]byte, (entries+1)/2)
+	for i := range data {
+		data[i] = fill | fill<<4
+	}
+
+	return &Table{Kind: kind, Width: width, Height: height, Entries: entries, Data: data}
+}
+
+// Get returns the ith entry.
+func (t *Table) Get(i int) byte {
+	return t.Data[i/2] >> (uint(i%2) * 4) & 0xf
+}
+
+// Set sets the ith entry to `v`, which must fit in 4 bits.
+func (t *Table) Set(i int, v byte) {
+	shift := uint(i%2) * 4
+	t.Data[i/2] = t.Data[i/2]&^(0xf<<shift) | v<<shift
+}
+
+// WriteTable writes a table in the table file format.
+func WriteTable(w io.Writer, t *Table) error {
+	var h [tableHeaderSize]byte
+	copy(h[:], tableMagic)
+	binary.LittleEndian.PutUint16(h[8:], TableVersion)
+	binary.LittleEndian.PutUint16(h[10:], uint16(t.Kind))
+	binary.LittleEndian.PutUint16(h[12:], uint16(t.Width))
+	binary.LittleEndian.PutUint16(h[14:], uint16(t.Height))
+	binary.LittleEndian.PutUint64(h[16:], uint64(t.Entries))
+	binary.LittleEndian.PutUint32(h[24:], crc32.ChecksumIEEE(t.Data))
+
+	if _, err := w.Write(h[:]); err != nil {
+		return err
+	}
+
+	_, err := w.Write(t.Data)
+	return err
+}
+
+// ReadTable reads a table in the table file format, verifying its checksum.
+func ReadTable(r io.Reader) (*Table, error) {
+	var h [tableHeaderSize]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return nil, fmt.Errorf("reading header: %s", err)
+	}
+
+	if !bytes.Equal(h[:8], []byte(tableMagic)) {
+		return nil, fmt.Errorf("not a table file")
+	}
+	if v := binary.LittleEndian.Uint16(h[8:]); v != TableVersion {
+		return nil, fmt.Errorf("unsupported table file version %d, expected %d", v, TableVersion)
+	}
+
+	t := &Table{
+		Kind:    TableKind(binary.LittleEndian.Uint16(h[10:])),
+		Width:   int(binary.LittleEndian.Uint16(h[12:])),
+		Height:  int(binary.LittleEndian.Uint16(h[14:])),
+		Entries: int(binary.LittleEndian.Uint64(h[16:])),
+	}
+
+	t.Data = make([]byte, (t.Entries+1)/2)
+	if _, err := io.ReadFull(r, t.Data); err != nil {
+		return nil, fmt.Errorf("reading %d entries: %s", t.Entries, err)
+	}
+
+	if sum := binary.LittleEndian.Uint32(h[24:]); crc32.ChecksumIEEE(t.Data) != sum {
+		return nil, fmt.Errorf("checksum mismatch, the table is corrupted")
+	}
+
+	return t, nil
+}
+
+// SaveTable writes a table to a file.
+func SaveTable(path string, t *Table) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = WriteTable(f, t)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// LoadTableFile reads a table from a file.
+func LoadTableFile(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t, err := ReadTable(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "tablebase",
+		Summary: "generate a table of optimal solutions for a small board size, or probe it",
+		Run:     runTablebase,
+	})
+}
+
+// MaxTablebaseTiles is the most tiles a board can have for a tablebase of its size to be generated.
+// A tablebase takes half a byte for every arrangement of the tiles, so 10 tiles take 1.8MB.
+const MaxTablebaseTiles = 10
+
+// unknownDistance marks arrangements that are not reachable from the solved board.
+// Distances have to be lower, to fit in a table entry.
+const unknownDistance = 0xf
+
+// Tablebase holds the least number of single shifts that solves every arrangement of a board size.
+// It is stored as a table of TablebaseKind, with the distance of every arrangement indexed by the rank of its tiles.
+type Tablebase struct {
+	*Table
+}
+
+// TablebaseOptions are how a tablebase is generated.
+type TablebaseOptions struct {
+	// Workers is how many goroutines search at once, 1 if not positive.
+	Workers int
+	// Progress is where the progress of the search is reported, if not nil.
+	Progress io.Writer
+	// Partial is the file the table is saved to after every layer of the search, if not empty.
+	// A search started with the table of an interrupted search there resumes from its last layer.
+	Partial string
+}
+
+// tablebaseShard is how many arrangements a worker searches through at a time.
+// It's a multiple of 64 so workers never write to the same word of a Bitset nor the same byte of a table.
+const tablebaseShard = 1 << 16
+
+// GenerateTablebase builds the tablebase of a board size with a breadth-first search from the solved board.
+func GenerateTablebase(width, height int, o TablebaseOptions) (*Tablebase, error) {
+	b, err := NewBoard(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	n := width * height
+	if n > MaxTablebaseTiles {
+		return nil, fmt.Errorf("%dx%d boards have more than %d tiles, too many for a tablebase", width, height, MaxTablebaseTiles)
+	}
+
+	// arrangements are kept packed, so making moves on them is a few bit operations instead of a board allocation.
+	l, err := NewPackedLayout(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := loadPartialTablebase(o.Partial, width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	// the arrangements of the last layer of the search are marked in `frontier`, which is the solved board if starting anew.
+	frontier := NewBitset(factorial(n))
+	var d byte
+	var reached int64
+	if t == nil {
+		t = &Tablebase{NewTable(TablebaseKind, width, height, factorial(n), unknownDistance)}
+		t.Set(l.Rank(l.Solved()), 0)
+		frontier.Add(l.Rank(l.Solved()))
+		reached = 1
+	} else {
+		for r := 0; r < t.Entries; r++ {
+			if e := t.Get(r); e != unknownDistance && e >= d {
+				d = e
+			}
+		}
+		for r := 0; r < t.Entries; r++ {
+			if e := t.Get(r); e != unknownDistance {
+				reached++
+				if e == d {
+					frontier.Add(r)
+				}
+			}
+		}
+	}
+
+	total := int64(t.Entries)
+	if width%2 != 0 && height%2 != 0 {
+		// only arrangements with an even parity can be reached.
+		total /= 2
+	}
+
+	progress := startTablebaseProgress(o.Progress, d, reached, total)
+	defer progress.Stop()
+
+	moves := UnitMoves(&b)
+	next := NewBitset(t.Entries)
+	for {
+		// first every worker marks the unreached arrangements one move away from its share of the frontier in `next`, while the table is only read.
+		tablebaseShards(t.Entries, o.Workers, func(from, to int) {
+			for r := from; r < to; r++ {
+				if !frontier.Has(r) {
+					continue
+				}
+
+				p := l.Unrank(r)
+				for i := range moves {
+					if nr := l.Rank(l.Move(p, &moves[i])); t.Get(nr) == unknownDistance {
+						next.AtomicAdd(nr)
+					}
+				}
+			}
+		})
+
+		// then the arrangements of the new layer are written to the table, every worker writing its own share.
+		var found int64
+		tablebaseShards(t.Entries, o.Workers, func(from, to int) {
+			var f int64
+			for r := from; r < to; r++ {
+				if next.Has(r) {
+					t.Set(r, d+1)
+					f++
+				}
+			}
+			atomic.AddInt64(&found, f)
+		})
+
+		if found == 0 {
+			break
+		}
+		d++
+		if d == unknownDistance {
+			return nil, fmt.Errorf("%dx%d boards take too many moves to solve for a tablebase", width, height)
+		}
+
+		progress.Layer(d, found)
+		if o.Partial != "" {
+			if err := savePartialTablebase(o.Partial, t); err != nil {
+				return nil, err
+			}
+		}
+
+		frontier, next = next, frontier
+		for i := range next {
+			next[i] = 0
+		}
+	}
+
+	return t, nil
+}
+
+// tablebaseShards calls `f` with every tablebaseShard arrangements from 0 to `entries`, on `workers` goroutines at once, and waits for them to finish.
+func tablebaseShards(entries, workers int, f func(from, to int)) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	var shard int64 = -1
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				from := int(atomic.AddInt64(&shard, 1)) * tablebaseShard
+				if from >= entries {
+					return
+				}
+
+				to := from + tablebaseShard
+				if to > entries {
+					to = entries
+				}
+				f(from, to)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// loadPartialTablebase reads the table of an interrupted search from `path`.
+// Returns nil and no error if there is none.
+func loadPartialTablebase(path string, width, height int) (*Tablebase, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	t, err := LoadTableFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Kind != TablebaseKind || t.Width != width || t.Height != height || t.Entries != factorial(width*height) {
+		return nil, fmt.Errorf("%s: not a partial %dx%d tablebase", path, width, height)
+	}
+
+	return &Tablebase{t}, nil
+}
+
+// savePartialTablebase writes the table of a search in progress to `path`.
+// The file is written next to it first and then renamed, so being interrupted while saving doesn't lose the last layer.
+func savePartialTablebase(path string, t *Tablebase) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := SaveTable(tmp, t.Table); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// tablebaseProgress reports the progress of a tablebase search every second, and after every layer.
+type tablebaseProgress struct {
+	w            io.Writer
+	start        time.Time
+	startReached int64
+	total        int64
+	stop         chan struct{}
+
+	mu      sync.Mutex
+	depth   byte
+	reached int64
+}
+
+// startTablebaseProgress starts reporting the progress of a search to `w`, or does nothing if it's nil.
+// The search starts at layer `depth` with `reached` out of `total` arrangements already reached.
+func startTablebaseProgress(w io.Writer, depth byte, reached, total int64) *tablebaseProgress {
+	p := &tablebaseProgress{w: w, start: time.Now(), startReached: reached, total: total, depth: depth, reached: reached, stop: make(chan struct{})}
+	if w == nil {
+		return p
+	}
+
+	if depth != 0 {
+		fmt.Fprintf(w, "Resuming from %d moves\n", depth)
+	}
+
+	go func() {
+		tick := time.NewTicker(time.Second)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-tick.C:
+				p.report()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// Layer records that a layer of `found` arrangements was reached at `depth` moves.
+func (p *tablebaseProgress) Layer(depth byte, found int64) {
+	p.mu.Lock()
+	p.depth = depth
+	p.reached += found
+	p.mu.Unlock()
+
+	p.report()
+}
+
+// report prints the progress of the search on a single line, estimating how long is left from how fast arrangements were reached so far.
+func (p *tablebaseProgress) report() {
+	if p.w == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	eta := "unknown"
+	if done := p.reached - p.startReached; done > 0 {
+		left := time.Duration(float64(time.Since(p.start)) * float64(p.total-p.reached) / float64(done))
+		eta = left.Round(time.Second).String()
+	}
+
+	fmt.Fprintf(p.w, "\r%d moves deep, %d/%d positions (%.1f%%), ETA %s   ", p.depth, p.reached, p.total, 100*float64(p.reached)/float64(p.total), eta)
+}
+
+// Stop stops reporting progress, ending the progress line.
+func (p *tablebaseProgress) Stop() {
+	if p.w == nil {
+		return
+	}
+
+	close(p.stop)
+	fmt.Fprintln(p.w)
+}
+
+// Distance returns the least number of single shifts that solves the board, or false if the board is not of the tablebase's size or can't be solved.
+func (t *Tablebase) Distance(b *Board) (int, bool) {
+	if b.Width() != t.Width || b.Height() != t.Height {
+		return 0, false
+	}
+
+	d := t.Get(rankTiles(b.Tiles()))
+	if d == unknownDistance {
+		return 0, false
+	}
+
+	return int(d), true
+}
+
+// Solve returns a sequence of single shifts that solves the board in the least number of moves.
+func (t *Tablebase) Solve(b *Board) (*SolveResult, error) {
+	start := time.Now()
+
+	d, ok := t.Distance(b)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in the %dx%d tablebase", EncodeState(b), t.Width, t.Height)
+	}
+
+	res := &SolveResult{Nodes: 1, Depth: d, TableProbes: 1, TableHits: 1}
+	moves := UnitMoves(b)
+	p := b.Clone()
+
+	for ; d > 0; d-- {
+		for i := range moves {
+			next := p.Applied(&moves[i])
+
+			nd, ok := t.Distance(&next)
+			res.Nodes++
+			res.TableProbes++
+			if ok {
+				res.TableHits++
+			}
+
+			if nd == d-1 {
+				res.Moves = append(res.Moves, moves[i])
+				p = next
+				break
+			}
+		}
+	}
+
+	res.Time = time.Since(start)
+	return res, nil
+}
+
+// TablebasePath returns the path of the tablebase of a board size in the data directory.
+func TablebasePath(width, height int) (string, error) {
+	dir, err := TablesDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%dx%d.tb", width, height)), nil
+}
+
+// LoadTablebase reads the tablebase of a board size from the data directory.
+func LoadTablebase(width, height int) (*Tablebase, error) {
+	path, err := TablebasePath(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("no %dx%d tablebase, generate it with \"tablebase gen %dx%d\"", width, height, width, height)
+	}
+
+	t, err := LoadTableFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Kind != TablebaseKind || t.Width != width || t.Height != height || t.Entries != factorial(width*height) {
+		return nil, fmt.Errorf("%s: not a %dx%d tablebase", path, width, height)
+	}
+
+	return &Tablebase{t}, nil
+}
+
+// Save writes the tablebase to the data directory.
+func (t *Tablebase) Save() error {
+	path, err := TablebasePath(t.Width, t.Height)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return SaveTable(path, t.Table)
+}
+
+// rankTiles returns the position of an arrangement of the tiles 1 to len(tiles) in the lexicographic order of all arrangements.
+func rankTiles(tiles []int) int {
+	var rank int
+	for i, t := range tiles {
+		smaller := 0
+		for _, u := range tiles[i+1:] {
+			if u < t {
+				smaller++
+			}
+		}
+
+		rank = rank*(len(tiles)-i) + smaller
+	}
+
+	return rank
+}
+
+// unrankTiles returns the arrangement of the tiles 1 to n with the given rank, the inverse of rankTiles.
+func unrankTiles(rank, n int) []int {
+	digits := make([]int, n)
+	for i := n - 1; i >= 0; i-- {
+		digits[i] = rank % (n - i)
+		rank /= n - i
+	}
+
+	left := make([]int, n)
+	for i := range left {
+		left[i] = i + 1
+	}
+
+	tiles := make([]int, n)
+	for i, d := range digits {
+		tiles[i] = left[d]
+		left = append(left[:d], left[d+1:]...)
+	}
+
+	return tiles
+}
+
+// factorial returns n!.
+func factorial(n int) int {
+	f := 1
+	for i := 2; i <= n; i++ {
+		f *= i
+	}
+
+	return f
+}
+
+// runTablebase runs the tablebase command.
+func runTablebase(args []string) error {
+	fs := flag.NewFlagSet("tablebase", flag.ContinueOnError)
+	verbose := fs.Bool("verbose", false, "print statistics on how the solution was found")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of goroutines generating the tablebase at once")
+	prof := AddProfilingFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: tablebase [-workers N] [-cpuprofile FILE] [-memprofile FILE] [-trace FILE] gen SIZE | tablebase [-verbose] probe STATE")
+		fmt.Fprintf(fs.Output(), "gen builds the table of optimal solutions for boards of SIZE, which can have up to %d tiles, into the data directory.\n", MaxTablebaseTiles)
+		fmt.Fprintln(fs.Output(), "An interrupted gen resumes where it was left off when it's run again.")
+		fmt.Fprintln(fs.Output(), "probe prints an optimal solution to the board with the state code STATE.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	defer prof.Stop()
+	if err := prof.Start(); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected an action and its argument")
+	}
+
+	switch fs.Arg(0) {
+	case "gen":
+		w, h, err := ParseTwoDimensions(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+
+		path, err := TablebasePath(w, h)
+		if err != nil {
+			return err
+		}
+
+		t, err := GenerateTablebase(w, h, TablebaseOptions{Workers: *workers, Progress: os.Stderr, Partial: path + ".partial"})
+		if err != nil {
+			return err
+		}
+
+		var reachable, deepest int
+		for i := 0; i < t.Entries; i++ {
+			if d := t.Get(i); d != unknownDistance {
+				reachable++
+				if int(d) > deepest {
+					deepest = int(d)
+				}
+			}
+		}
+		fmt.Printf("%d positions, every one solvable in %d moves or less\n", reachable, deepest)
+
+		if err := t.Save(); err != nil {
+			return err
+		}
+		if err := os.Remove(path + ".partial"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	case "probe":
+		b, err := DecodeState(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+
+		t, err := LoadTablebase(b.Width(), b.Height())
+		if err != nil {
+			return err
+		}
+
+		res, err := t.Solve(&b)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d moves: %s\n", len(res.Moves), MoveFormat{}.FormatMoves(res.Moves, &b))
+		if *verbose {
+			fmt.Println(res)
+		}
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", fs.Arg(0))
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "tables",
+		Summary: "download published tables or list the installed ones",
+		Run:     runTables,
+	})
+}
+
+// TablesDir returns the directory of the data directory where tables are stored.
+func TablesDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "tables"), nil
+}
+
+// FetchTable downloads the table for a board size from `baseURL` into the tables directory, verifying it before installing it.
+// Tables are published as files named like 5x5.tb under the base URL.
+func FetchTable(baseURL string, width, height int) (string, error) {
+	name := fmt.Sprintf("%dx%d.tb", width, height)
+	url := strings.TrimSuffix(baseURL, "/") + "/" + name
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", url, err)
+	}
+
+	t, err := ReadTable(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", url, err)
+	}
+	if t.Width != width || t.Height != height {
+		return "", fmt.Errorf("%s: table is for %dx%d boards", url, t.Width, t.Height)
+	}
+
+	dir, err := TablesDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// write to a temporary file first so an interrupted download doesn't leave a broken table behind.
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return "", err
+	}
+
+	return path, os.Rename(path+".tmp", path)
+}
+
+// runTables runs the tables command.
+func runTables(args []string) error {
+	fs := flag.NewFlagSet("tables", flag.ContinueOnError)
+	url := fs.String("url", os.Getenv("LOOPOVER_TABLES_URL"), "base URL tables are published under, defaults to $LOOPOVER_TABLES_URL")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: tables [-url URL] fetch SIZE | tables ls")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch fs.Arg(0) {
+	case "fetch":
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return fmt.Errorf("expected a board size")
+		}
+		if *url == "" {
+			return fmt.Errorf("no URL to fetch tables from, pass -url or set $LOOPOVER_TABLES_URL")
+		}
+
+		w, h, err := ParseTwoDimensions(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+
+		path, err := FetchTable(*url, w, h)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Installed %s\n", path)
+		return nil
+	case "ls":
+		dir, err := TablesDir()
+		if err != nil {
+			return err
+		}
+
+		paths, err := filepath.Glob(filepath.Join(dir, "*.tb"))
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			fmt.Printf("No tables installed in %s\n", dir)
+			return nil
+		}
+
+		for _, path := range paths {
+			t, err := LoadTableFile(path)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			fmt.Printf("%-10s %dx%d %s, %d entries, %d bytes\n", filepath.Base(path), t.Width, t.Height, t.Kind, t.Entries, tableHeaderSize+len(t.Data))
+		}
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("expected an action")
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "telemetry",
+		Summary: "opt in or out of sending anonymous usage statistics, or show what would be sent",
+		Run:     runTelemetry,
+	})
+}
+
+// TelemetryInterval is the least time between two telemetry reports.
+const TelemetryInterval = 24 * time.Hour
+
+// telemetryTimeout is how long sending a telemetry report can take before it is given up on.
+const telemetryTimeout = 10 * time.Second
+
+// telemetryMu guards the telemetry file, which a report being sent in the background updates once it's sent.
+var telemetryMu sync.Mutex
+
+// Telemetry holds the anonymous usage statistics collected while opted in, saved as JSON in the data directory.
+// Only aggregates are kept: how many boards of every size were solved, and how long and how many moves they took in total.
+type Telemetry struct {
+	path string
+	// Enabled is true if the user opted in. Nothing is collected nor sent otherwise.
+	Enabled bool `json:"enabled"`
+	// URL is the endpoint reports are sent to.
+	URL string `json:"url,omitempty"`
+	// LastReport is when the last report was sent.
+	LastReport time.Time `json:"last_report"`
+	// Sizes holds the statistics of every board size solved since the last report, by size like "5x5".
+	Sizes map[string]*TelemetrySize `json:"sizes,omitempty"`
+}
+
+// TelemetrySize holds the statistics of the solves of a board size.
+type TelemetrySize struct {
+	Solves int `json:"solves"`
+	// Seconds and Moves are the total time and moves the solves took.
+	Seconds float64 `json:"seconds"`
+	Moves   int     `json:"moves"`
+}
+
+// TelemetryReport is what is sent to the telemetry endpoint.
+type TelemetryReport struct {
+	Sizes []TelemetryReportSize `json:"sizes"`
+}
+
+// TelemetryReportSize holds the statistics of a board size in a report.
+type TelemetryReportSize struct {
+	Size           string  `json:"size"`
+	Solves         int     `json:"solves"`
+	AverageSeconds float64 `json:"average_seconds"`
+	AverageMoves   float64 `json:"average_moves"`
+}
+
+// LoadTelemetry reads the telemetry settings and statistics from the data directory. A missing file means telemetry is off.
+func LoadTelemetry() (*Telemetry, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Telemetry{path: filepath.Join(dir, "telemetry.json")}
+
+	data, err := os.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("%s: %s", t.path, err)
+	}
+
+	return t, nil
+}
+
+// Save writes the telemetry settings and statistics to the data directory.
+func (t *Telemetry) Save() error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(t.path, data, 0o644)
+}
+
+// Report returns the report of the statistics collected since the last one, with the sizes in order.
+func (t *Telemetry) Report() TelemetryReport {
+	r := TelemetryReport{Sizes: []TelemetryReportSize{}}
+	for size, s := range t.Sizes {
+		r.Sizes = append(r.Sizes, TelemetryReportSize{
+			Size:           size,
+			Solves:         s.Solves,
+			AverageSeconds: s.Seconds / float64(s.Solves),
+			AverageMoves:   float64(s.Moves) / float64(s.Solves),
+		})
+	}
+
+	sort.Slice(r.Sizes, func(i, j int) bool { return r.Sizes[i].Size < r.Sizes[j].Size })
+	return r
+}
+
+// Due returns true if a report should be sent: telemetry is on, there is something to report and the last report was long enough ago.
+func (t *Telemetry) Due() bool {
+	return t.Enabled && t.URL != "" && len(t.Sizes) != 0 && time.Since(t.LastReport) >= TelemetryInterval
+}
+
+// Send sends a report to the telemetry endpoint.
+func (r TelemetryReport) Send(url string) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: telemetryTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	return nil
+}
+
+// RecordTelemetry adds a solve to the statistics if telemetry is on, doing nothing otherwise.
+func RecordTelemetry(width, height int, elapsed time.Duration, moves int) error {
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+
+	t, err := LoadTelemetry()
+	if err != nil || !t.Enabled {
+		return err
+	}
+
+	if t.Sizes == nil {
+		t.Sizes = map[string]*TelemetrySize{}
+	}
+	size := fmt.Sprintf("%dx%d", width, height)
+	s, ok := t.Sizes[size]
+	if !ok {
+		s = &TelemetrySize{}
+		t.Sizes[size] = s
+	}
+	s.Solves++
+	s.Seconds += elapsed.Seconds()
+	s.Moves += moves
+
+	return t.Save()
+}
+
+// SendTelemetry sends a report of the statistics if one is due, and clears them once it's sent.
+// It's meant to be run in the background, so failures are logged instead of returned, and the report is tried again next time.
+func SendTelemetry() {
+	telemetryMu.Lock()
+	t, err := LoadTelemetry()
+	telemetryMu.Unlock()
+	if err != nil || !t.Due() {
+		return
+	}
+
+	sent := t.Sizes
+	if err := t.Report().Send(t.URL); err != nil {
+		logger.Debug("telemetry report failed", "err", err)
+		return
+	}
+
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+
+	// solves recorded while the report was being sent are kept for the next one.
+	if t, err = LoadTelemetry(); err != nil {
+		return
+	}
+	for size, s := range sent {
+		if cur, ok := t.Sizes[size]; ok {
+			cur.Solves -= s.Solves
+			cur.Seconds -= s.Seconds
+			cur.Moves -= s.Moves
+			if cur.Solves <= 0 {
+				delete(t.Sizes, size)
+			}
+		}
+	}
+	t.LastReport = time.Now()
+
+	if err := t.Save(); err != nil {
+		logger.Warn("could not save telemetry", "err", err)
+	}
+}
+
+// runTelemetry runs the telemetry command.
+func runTelemetry(args []string) error {
+	fs := flag.NewFlagSet("telemetry", flag.ContinueOnError)
+	url := fs.String("url", os.Getenv("LOOPOVER_TELEMETRY_URL"), "endpoint to send reports to when turning telemetry on, defaults to $LOOPOVER_TELEMETRY_URL")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: telemetry [-url URL] on | telemetry off | telemetry status")
+		fmt.Fprintln(fs.Output(), "on opts in to sending the number of solves, average time and average moves of every board size played, at most once a day. Nothing else is collected.")
+		fmt.Fprintln(fs.Output(), "off opts out and deletes the statistics collected so far. status shows whether telemetry is on and the report that would be sent next.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected on, off or status")
+	}
+
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+
+	t, err := LoadTelemetry()
+	if err != nil {
+		return err
+	}
+
+	switch fs.Arg(0) {
+	case "on":
+		if *url == "" {
+			return fmt.Errorf("no endpoint to send reports to, pass one with -url or $LOOPOVER_TELEMETRY_URL")
+		}
+
+		t.Enabled, t.URL = true, *url
+		if err := t.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Telemetry is on, thank you! Reports are sent to %s\n", t.URL)
+	case "off":
+		t.Enabled, t.Sizes = false, nil
+		if err := t.Save(); err != nil {
+			return err
+		}
+		fmt.Println("Telemetry is off, the statistics collected so far were deleted")
+	case "status":
+		if !t.Enabled {
+			fmt.Println(`Telemetry is off, turn it on with "telemetry on"`)
+			return nil
+		}
+
+		fmt.Printf("Telemetry is on, sending reports to %s\n", t.URL)
+		if !t.LastReport.IsZero() {
+			fmt.Printf("Last report sent %s\n", t.LastReport.Format(time.RFC1123))
+		}
+
+		data, err := json.MarshalIndent(t.Report(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Next report:\n%s\n", data)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", fs.Arg(0))
+	}
+
+	return nil
+}
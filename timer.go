@@ -0,0 +1,344 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "timer",
+		Summary: "time solves like a stackmat: hold space to arm, release to start and press any key to stop",
+		Run:     runTimer,
+	})
+}
+
+// TimerState is the state of a KeyTimer.
+type TimerState int
+
+const (
+	// TimerIdle waits for space to be held.
+	TimerIdle TimerState = iota
+	// TimerHolding has space held, but not for long enough to arm. Releasing it goes back to idle.
+	TimerHolding
+	// TimerArmed has space held for long enough that releasing it starts the timer.
+	TimerArmed
+	// TimerRunning is timing a solve, until any key but tab is pressed. Tab marks a split.
+	TimerRunning
+	// TimerStopped has stopped timing a solve.
+	TimerStopped
+)
+
+// KeyTimer emulates a speedcubing timer on the keyboard, from key presses alone: holding space arms it, releasing space starts it and pressing any key stops it.
+// While running, tab marks the end of a phase of the solve instead.
+// Terminals only report key presses, repeating them while a key is held, so space is known to be released once it stops repeating.
+type KeyTimer struct {
+	State TimerState
+	// ArmAfter is how long space has to be held to arm the timer, like the 0.55 seconds of a stackmat.
+	ArmAfter time.Duration
+	// RepeatDelay is the longest terminals wait before repeating a held key, and RepeatGap the longest they wait between repeats.
+	RepeatDelay, RepeatGap time.Duration
+	// Start and Stop are when the timer started and stopped.
+	Start, Stop time.Time
+	// Splits are when the phases of the solve that was timed last ended, as marked with tab.
+	Splits []time.Time
+
+	pressed, last time.Time
+	repeated      bool
+}
+
+// NewKeyTimer returns an idle KeyTimer with the repeat timings of common terminals.
+func NewKeyTimer() *KeyTimer {
+	return &KeyTimer{ArmAfter: 550 * time.Millisecond, RepeatDelay: 700 * time.Millisecond, RepeatGap: 150 * time.Millisecond}
+}
+
+// Key handles a key pressed at `t`. Returns true if it changed the state of the timer.
+func (k *KeyTimer) Key(key byte, t time.Time) bool {
+	switch k.State {
+	case TimerIdle, TimerStopped:
+		if key == ' ' {
+			k.State = TimerHolding
+			k.pressed, k.last, k.repeated = t, t, false
+			return true
+		}
+	case TimerHolding, TimerArmed:
+		if key != ' ' {
+			return false
+		}
+
+		k.last, k.repeated = t, true
+		if k.State == TimerHolding && t.Sub(k.pressed) >= k.ArmAfter {
+			k.State = TimerArmed
+			return true
+		}
+	case TimerRunning:
+		if key == '\t' {
+			k.Splits = append(k.Splits, t)
+		} else {
+			k.State, k.Stop = TimerStopped, t
+		}
+		return true
+	}
+
+	return false
+}
+
+// Tick handles time passing until `t` with no key pressed, releasing space once it stopped repeating. Returns true if it changed the state of the timer.
+// The timer starts from the last time space was seen held, as it was released soon after.
+func (k *KeyTimer) Tick(t time.Time) bool {
+	if k.State != TimerHolding && k.State != TimerArmed {
+		return false
+	}
+
+	gap := k.RepeatGap
+	if !k.repeated {
+		gap = k.RepeatDelay
+	}
+	if t.Sub(k.last) < gap {
+		return false
+	}
+
+	if k.State == TimerArmed {
+		k.State, k.Start, k.Splits = TimerRunning, k.last, nil
+	} else {
+		k.State = TimerIdle
+	}
+	return true
+}
+
+// Elapsed returns the time on the timer at `t`.
+func (k *KeyTimer) Elapsed(t time.Time) time.Duration {
+	switch k.State {
+	case TimerRunning:
+		return t.Sub(k.Start)
+	case TimerStopped:
+		return k.Stop.Sub(k.Start)
+	default:
+		return 0
+	}
+}
+
+// rawTerminal makes the terminal `f` report keys as soon as they are pressed, without echoing them, using stty. Returns a function that restores it.
+func rawTerminal(f *os.File) (func(), error) {
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("reading single keys is not available on %s", runtime.GOOS)
+	}
+
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = f
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+
+	state, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("could not read the terminal settings (%s)", err)
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, fmt.Errorf("could not change the terminal settings (%s)", err)
+	}
+
+	return func() { stty(state) }, nil
+}
+
+// keyPress is a key read from the terminal, along with when it was read.
+type keyPress struct {
+	Key  byte
+	Time time.Time
+}
+
+// readKeys reads keys from `f` until it ends, sending them to the returned channel, which is then closed.
+func readKeys(f *os.File) <-chan keyPress {
+	keys := make(chan keyPress)
+	go func() {
+		defer close(keys)
+
+		buf := make([]byte, 1)
+		for {
+			if _, err := f.Read(buf); err != nil {
+				return
+			}
+			keys <- keyPress{Key: buf[0], Time: time.Now()}
+		}
+	}()
+
+	return keys
+}
+
+// timerScramble scrambles a board to a random state, returning the moves that scramble a solved board to it, to scramble a puzzle outside the game.
+func timerScramble(width, height int) (Board, []Move, error) {
+	b, err := NewBoard(width, height)
+	if err != nil {
+		return nil, nil, err
+	}
+	RandomStateScramble.Scramble(&b)
+
+	res, err := SolveHuman(&b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return b, SimplifyMoves(InverseMoves(res.Moves), &b), nil
+}
+
+// timerSplit formats the split of the ith phase marked on the timer, like "Split 2: 4.20 (+1.73)": the time it ended at, followed by how long it took.
+func timerSplit(k *KeyTimer, i int) string {
+	start := k.Start
+	if i > 0 {
+		start = k.Splits[i-1]
+	}
+
+	return fmt.Sprintf("Split %d: %.2f (+%.2f)", i+1, k.Splits[i].Sub(k.Start).Seconds(), k.Splits[i].Sub(start).Seconds())
+}
+
+// runTimer runs the timer command.
+func runTimer(args []string) error {
+	fs := flag.NewFlagSet("timer", flag.ContinueOnError)
+	size := fs.String("size", "5x5", "size of the boards to scramble")
+	inspection := fs.Duration("inspection", 0, "inspect every scramble for up to this long before starting the timer, like 15s, with a +2 for starting late and a DNF for starting more than 2s late")
+	player := fs.String("player", "", "name to record the solves under, the one of the config by default")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: timer [-size 5x5] [-inspection 15s] [-player NAME]")
+		fmt.Fprintln(fs.Output(), "Times solves made outside the game like a speedcubing timer: shows a scramble, then holding space arms the timer, releasing it starts it and pressing any key stops it.")
+		fmt.Fprintln(fs.Output(), "Pressing tab while timing marks the end of a phase, showing its split.")
+		fmt.Fprintln(fs.Output(), "Every solve is recorded along with its scramble. Press q to quit.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	w, h, err := ParseTwoDimensions(*size)
+	if err != nil {
+		return err
+	}
+
+	if *player == "" {
+		*player = "player"
+		if c, err := LoadConfig(); err == nil && c.Player != "" {
+			*player = c.Player
+		}
+	}
+	history, err := LoadSolveHistory()
+	if err != nil {
+		return err
+	}
+
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("the timer reads keys from a terminal")
+	}
+	restore, err := rawTerminal(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	var scramble Board
+	var inspectionStart time.Time
+	next := func() error {
+		b, seq, err := timerScramble(w, h)
+		if err != nil {
+			return err
+		}
+
+		scramble, inspectionStart = b, time.Now()
+		fmt.Printf("\nScramble: %s\n%s\n", MoveFormat{}.FormatMoves(seq, &b), SprintBoard(&b))
+		return nil
+	}
+	if err := next(); err != nil {
+		return err
+	}
+
+	k := NewKeyTimer()
+	keys := readKeys(os.Stdin)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	var status string
+	var solves int
+	for {
+		now := time.Now()
+		select {
+		case kp, ok := <-keys:
+			if !ok || (k.State == TimerIdle || k.State == TimerStopped) && (kp.Key == 'q' || kp.Key == 4) {
+				fmt.Printf("\nRecorded %d solves\n", solves)
+				return nil
+			}
+			now = kp.Time
+			k.Key(kp.Key, now)
+			if k.State == TimerRunning && len(k.Splits) != 0 && k.Splits[len(k.Splits)-1] == now {
+				fmt.Printf("\r\x1b[K%s\n", timerSplit(k, len(k.Splits)-1))
+				status = ""
+			}
+		case now = <-ticker.C:
+			k.Tick(now)
+		case <-interrupt:
+			fmt.Printf("\nRecorded %d solves\n", solves)
+			return nil
+		}
+
+		if k.State == TimerStopped {
+			var p Penalty
+			if *inspection > 0 {
+				p = InspectionPenalty(k.Start.Sub(inspectionStart), *inspection)
+			}
+
+			r := SolveRecord{
+				Date:     k.Stop,
+				Player:   *player,
+				Size:     *size,
+				Scramble: EncodeState(&scramble),
+				Seconds:  k.Elapsed(now).Seconds(),
+				Penalty:  p.String(),
+			}
+			fmt.Printf("\r\x1b[K%s\n", r.FormatResult())
+			if len(k.Splits) != 0 {
+				k.Splits = append(k.Splits, k.Stop)
+				for i := range k.Splits {
+					fmt.Println(timerSplit(k, i))
+				}
+			}
+
+			history.Merge([]SolveRecord{r})
+			if err := history.Save(); err != nil {
+				return err
+			}
+			solves++
+
+			if err := next(); err != nil {
+				return err
+			}
+			k.State, status = TimerIdle, ""
+			continue
+		}
+
+		var s string
+		switch k.State {
+		case TimerIdle:
+			s = "Hold space to arm the timer, q to quit"
+			if *inspection > 0 {
+				s = fmt.Sprintf("Inspecting: %.0fs of %s, hold space to arm the timer", time.Since(inspectionStart).Seconds(), *inspection)
+			}
+		case TimerHolding:
+			s = "Keep holding..."
+		case TimerArmed:
+			s = "Release to start"
+		case TimerRunning:
+			s = fmt.Sprintf("%.2f", k.Elapsed(now).Seconds())
+		}
+		if s != status {
+			status = s
+			fmt.Printf("\r\x1b[K%s", s)
+		}
+	}
+}
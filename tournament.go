@@ -0,0 +1,628 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "tournament",
+		Summary: "run a single or double elimination tournament: register players, draw the bracket, record results and print standings",
+		Run:     runTournament,
+	})
+}
+
+// Tournament is an elimination tournament, saved as JSON in the tournaments directory of the data directory so that it can be run over several sessions.
+type Tournament struct {
+	path string
+	Name string `json:"name"`
+	// Size is the size of the boards played on, like 5x5.
+	Size string `json:"size"`
+	// Double is true for a double elimination tournament, where players are out after losing two matches instead of one.
+	Double bool `json:"double"`
+	// Players are the registered players, in the order of their seeds once the tournament started.
+	Players []string `json:"players"`
+	// Scrambles holds the state code of the scramble of every round, by the name of the round, so that every match of a round is played on the same scramble.
+	Scrambles map[string]string `json:"scrambles,omitempty"`
+	// Matches are the matches of the bracket, drawn when the tournament starts, every one only depending on matches before it.
+	Matches []*Match `json:"matches,omitempty"`
+}
+
+// Match is a race between two players of a tournament.
+type Match struct {
+	Round string `json:"round"`
+	// From holds where both players come from: "seed N" for the Nth seed, or "winner N" or "loser N" for the winner or loser of the Nth match, counted from 1.
+	From [2]string `json:"from"`
+	// Played is true once the match was played, and Times then holds how many seconds both players took to solve the scramble, 0 for an unfinished solve.
+	Played bool       `json:"played"`
+	Times  [2]float64 `json:"times,omitempty"`
+	// Reset is true for the reset of the grand final of a double elimination tournament, between the players of the match before it,
+	// which is only played if the player coming from the losers' bracket won the grand final, handing the other player their first loss.
+	Reset bool `json:"reset,omitempty"`
+}
+
+// TournamentPath returns the path of the file of a tournament in the data directory.
+func TournamentPath(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\.`) {
+		return "", fmt.Errorf("invalid tournament name %q", name)
+	}
+
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "tournaments", name+".json"), nil
+}
+
+// LoadTournament reads a tournament from the data directory.
+func LoadTournament(name string) (*Tournament, error) {
+	path, err := TournamentPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no tournament named %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tournament{path: path}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return t, nil
+}
+
+// Save writes the tournament to the data directory.
+func (t *Tournament) Save() error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(t.path, data, 0o644)
+}
+
+// Started returns true if the bracket was drawn.
+func (t *Tournament) Started() bool {
+	return len(t.Matches) != 0
+}
+
+// seedOrder returns the seeds of the first round of a bracket of `n` slots, a power of 2, pair by pair, so that the best seeds only meet in the last rounds, like 1, 8, 4, 5, 2, 7, 3, 6.
+func seedOrder(n int) []int {
+	order := []int{1}
+	for len(order) < n {
+		next := make([]int, 0, 2*len(order))
+		for _, s := range order {
+			next = append(next, s, 2*len(order)+1-s)
+		}
+		order = next
+	}
+
+	return order
+}
+
+// Start draws the bracket, seeding the players from the highest to the lowest rating among the profiles, and picks the scramble of every round.
+// The best seeds get byes in the first round when the number of players isn't a power of 2.
+func (t *Tournament) Start(p *Profiles) error {
+	if t.Started() {
+		return fmt.Errorf("the tournament already started")
+	}
+	if len(t.Players) < 2 {
+		return fmt.Errorf("a tournament needs at least 2 players")
+	}
+	w, h, err := ParseTwoDimensions(t.Size)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(t.Players, func(i, j int) bool {
+		return p.Get(t.Players[i]).Rating > p.Get(t.Players[j]).Rating
+	})
+
+	rounds := 1
+	for 1<<rounds < len(t.Players) {
+		rounds++
+	}
+
+	add := func(round, a, b string) int {
+		t.Matches = append(t.Matches, &Match{Round: round, From: [2]string{a, b}})
+		return len(t.Matches)
+	}
+	ref := func(kind string, match int) string {
+		return kind + " " + strconv.Itoa(match)
+	}
+
+	// winners holds the matches of every round of the winners' bracket, which is the whole bracket in single elimination.
+	winners := make([][]int, rounds)
+	order := seedOrder(1 << rounds)
+	for r := range winners {
+		round := fmt.Sprintf("Round %d", r+1)
+		if t.Double {
+			round = fmt.Sprintf("Winners %d", r+1)
+		}
+		if r == rounds-1 {
+			round = "Final"
+			if t.Double {
+				round = "Winners final"
+			}
+		}
+
+		for i := 0; i < 1<<(rounds-1-r); i++ {
+			if r == 0 {
+				winners[r] = append(winners[r], add(round, ref("seed", order[2*i]), ref("seed", order[2*i+1])))
+			} else {
+				winners[r] = append(winners[r], add(round, ref("winner", winners[r-1][2*i]), ref("winner", winners[r-1][2*i+1])))
+			}
+		}
+	}
+
+	if t.Double {
+		// the losers of every round of the winners' bracket drop into the losers' bracket, where they first play the players still in it,
+		// and then the winners of those matches play each other, until the winner of the losers' final meets the winner of the winners' final.
+		final := ref("loser", winners[0][0])
+		if rounds > 1 {
+			var losers [][]int
+			name := func() string { return fmt.Sprintf("Losers %d", len(losers)+1) }
+
+			var first []int
+			for i := 0; i < len(winners[0]); i += 2 {
+				first = append(first, add(name(), ref("loser", winners[0][i]), ref("loser", winners[0][i+1])))
+			}
+			losers = append(losers, first)
+
+			for r := 1; r < rounds; r++ {
+				var drop []int
+				round := name()
+				if r == rounds-1 {
+					round = "Losers final"
+				}
+				prev, in := losers[len(losers)-1], winners[r]
+				for i := range prev {
+					// players dropping in meet the other half of the losers' bracket, to put off rematches.
+					drop = append(drop, add(round, ref("winner", prev[i]), ref("loser", in[len(in)-1-i])))
+				}
+				losers = append(losers, drop)
+
+				if r < rounds-1 {
+					var next []int
+					round := name()
+					for i := 0; i < len(drop); i += 2 {
+						next = append(next, add(round, ref("winner", drop[i]), ref("winner", drop[i+1])))
+					}
+					losers = append(losers, next)
+				}
+			}
+			final = ref("winner", losers[len(losers)-1][0])
+		}
+		grand := add("Grand final", ref("winner", winners[rounds-1][0]), final)
+		t.Matches = append(t.Matches, &Match{Round: "Grand final reset", From: [2]string{ref("winner", grand), ref("loser", grand)}, Reset: true})
+	}
+
+	board, err := NewBoard(w, h)
+	if err != nil {
+		return err
+	}
+	t.Scrambles = map[string]string{}
+	for _, m := range t.Matches {
+		if _, ok := t.Scrambles[m.Round]; !ok {
+			board.uniformShuffle()
+			t.Scrambles[m.Round] = EncodeState(&board)
+		}
+	}
+
+	return nil
+}
+
+// matchState is what is known of a match from the matches before it.
+type matchState struct {
+	// Players are the players of the match, with an empty name for a bye, once Known.
+	Players [2]string
+	Known   bool
+	// Winner and Loser are the winner and loser of the match, once Decided. A player facing a bye wins without playing, and the loser is then a bye.
+	Winner, Loser string
+	Decided       bool
+	// Skipped is true for a reset of the grand final that isn't played, because the player coming from the winners' bracket won the grand final.
+	// The winner of the grand final then wins it without a loser.
+	Skipped bool
+}
+
+// states returns what is known of every match, deciding the matches that were played or have a bye, in order.
+func (t *Tournament) states() []matchState {
+	states := make([]matchState, len(t.Matches))
+
+	// player returns the player that comes from a source of a match, as written in Match.From, or false if it isn't known yet.
+	player := func(from string) (string, bool) {
+		var kind string
+		var n int
+		if _, err := fmt.Sscanf(from, "%s %d", &kind, &n); err != nil || n < 1 {
+			return "", false
+		}
+
+		switch {
+		case kind == "seed" && n > len(t.Players):
+			return "", true
+		case kind == "seed":
+			return t.Players[n-1], true
+		case kind == "winner" && n <= len(states):
+			return states[n-1].Winner, states[n-1].Decided
+		case kind == "loser" && n <= len(states):
+			return states[n-1].Loser, states[n-1].Decided
+		default:
+			return "", false
+		}
+	}
+
+	for i, m := range t.Matches {
+		s := &states[i]
+		a, okA := player(m.From[0])
+		b, okB := player(m.From[1])
+		s.Players, s.Known = [2]string{a, b}, okA && okB
+
+		switch {
+		case !s.Known:
+		case m.Reset && (b == "" || a == states[i-1].Players[0]):
+			s.Winner, s.Decided, s.Skipped = a, true, true
+		case a == "" || b == "":
+			s.Winner, s.Decided = a+b, true
+		case m.Played:
+			r := RaceResult{Player: a, Opponent: b, PlayerTime: time.Duration(m.Times[0] * float64(time.Second)), OpponentTime: time.Duration(m.Times[1] * float64(time.Second))}
+			s.Winner, s.Loser, s.Decided = b, a, true
+			if r.Score() == 1 {
+				s.Winner, s.Loser = a, b
+			}
+		}
+	}
+
+	return states
+}
+
+// Winner returns the winner of the tournament, or false if it isn't over.
+func (t *Tournament) Winner() (string, bool) {
+	if !t.Started() {
+		return "", false
+	}
+
+	last := t.states()[len(t.Matches)-1]
+	return last.Winner, last.Decided
+}
+
+// Record records the result of the match between two players that is waiting to be played, where a time of 0 is an unfinished solve.
+// Returns the number of the match, counted from 1.
+func (t *Tournament) Record(r RaceResult) (int, error) {
+	if r.Score() == 0.5 {
+		return 0, fmt.Errorf("a match can't end in a draw, race again")
+	}
+
+	for i, s := range t.states() {
+		m := t.Matches[i]
+		a, b := s.Players[0], s.Players[1]
+		if !s.Known || s.Skipped || m.Played || a == "" || b == "" {
+			continue
+		}
+
+		switch {
+		case a == r.Player && b == r.Opponent:
+			m.Times = [2]float64{r.PlayerTime.Seconds(), r.OpponentTime.Seconds()}
+		case a == r.Opponent && b == r.Player:
+			m.Times = [2]float64{r.OpponentTime.Seconds(), r.PlayerTime.Seconds()}
+		default:
+			continue
+		}
+		m.Played = true
+		return i + 1, nil
+	}
+
+	return 0, fmt.Errorf("%s and %s have no match waiting to be played", r.Player, r.Opponent)
+}
+
+// Standing is the place of a player in a tournament.
+type Standing struct {
+	Place  int
+	Player string
+	// Wins and Losses count the matches played, byes left out.
+	Wins, Losses int
+	// Status is "winner", "still in", or "out in" followed by the round the player was eliminated in.
+	Status string
+}
+
+// Standings returns the standings of every player: the winner first, then the players still in the tournament,
+// and then the eliminated players from the last to the first eliminated, where players eliminated in the same round share their place.
+func (t *Tournament) Standings() []Standing {
+	// rank holds how far every player went: the index of the first match of the round they were eliminated in,
+	// and past the last match for the players still in, and further for the winner.
+	rank := map[string]int{}
+	for _, p := range t.Players {
+		rank[p] = len(t.Matches)
+	}
+	wins, losses := map[string]int{}, map[string]int{}
+	first := map[string]int{}
+
+	states := t.states()
+	for i, s := range states {
+		if _, ok := first[t.Matches[i].Round]; !ok {
+			first[t.Matches[i].Round] = i
+		}
+		if !s.Decided || s.Loser == "" {
+			continue
+		}
+		wins[s.Winner]++
+		losses[s.Loser]++
+
+		// in double elimination, the losers of the winners' bracket drop into the losers' bracket instead of being eliminated,
+		// and the loser of the grand final plays the reset, if it is played.
+		dropped := false
+		for j, m := range t.Matches[i+1:] {
+			if states[i+1+j].Skipped {
+				continue
+			}
+			dropped = dropped || m.From[0] == "loser "+strconv.Itoa(i+1) || m.From[1] == "loser "+strconv.Itoa(i+1)
+		}
+		if !dropped {
+			rank[s.Loser] = first[t.Matches[i].Round]
+		}
+	}
+	if winner, ok := t.Winner(); ok {
+		rank[winner] = len(t.Matches) + 1
+	}
+
+	standings := make([]Standing, len(t.Players))
+	for i, p := range t.Players {
+		standings[i] = Standing{Player: p, Wins: wins[p], Losses: losses[p], Status: "still in"}
+		switch r := rank[p]; {
+		case r > len(t.Matches):
+			standings[i].Status = "winner"
+		case r < len(t.Matches):
+			standings[i].Status = "out in " + t.Matches[r].Round
+		}
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		return rank[standings[i].Player] > rank[standings[j].Player]
+	})
+	for i := range standings {
+		standings[i].Place = i + 1
+		if i != 0 && rank[standings[i].Player] == rank[standings[i-1].Player] {
+			standings[i].Place = standings[i-1].Place
+		}
+	}
+
+	return standings
+}
+
+// formatTime formats the time of a player in a match, in seconds, or DNF for an unfinished solve.
+func formatTime(seconds float64) string {
+	if seconds == 0 {
+		return "DNF"
+	}
+	return fmt.Sprintf("%.2fs", seconds)
+}
+
+// runTournament runs the tournament command.
+func runTournament(args []string) error {
+	fs := flag.NewFlagSet("tournament", flag.ContinueOnError)
+	size := fs.String("size", "5x5", "board size, for new")
+	double := fs.Bool("double", false, "double elimination instead of single elimination, for new")
+	seed := fs.Int64("seed", 0, "seed of the scrambles, for start, to repeat a draw; without one, scrambles are unpredictable with -crypto-rand")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: tournament list | tournament [-size 5x5] [-double] new NAME | tournament add NAME PLAYER... | tournament [-seed N] start NAME")
+		fmt.Fprintln(fs.Output(), "       tournament show NAME | tournament result NAME PLAYER TIME OPPONENT TIME | tournament standings NAME")
+		fmt.Fprintln(fs.Output(), "Runs an elimination tournament over as many sessions as it takes. Players are added before it starts, and start draws the bracket, seeding the players by rating,")
+		fmt.Fprintln(fs.Output(), "and picks a scramble for every round, which every match of the round is played on. Results are recorded like with the rating command, where a time of 0 means the solve wasn't finished.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected an action")
+	}
+
+	action := fs.Arg(0)
+	if action == "list" {
+		dir, err := DataDir()
+		if err != nil {
+			return err
+		}
+		paths, err := filepath.Glob(filepath.Join(dir, "tournaments", "*.json"))
+		if err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			t, err := LoadTournament(strings.TrimSuffix(filepath.Base(path), ".json"))
+			if err != nil {
+				return err
+			}
+
+			state := "registering players"
+			if winner, ok := t.Winner(); ok {
+				state = "won by " + winner
+			} else if t.Started() {
+				state = "in progress"
+			}
+			kind := "single"
+			if t.Double {
+				kind = "double"
+			}
+			fmt.Printf("%-20s %s elimination on %s, %d players, %s\n", t.Name, kind, t.Size, len(t.Players), state)
+		}
+		return nil
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("expected the name of a tournament")
+	}
+
+	name := fs.Arg(1)
+	if action == "new" {
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return fmt.Errorf("expected the name of the tournament")
+		}
+		if _, _, err := ParseTwoDimensions(*size); err != nil {
+			return err
+		}
+
+		path, err := TournamentPath(name)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("a tournament named %q already exists", name)
+		}
+
+		t := &Tournament{path: path, Name: name, Size: *size, Double: *double}
+		return t.Save()
+	}
+
+	t, err := LoadTournament(name)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "add":
+		if t.Started() {
+			return fmt.Errorf("the tournament already started")
+		}
+		if fs.NArg() < 3 {
+			fs.Usage()
+			return fmt.Errorf("expected players to add")
+		}
+
+		for _, p := range fs.Args()[2:] {
+			for _, q := range t.Players {
+				if p == q {
+					return fmt.Errorf("%s is already registered", p)
+				}
+			}
+			t.Players = append(t.Players, p)
+		}
+		fmt.Printf("%d players registered\n", len(t.Players))
+		return t.Save()
+	case "start":
+		p, err := LoadProfiles()
+		if err != nil {
+			return err
+		}
+
+		// without a seed, scrambles come from the usual source, which is crypto/rand with -crypto-rand.
+		if *seed != 0 {
+			rng = rand.New(rand.NewSource(*seed))
+			fmt.Printf("Seed %d\n", *seed)
+		}
+
+		if err := t.Start(p); err != nil {
+			return err
+		}
+		if err := t.Save(); err != nil {
+			return err
+		}
+	case "show":
+	case "result":
+		if fs.NArg() != 6 {
+			fs.Usage()
+			return fmt.Errorf("expected a player, a time, an opponent and a time")
+		}
+		if !t.Started() {
+			return fmt.Errorf("the tournament didn't start yet")
+		}
+
+		r := RaceResult{Player: fs.Arg(2), Opponent: fs.Arg(4)}
+		if r.PlayerTime, err = time.ParseDuration(fs.Arg(3)); err != nil {
+			return err
+		}
+		if r.OpponentTime, err = time.ParseDuration(fs.Arg(5)); err != nil {
+			return err
+		}
+
+		n, err := t.Record(r)
+		if err != nil {
+			return err
+		}
+		if err := t.Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Match %d won by %s\n", n, t.states()[n-1].Winner)
+		if winner, ok := t.Winner(); ok {
+			fmt.Printf("%s wins the tournament\n", winner)
+		}
+		return nil
+	case "standings":
+		if !t.Started() {
+			return fmt.Errorf("the tournament didn't start yet")
+		}
+
+		for _, s := range t.Standings() {
+			fmt.Printf("%3d. %-20s %d-%d, %s\n", s.Place, s.Player, s.Wins, s.Losses, s.Status)
+		}
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+
+	// show the bracket, which start does too once it's drawn.
+	if !t.Started() {
+		fmt.Printf("%s, registering players: %s\n", t.Name, strings.Join(t.Players, ", "))
+		return nil
+	}
+
+	round := ""
+	for i, s := range t.states() {
+		m := t.Matches[i]
+		if m.Round != round {
+			round = m.Round
+			fmt.Printf("%s, scramble %s\n", round, t.Scrambles[round])
+		}
+
+		// players that aren't known yet are shown by where they come from, like "(winner 3)".
+		names := s.Players
+		for j := range names {
+			if !s.Known && names[j] == "" {
+				names[j] = "(" + m.From[j] + ")"
+			}
+		}
+
+		switch {
+		case s.Skipped:
+			fmt.Printf("  %2d. not played, %s won the grand final undefeated\n", i+1, s.Winner)
+		case m.Reset && !s.Known:
+			fmt.Printf("  %2d. %s vs %s, if the player from the losers' bracket wins the grand final\n", i+1, names[0], names[1])
+		case s.Known && (names[0] == "" || names[1] == ""):
+			if names[0]+names[1] == "" {
+				fmt.Printf("  %2d. byes on both sides\n", i+1)
+			} else {
+				fmt.Printf("  %2d. %s gets a bye\n", i+1, names[0]+names[1])
+			}
+		case m.Played:
+			fmt.Printf("  %2d. %s %s vs %s %s, won by %s\n", i+1, names[0], formatTime(m.Times[0]), names[1], formatTime(m.Times[1]), s.Winner)
+		case s.Known:
+			fmt.Printf("  %2d. %s vs %s, to play\n", i+1, names[0], names[1])
+		default:
+			fmt.Printf("  %2d. %s vs %s\n", i+1, names[0], names[1])
+		}
+	}
+	return nil
+}
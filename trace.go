@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "trace",
+		Summary: "record a search for the shortest solution, replay it step by step and print its tree down a line of moves",
+		Run:     runTrace,
+	})
+}
+
+/* Search trace file
+A line of JSON with the scramble, MaxMoves, Seed and Moves of the trace, followed by a line of JSON for every position searched, in order, like
+{"limit":5,"depth":2,"move":7,"bound":2,"outcome":"expanded"}
+*/
+
+// SearchTrace records the positions SolveWithinSeeded searches, in the order it searches them, so that a search can be replayed and its tree inspected.
+type SearchTrace struct {
+	// Scramble is the state code of the board searched, MaxMoves the most moves a solution was looked for in, and Seed the seed of the order moves are tried in.
+	Scramble string `json:"scramble"`
+	MaxMoves int    `json:"max_moves"`
+	Seed     int64  `json:"seed"`
+	// Moves are the single shifts tried from every position, in Programmer's Notation, in the order they are tried.
+	Moves []string `json:"moves"`
+	// Truncated is true if the search went through more than MaxTraceNodes positions, of which only the first ones were recorded.
+	Truncated bool `json:"truncated,omitempty"`
+
+	Nodes []TraceNode `json:"-"`
+}
+
+// MaxTraceNodes is the most positions a trace records. Searches through millions of positions take seconds, but their traces would take gigabytes.
+const MaxTraceNodes = 1 << 20
+
+// Outcomes of a position in a search.
+const (
+	// SolvedNode is a solved position, which ends the search.
+	SolvedNode = "solved"
+	// PrunedNode is a position whose lower bound is past the moves left in the iteration, so no move from it was searched.
+	PrunedNode = "pruned"
+	// ExpandedNode is a position the moves from which were searched.
+	ExpandedNode = "expanded"
+)
+
+// TraceNode is a position that was searched.
+type TraceNode struct {
+	// Limit is the most moves of the iteration of the search the position was searched in, and Depth how many moves it is from the scramble.
+	Limit int `json:"limit"`
+	Depth int `json:"depth"`
+	// Move is the index in Moves of the move that led to the position, or -1 for the scramble.
+	Move int `json:"move"`
+	// Bound is the lower bound of the moves solving the position takes, or 0 if it is solved.
+	Bound   int    `json:"bound"`
+	Outcome string `json:"outcome"`
+}
+
+// start resets the trace for a search of the board with the moves, in the order they are tried.
+func (t *SearchTrace) start(b *Board, maxMoves int, seed int64, moves []Move) {
+	if t == nil {
+		return
+	}
+
+	*t = SearchTrace{Scramble: EncodeState(b), MaxMoves: maxMoves, Seed: seed, Moves: make([]string, len(moves))}
+	for i, m := range moves {
+		t.Moves[i] = m.String()
+	}
+}
+
+// visit adds a searched position to the trace, unless it already has MaxTraceNodes positions.
+func (t *SearchTrace) visit(limit, depth, move, bound int, outcome string) {
+	if t == nil {
+		return
+	}
+	if len(t.Nodes) == MaxTraceNodes {
+		t.Truncated = true
+		return
+	}
+
+	t.Nodes = append(t.Nodes, TraceNode{Limit: limit, Depth: depth, Move: move, Bound: bound, Outcome: outcome})
+}
+
+// puzzle returns the scramble of the trace with its moves, in the order they were tried.
+func (t *SearchTrace) puzzle() (*boardPuzzle, error) {
+	b, err := DecodeState(t.Scramble)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &boardPuzzle{board: b, moves: make([]Move, len(t.Moves))}
+	for i, s := range t.Moves {
+		seq, err := ParseMoves(s, &b)
+		if err != nil {
+			return nil, err
+		}
+		if len(seq) != 1 || Abs(seq[0].Amount) != 1 {
+			return nil, fmt.Errorf("%q is not a single shift", s)
+		}
+		p.moves[i] = seq[0]
+	}
+
+	return p, nil
+}
+
+// Replay searches the scramble of the trace again, with its moves in the same order, and returns the index of the first position that was searched differently,
+// or -1 if the search went exactly the same way.
+func (t *SearchTrace) Replay() (int, error) {
+	p, err := t.puzzle()
+	if err != nil {
+		return 0, err
+	}
+
+	again := &SearchTrace{}
+	searchWithin(p, t.MaxMoves, again)
+
+	for i, n := range t.Nodes {
+		if i == len(again.Nodes) || again.Nodes[i] != n {
+			return i, nil
+		}
+	}
+	if len(again.Nodes) != len(t.Nodes) {
+		return len(t.Nodes), nil
+	}
+
+	return -1, nil
+}
+
+// Path returns the indices in Moves of the moves leading from the scramble to the ith position.
+func (t *SearchTrace) Path(i int) []int {
+	// positions are recorded before the ones searched from them, so the last position of every depth before the ith one leads to it.
+	d := t.Nodes[i].Depth
+	path := make([]int, d)
+	for j := i; d > 0; j-- {
+		if t.Nodes[j].Depth == d {
+			path[d-1] = t.Nodes[j].Move
+			d--
+		}
+	}
+
+	return path
+}
+
+// FormatPath formats a path of indices in Moves as moves separated by spaces.
+func (t *SearchTrace) FormatPath(path []int) string {
+	moves := make([]string, len(path))
+	for i, m := range path {
+		moves[i] = t.Moves[m]
+	}
+
+	return strings.Join(moves, " ")
+}
+
+// children returns the indices of the positions searched right after the ith one, and how many positions were searched from it, itself included.
+func (t *SearchTrace) children(i int) ([]int, int) {
+	var children []int
+	n := t.Nodes[i]
+	j := i + 1
+	for ; j < len(t.Nodes) && t.Nodes[j].Limit == n.Limit && t.Nodes[j].Depth > n.Depth; j++ {
+		if t.Nodes[j].Depth == n.Depth+1 {
+			children = append(children, j)
+		}
+	}
+
+	return children, j - i
+}
+
+// WriteTree writes the tree of the iteration of the search with the given limit down a path of indices in Moves:
+// every position along the path with the positions searched from it, marking the one the path goes through.
+func (t *SearchTrace) WriteTree(w io.Writer, limit int, path []int) error {
+	i := -1
+	for j, n := range t.Nodes {
+		if n.Limit == limit && n.Depth == 0 {
+			i = j
+			break
+		}
+	}
+	if i == -1 {
+		return fmt.Errorf("no iteration with a limit of %d moves was recorded", limit)
+	}
+
+	for d := 0; ; d++ {
+		children, size := t.children(i)
+		name := "Scramble"
+		if d != 0 {
+			name = "After " + t.FormatPath(path[:d])
+		}
+		fmt.Fprintf(w, "%s: lower bound %d, %s, %d positions\n", name, t.Nodes[i].Bound, t.Nodes[i].Outcome, size)
+
+		next := -1
+		for _, c := range children {
+			n := t.Nodes[c]
+			mark := "  "
+			if d < len(path) && n.Move == path[d] {
+				mark, next = "> ", c
+			}
+
+			_, size := t.children(c)
+			fmt.Fprintf(w, "%s%-6s lower bound %d, %s, %d positions\n", mark, t.Moves[n.Move], n.Bound, n.Outcome, size)
+		}
+
+		if d == len(path) {
+			return nil
+		}
+		if next == -1 {
+			return fmt.Errorf("%s was not searched after %q", t.Moves[path[d]], t.FormatPath(path[:d]))
+		}
+		i = next
+	}
+}
+
+// WriteSearchTrace writes a trace in the format of search trace files.
+func WriteSearchTrace(w io.Writer, t *SearchTrace) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(t); err != nil {
+		return err
+	}
+	for _, n := range t.Nodes {
+		if err := enc.Encode(n); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ReadSearchTrace reads a trace in the format of search trace files.
+func ReadSearchTrace(r io.Reader) (*SearchTrace, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var t SearchTrace
+	if err := dec.Decode(&t); err != nil {
+		return nil, err
+	}
+
+	for {
+		var n TraceNode
+		if err := dec.Decode(&n); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if n.Move < -1 || n.Move >= len(t.Moves) || n.Depth < 0 || (n.Move == -1) != (n.Depth == 0) {
+			return nil, fmt.Errorf("invalid position %d", len(t.Nodes))
+		}
+
+		t.Nodes = append(t.Nodes, n)
+	}
+
+	return &t, nil
+}
+
+// SaveSearchTrace writes a trace to a file.
+func SaveSearchTrace(path string, t *SearchTrace) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = WriteSearchTrace(f, t)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// LoadSearchTraceFile reads a trace from a file.
+func LoadSearchTraceFile(path string) (*SearchTrace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t, err := ReadSearchTrace(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return t, nil
+}
+
+// runTrace runs the trace command.
+func runTrace(args []string) error {
+	fs := flag.NewFlagSet("trace", flag.ContinueOnError)
+	maxMoves := fs.Int("max", 8, "most single shifts to look for a solution in, for record")
+	seed := fs.Int64("seed", 0, "seed of the order moves are tried in, which picks among equally short solutions, or 0 for the usual order, for record")
+	out := fs.String("o", "search.trace", "file to record the trace to, for record")
+	from := fs.Int("from", 0, "first position to print, for replay")
+	count := fs.Int("count", 20, "number of positions to print, for replay")
+	limit := fs.Int("limit", 0, "limit of the iteration to print the tree of, for view, or 0 for the last one")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: trace [-max 8] [-seed N] [-o search.trace] record STATE | trace [-from 0] [-count 20] replay FILE | trace [-limit N] view FILE [MOVES]")
+		fmt.Fprintln(fs.Output(), "record looks for the shortest solution to the board with the state code STATE, recording every position searched to a trace file.")
+		fmt.Fprintln(fs.Output(), "The solve command of the game records its searches too when the game is run with -log-level debug and -solve-trace FILE.")
+		fmt.Fprintln(fs.Output(), "replay searches the board of a trace again, checking that the search goes the same way, and prints the positions searched one by one.")
+		fmt.Fprintln(fs.Output(), "view prints the tree of an iteration of the search down a line of moves: every position along the line with the positions searched from it.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 || fs.NArg() > 3 || fs.NArg() == 3 && fs.Arg(0) != "view" {
+		fs.Usage()
+		return fmt.Errorf("expected an action and its arguments")
+	}
+
+	switch fs.Arg(0) {
+	case "record":
+		b, err := DecodeState(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+
+		t := &SearchTrace{}
+		res, found := SolveWithinSeeded(&b, *maxMoves, nil, *seed, t)
+		if found {
+			fmt.Printf("Shortest solution (%d moves): %s\n", len(res.Moves), MoveFormat{}.FormatMoves(res.Moves, &b))
+		} else {
+			fmt.Printf("No solution in %d moves or less\n", *maxMoves)
+		}
+		fmt.Println(res)
+		if t.Truncated {
+			fmt.Printf("Only the first %d positions were recorded\n", MaxTraceNodes)
+		}
+
+		return SaveSearchTrace(*out, t)
+	case "replay":
+		t, err := LoadSearchTraceFile(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+
+		p, err := t.puzzle()
+		if err != nil {
+			return err
+		}
+		for i := *from; i < *from+*count && i < len(t.Nodes); i++ {
+			n := t.Nodes[i]
+			path := t.Path(i)
+			b := p.board.Clone()
+			for _, m := range path {
+				b.MakeMove(&p.moves[m])
+			}
+
+			fmt.Printf("Position %d, at depth %d of the iteration with a limit of %d moves, after %q: lower bound %d, %s\n", i, n.Depth, n.Limit, t.FormatPath(path), n.Bound, n.Outcome)
+			fmt.Println(NoColors.SprintBoard(&b))
+		}
+
+		diverged, err := t.Replay()
+		if err != nil {
+			return err
+		}
+		if diverged != -1 {
+			return fmt.Errorf("the search went differently from position %d on", diverged)
+		}
+		fmt.Printf("The search went the same way through all %d positions\n", len(t.Nodes))
+		return nil
+	case "view":
+		t, err := LoadSearchTraceFile(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		if len(t.Nodes) == 0 {
+			return fmt.Errorf("no position was recorded")
+		}
+
+		var path []int
+		if fs.NArg() == 3 {
+			p, err := t.puzzle()
+			if err != nil {
+				return err
+			}
+			seq, err := ParseMoves(fs.Arg(2), &p.board)
+			if err != nil {
+				return err
+			}
+
+			// the search makes single shifts, so longer moves go through as many positions.
+			for _, m := range seq {
+				unit := Move{Axis: m.Axis, Index: m.Index, Amount: 1}
+				if m.Amount < 0 {
+					unit.Amount = -1
+				}
+
+				i := 0
+				for i < len(t.Moves) && t.Moves[i] != unit.String() {
+					i++
+				}
+				if i == len(t.Moves) {
+					return fmt.Errorf("the search does not make the move %s", unit)
+				}
+				for j := 0; j < Abs(m.Amount); j++ {
+					path = append(path, i)
+				}
+			}
+		}
+
+		if *limit == 0 {
+			*limit = t.Nodes[len(t.Nodes)-1].Limit
+		}
+		fmt.Printf("Iteration with a limit of %d moves\n", *limit)
+		return t.WriteTree(os.Stdout, *limit, path)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", fs.Arg(0))
+	}
+}
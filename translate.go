@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "translate",
+		Summary: "translate a sequence of moves between notations",
+		Run:     runTranslate,
+	})
+}
+
+// runTranslate runs the translate command.
+func runTranslate(args []string) error {
+	fs := flag.NewFlagSet("translate", flag.ContinueOnError)
+	size := fs.String("size", "5x5", "size of the board the moves are made on, which standard notation and reverse indices depend on")
+	var from, to MoveFormat
+	fs.Func("from", "notation of the moves: programmer, standard or english", func(s string) (err error) {
+		from.Notation, err = ParseNotation(s)
+		return err
+	})
+	fs.Func("to", "notation to translate the moves to: programmer, standard or english", func(s string) (err error) {
+		to.Notation, err = ParseNotation(s)
+		return err
+	})
+	fs.BoolVar(&to.ReverseIndex, "reverse-index", false, "write indices in Programmer's Notation counted from the bottom or right when that makes them smaller")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: translate [-size 5x5] -from NOTATION -to NOTATION [-reverse-index] MOVES")
+		fmt.Fprintln(fs.Output(), "Translates a sequence of moves, like translate -from programmer -to standard \"2R0 -1C3'\".")
+		fmt.Fprintln(fs.Output(), "Programmer's Notation counts slices from 0, standard notation from 1, and moves in English are separated by commas.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected moves to translate")
+	}
+
+	w, h, err := ParseTwoDimensions(*size)
+	if err != nil {
+		return err
+	}
+	b, err := NewBoard(w, h)
+	if err != nil {
+		return err
+	}
+
+	// moves can be given as a single argument or as one argument each.
+	input := strings.Join(fs.Args(), " ")
+	if from.Notation == EnglishNotation && fs.NArg() > 1 && !strings.ContainsAny(input, ",;") {
+		input = strings.Join(fs.Args(), ",")
+	}
+
+	seq, err := ParseMovesIn(from.Notation, input, &b)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(to.FormatMoves(seq, &b))
+	return nil
+}
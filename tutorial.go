@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func init() {
+	registerCommand(Command{
+		Name:    "tutorial",
+		Summary: "learn how to play with a few guided exercises",
+		Run: func(args []string) error {
+			ScanTutorial(NewConsole(os.Stdin, os.Stdout))
+			return nil
+		},
+	})
+}
+
+// tutorialStep is an exercise of the tutorial: a board scrambled with some moves, to be solved after reading the explanation.
+type tutorialStep struct {
+	Explanation string
+	Scramble    string
+}
+
+// tutorialSteps are the exercises of the tutorial, on 3x3 boards.
+var tutorialSteps = []tutorialStep{
+	{
+		Explanation: "Loopover is a sliding puzzle where every row and column loops around: shifting a row right moves its last tile to its start.\n" +
+			"Moves are typed in Programmer's Notation: how far to shift, R for a row or C for a column, and which one, counting from 0.\n" +
+			"So 1R0 shifts the top row right by one, and -1R0 shifts it back left. The top row below was shifted right, put it back in order.",
+		Scramble: "1R0",
+	},
+	{
+		Explanation: "Columns shift down for positive amounts and up for negative ones. Shifting a slice of 3 by 2 is the same as shifting it back by 1.\n" +
+			"The middle column below was shifted down, put it back in order.",
+		Scramble: "1C1",
+	},
+	{
+		Explanation: "Moves can be combined to move single tiles around. Here the bottom row and the last column were shifted, one after the other.",
+		Scramble:    "1R2 1C2",
+	},
+}
+
+// ScanTutorial walks through the tutorial's exercises, letting the player type moves until every board is solved.
+// Returns false if the input ended before the tutorial was done.
+func ScanTutorial(con *Console) bool {
+	for i, step := range tutorialSteps {
+		b, _ := NewBoard(3, 3)
+		seq, _ := ParseMoves(step.Scramble, &b)
+		b.ApplyMoves(seq)
+
+		fmt.Fprintf(con, "\nExercise %d of %d\n%s\n", i+1, len(tutorialSteps), step.Explanation)
+		for !b.IsSolved() {
+			fmt.Fprintf(con, "%s\nMove: ", SprintBoard(&b))
+			if !con.Scan() {
+				return false
+			}
+
+			input := strings.TrimSpace(con.Text())
+			if input == "" {
+				continue
+			}
+			if input == "skip" {
+				break
+			}
+
+			m, err := ParseMove(input, &b)
+			if err != nil {
+				fmt.Fprintf(con, "Invalid move (%s), type skip to skip the exercise\n", err)
+				continue
+			}
+			b.MakeMove(m)
+			if b.IsSolved() {
+				fmt.Fprintf(con, "%s\nSolved!\n", SprintBoard(&b))
+			}
+		}
+	}
+
+	fmt.Fprintln(con, "\nThat's it! In the game, type shuffle to scramble the board and start solving.")
+	return true
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Variant changes the rules of the game: which moves can be made, and what happens after a move.
+type Variant interface {
+	// Name returns the name the variant is chosen by.
+	Name() string
+	// Restart is called whenever the game restarts on a new scramble.
+	Restart(b *Board)
+	// Allows returns an error explaining why the move can't be made on the board, or nil if it can.
+	Allows(b *Board, m *Move) error
+	// AfterMove is called after a move is made on the board.
+	AfterMove(b *Board, m *Move)
+}
+
+// variants holds a constructor for every variant by name.
+var variants = map[string]func() Variant{
+	"lockin": func() Variant { return &LockIn{} },
+}
+
+// NewVariant creates the variant with the given name.
+func NewVariant(name string) (Variant, error) {
+	v, ok := variants[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(variants))
+		for n := range variants {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("unknown variant %q, available variants are %s", name, strings.Join(names, ", "))
+	}
+
+	return v(), nil
+}
+
+// LockIn is a variant where tiles moved into their place lock there, blocking their row and column from moving.
+// Tiles that are already in place when the game restarts don't lock, and undoing the move that locked a tile frees it again.
+type LockIn struct {
+	// locked holds the tiles that were moved into their place.
+	locked map[int]bool
+}
+
+func (v *LockIn) Name() string {
+	return "lockin"
+}
+
+func (v *LockIn) Restart(b *Board) {
+	v.locked = map[int]bool{}
+}
+
+// Allows returns an error if the slice of the move holds a locked tile that is in its place.
+func (v *LockIn) Allows(b *Board, m *Move) error {
+	for i := 0; i < b.SliceLength(m.Axis); i++ {
+		x, y := i, m.Index
+		if m.Axis == VerticalAxis {
+			x, y = m.Index, i
+		}
+
+		if t := (*b)[x][y]; v.locked[t] && t == b.defaultTileValue(x, y) {
+			return fmt.Errorf("tile %d is locked in its place", t)
+		}
+	}
+
+	return nil
+}
+
+// AfterMove locks the tiles the move brought into their place.
+func (v *LockIn) AfterMove(b *Board, m *Move) {
+	if v.locked == nil {
+		v.locked = map[int]bool{}
+	}
+
+	for i := 0; i < b.SliceLength(m.Axis); i++ {
+		x, y := i, m.Index
+		if m.Axis == VerticalAxis {
+			x, y = m.Index, i
+		}
+
+		if t := (*b)[x][y]; t == b.defaultTileValue(x, y) {
+			v.locked[t] = true
+		}
+	}
+}
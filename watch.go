@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SearchView draws a search for the shortest solution while it goes, like an animation, for demos and to see how the search works:
+// the iteration, how many positions were searched and how fast, the closest position to solved found so far and the position being searched.
+type SearchView struct {
+	Out    io.Writer
+	Colors ColorScheme
+	Format MoveFormat
+	// Interval is the least time between two frames, and Delay how long to pause on every position, to slow the search down enough to follow it.
+	Interval, Delay time.Duration
+
+	puzzle       *boardPuzzle
+	scramble     Board
+	line         []Move
+	closest      []Move
+	closestBound int
+	positions    int
+	start, drawn time.Time
+}
+
+// SolveWithinWatched is SolveWithin drawing the search to the view while it goes.
+func SolveWithinWatched(b *Board, maxMoves int, r *Restrictions, v *SearchView) (*SolveResult, bool) {
+	p := &boardPuzzle{board: b.Clone(), moves: r.Filter(UnitMoves(b))}
+	v.puzzle, v.scramble = p, b.Clone()
+	v.line, v.closest, v.closestBound, v.positions = nil, nil, -1, 0
+	v.start = time.Now()
+
+	res, path, found := searchWithin(p, maxMoves, v)
+	for _, i := range path {
+		res.Moves = append(res.Moves, p.moves[i])
+	}
+
+	return res, found
+}
+
+func (v *SearchView) visit(limit, depth, move, bound int, outcome string) {
+	v.positions++
+	if move == -1 {
+		v.line = v.line[:0]
+	} else {
+		v.line = append(v.line[:depth-1], v.puzzle.moves[move])
+	}
+	if v.closestBound == -1 || bound < v.closestBound {
+		v.closest, v.closestBound = append(v.closest[:0], v.line...), bound
+	}
+
+	if v.Delay > 0 {
+		time.Sleep(v.Delay)
+	}
+	if outcome == SolvedNode || v.Delay > 0 || time.Since(v.drawn) >= v.Interval {
+		v.draw(limit, bound, outcome)
+		v.drawn = time.Now()
+	}
+}
+
+// draw draws a frame of the search, clearing the screen.
+func (v *SearchView) draw(limit, bound int, outcome string) {
+	elapsed := time.Since(v.start)
+	var sb strings.Builder
+	sb.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&sb, "Looking for a solution in %d moves\n", limit)
+	fmt.Fprintf(&sb, "%d positions in %s, %.0f per second\n", v.positions, elapsed.Round(time.Millisecond), float64(v.positions)/elapsed.Seconds())
+	fmt.Fprintf(&sb, "Closest so far: lower bound %d after %q\n", v.closestBound, v.Format.FormatMoves(v.closest, &v.scramble))
+	fmt.Fprintf(&sb, "Searching: %q, lower bound %d, %s\n\n", v.Format.FormatMoves(v.line, &v.scramble), bound, outcome)
+	sb.WriteString(v.Colors.SprintBoard(&v.puzzle.board))
+	sb.WriteString("\n")
+
+	io.WriteString(v.Out, sb.String())
+}
+
+// parseWatchArgs parses the arguments of the watch command: the most moves to look for a solution in, optionally followed by the pause on every position.
+func parseWatchArgs(arg string) (int, time.Duration, error) {
+	fields := strings.Fields(arg)
+	if len(fields) != 1 && len(fields) != 2 {
+		return 0, 0, fmt.Errorf("expected 1 or 2 arguments")
+	}
+
+	maxMoves, err := strconv.Atoi(fields[0])
+	if err != nil || maxMoves < 0 {
+		return 0, 0, fmt.Errorf("invalid number of moves %q", fields[0])
+	}
+	if len(fields) == 1 {
+		return maxMoves, 0, nil
+	}
+
+	delay, err := time.ParseDuration(fields[1])
+	if err != nil || delay < 0 {
+		return 0, 0, fmt.Errorf("invalid pause %q", fields[1])
+	}
+	return maxMoves, delay, nil
+}